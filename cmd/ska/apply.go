@@ -0,0 +1,154 @@
+/*
+Copyright 2025 - Scott Hussey, Jerrod Early
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sthussey/ska/graph"
+	"github.com/sthussey/ska/prompt"
+	"github.com/sthussey/ska/render"
+	"github.com/sthussey/ska/sink/fs"
+	"github.com/urfave/cli/v3"
+)
+
+// printApplyPlan prints the operation applying root to dest with opts would
+// perform for each node. It fails when any conflict is found, as the real
+// apply would.
+func printApplyPlan(root graph.SkaffoldNode, dest string, opts fs.WriteOptions) error {
+	plan, err := fs.PlanGraphWithOptions(root, dest, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, op := range plan {
+		target := op.Path
+		if op.Node.Type() == graph.NODETYPE_DIRECTORY {
+			target += string(filepath.Separator)
+		}
+		line := fmt.Sprintf("%-9s %s", strings.ToLower(op.Kind), target)
+		if op.Reason != "" {
+			line += " (" + op.Reason + ")"
+		}
+		fmt.Println(line)
+	}
+
+	if conflicts := plan.Conflicts(); len(conflicts) > 0 {
+		return cli.Exit(fmt.Sprintf("%d paths conflict with %s; use --force to overwrite files", len(conflicts), dest), 1)
+	}
+	return nil
+}
+
+// writeDest writes root into the --dest directory as the --force and --skip-*
+// flags direct, or prints the plan with --dry-run. Once written it reports
+// the counts prefixed by verb.
+func writeDest(ctx context.Context, cmd *cli.Command, root graph.SkaffoldNode, verb string) error {
+	writeOpts := fs.WriteOptions{
+		Overwrite:          cmd.Bool("force"),
+		SkipIfExists:       cmd.Bool("skip-existing"),
+		SkipIfContentEqual: cmd.Bool("skip-unchanged"),
+		OnWarning: func(err error) {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		},
+	}
+	if cmd.Bool("dry-run") {
+		return printApplyPlan(root, cmd.String("dest"), writeOpts)
+	}
+
+	plan, err := fs.PlanGraphWithOptions(root, cmd.String("dest"), writeOpts)
+	if err != nil {
+		return fmt.Errorf("failed to apply graph: %w", err)
+	}
+	if err := fs.ApplyPlan(ctx, plan, writeOpts); err != nil {
+		return fmt.Errorf("failed to apply graph: %w", err)
+	}
+
+	summary := plan.Summary()
+	fmt.Printf("%s %s to %s (%d written, %d skipped)\n", verb, cmd.String("path"), cmd.String("dest"), summary.Written, summary.Skipped)
+	return nil
+}
+
+// renderGraph renders root with the variables its templates use. Values come
+// from the --values file, then the environment, then --var flags, each
+// overriding the last, and any still missing are prompted for when
+// interactive is set and are otherwise an error.
+func renderGraph(ctx context.Context, root graph.SkaffoldNode, cmd *cli.Command, interactive bool) (graph.SkaffoldNode, error) {
+	names, err := render.RequiredVars(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find template variables: %w", err)
+	}
+
+	var fileVars map[string]any
+	if path := cmd.String("values"); path != "" {
+		fileVars, err = render.LoadValuesFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	flagVars := make(map[string]any)
+	for _, assignment := range cmd.StringSlice("var") {
+		name, value, err := render.ParseValue(assignment)
+		if err != nil {
+			return nil, err
+		}
+		if err := render.SetValue(flagVars, name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	vars, err := prompt.CollectWithOptions(names, prompt.PromptOptions{
+		Values:         render.MergeValues(fileVars, render.ValuesFromEnv(render.EnvPrefix, os.Environ()), flagVars),
+		NonInteractive: !interactive,
+	})
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := render.RenderWithOptions(ctx, root, vars, render.RenderOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render graph: %w", err)
+	}
+	return rendered, nil
+}
+
+// renderFile renders the single template file named by the --file flag as a
+// full render of a graph holding only that file would, so templates behave
+// as they do in a tree, and writes the result to stdout. Template faults are
+// reported with the file name and the line and, once executing, the column.
+func renderFile(ctx context.Context, cmd *cli.Command) error {
+	path := cmd.String("file")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read template %s: %w", path, err)
+	}
+
+	root := graph.NewDirectoryNode(".")
+	fileNode, err := graph.NewFileNodeFull(filepath.Base(path), content, graph.FILEACTION_TEMPLATE)
+	if err != nil {
+		return err
+	}
+	_ = fileNode.SetParent(root)
+	if err := root.AddChild(fileNode); err != nil {
+		return err
+	}
+
+	rendered, err := renderGraph(ctx, root, cmd, false)
+	if err != nil {
+		return err
+	}
+	for _, child := range rendered.(*graph.DirectoryNode).Children() {
+		output, err := child.(*graph.FileNode).Content()
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stdout.Write(output); err != nil {
+			return fmt.Errorf("failed to write rendered template: %w", err)
+		}
+	}
+	return nil
+}
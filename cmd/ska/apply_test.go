@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+	"github.com/sthussey/ska/render"
+	"github.com/sthussey/ska/sink/fs"
+	srcfs "github.com/sthussey/ska/source/fs"
+	"github.com/urfave/cli/v3"
+)
+
+// captureStdout runs fn with os.Stdout redirected and returns what it printed.
+func captureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+
+	fnErr := fn()
+	w.Close()
+	return <-out, fnErr
+}
+
+// hasLine reports whether out has a line reading line, optionally followed by
+// a parenthesized reason.
+func hasLine(out, line string) bool {
+	for _, l := range strings.Split(out, "\n") {
+		if l == line || strings.HasPrefix(l, line+" (") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPrintApplyPlan(t *testing.T) {
+	src := t.TempDir()
+	for _, p := range []string{"README.md", "src/main.go"} {
+		path := filepath.Join(src, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(p), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, err := srcfs.BuildGraph(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dest, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dest, "README.md"), []byte("existing"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		force   bool
+		readme  string
+		wantErr bool
+	}{
+		{"conflict", false, "conflict", true},
+		{"force", true, "overwrite", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := captureStdout(t, func() error { return printApplyPlan(root, dest, fs.WriteOptions{Overwrite: tt.force}) })
+			if (err != nil) != tt.wantErr {
+				t.Errorf("printApplyPlan error = %v, want error %v", err, tt.wantErr)
+			}
+
+			sep := string(filepath.Separator)
+			for _, want := range [][2]string{
+				{"skip", dest + sep},
+				{tt.readme, filepath.Join(dest, "README.md")},
+				{"skip", filepath.Join(dest, "src") + sep},
+				{"create", filepath.Join(dest, "src", "main.go")},
+			} {
+				if line := fmt.Sprintf("%-9s %s", want[0], want[1]); !hasLine(out, line) {
+					t.Errorf("plan is missing %q:\n%s", line, out)
+				}
+			}
+		})
+	}
+
+	// A dry run never touches the destination
+	if _, err := os.Stat(filepath.Join(dest, "src", "main.go")); !os.IsNotExist(err) {
+		t.Errorf("dry run wrote to the destination: %v", err)
+	}
+}
+
+// renderCommand returns a command taking the flags of template render, which
+// runs action once parsed.
+func renderCommand(action cli.ActionFunc) *cli.Command {
+	return &cli.Command{
+		Name: "render",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "path"},
+			&cli.StringFlag{Name: "dest"},
+			&cli.StringFlag{Name: "values"},
+			&cli.StringSliceFlag{Name: "var"},
+			&cli.BoolFlag{Name: "dry-run"},
+			&cli.BoolFlag{Name: "force"},
+			&cli.BoolFlag{Name: "skip-existing"},
+			&cli.BoolFlag{Name: "skip-unchanged"},
+		},
+		Action: action,
+	}
+}
+
+func TestRenderGraphValuesPrecedence(t *testing.T) {
+	values := filepath.Join(t.TempDir(), "values.yaml")
+	data := "Name: file\nLicense: MIT\nAuthor:\n  Name: file\n  Email: file@example.com\n"
+	if err := os.WriteFile(values, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(render.EnvPrefix+"NAME", "env")
+	t.Setenv(render.EnvPrefix+"AUTHOR__NAME", "env")
+	t.Setenv(render.EnvPrefix+"VERSION", "env")
+
+	root, err := graph.NewBuilder("app").
+		File("out.txt").Template().
+		Content([]byte("{{.Name}} {{.License}} {{.Version}} {{.Author.Name}} {{.Author.Email}}")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rendered graph.SkaffoldNode
+	cmd := renderCommand(func(ctx context.Context, cmd *cli.Command) error {
+		var err error
+		rendered, err = renderGraph(ctx, root, cmd, false)
+		return err
+	})
+	args := []string{"render", "--values", values, "--var", "Name=flag", "--var", "Author.Name=flag"}
+	if err := cmd.Run(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := graph.FindByPath(rendered, "out.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := node.(*graph.FileNode).Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "flag MIT env flag file@example.com"; string(content) != want {
+		t.Errorf("rendered %q, want %q", content, want)
+	}
+}
+
+func TestRenderGraphMissingValues(t *testing.T) {
+	root, err := graph.NewBuilder("app").
+		File("out.txt").Template().Content([]byte("{{.Name}} {{.Version}}")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := renderCommand(func(ctx context.Context, cmd *cli.Command) error {
+		_, err := renderGraph(ctx, root, cmd, false)
+		return err
+	})
+	err = cmd.Run(context.Background(), []string{"render", "--var", "Name=flag"})
+	if err == nil || !strings.Contains(err.Error(), "no value for variables Version") {
+		t.Errorf("renderGraph error = %v, want Version reported missing", err)
+	}
+}
+
+func TestTemplateRender(t *testing.T) {
+	root, err := graph.NewBuilder("app").
+		Dir("{{.Module}}", func(b *graph.Builder) {
+			b.File("main.go.tmpl").Content([]byte("package {{.Module}}\n"))
+		}).
+		File("LICENSE").Content([]byte("MIT\n")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dest, "LICENSE"), []byte("MIT\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := renderCommand(func(ctx context.Context, cmd *cli.Command) error {
+		rendered, err := renderGraph(ctx, root, cmd, false)
+		if err != nil {
+			return err
+		}
+		return writeDest(ctx, cmd, rendered, "Rendered")
+	})
+	args := []string{"render", "--path", "tmpl", "--dest", dest, "--var", "Module=api", "--skip-unchanged"}
+	out, err := captureStdout(t, func() error { return cmd.Run(context.Background(), args) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := fmt.Sprintf("Rendered tmpl to %s (1 written, 1 skipped)\n", dest); out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+	data, err := os.ReadFile(filepath.Join(dest, "api", "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "package api\n" {
+		t.Errorf("api/main.go = %q, want it rendered", data)
+	}
+}
+
+func TestRenderFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "greeting.txt.tmpl")
+	if err := os.WriteFile(file, []byte("Hello, {{.Name}}!\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := &cli.Command{
+		Name: "render-file",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "file"},
+			&cli.StringFlag{Name: "values"},
+			&cli.StringSliceFlag{Name: "var"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return renderFile(ctx, cmd)
+		},
+	}
+
+	out, err := captureStdout(t, func() error {
+		return cmd.Run(context.Background(), []string{"render-file", "--file", file, "--var", "Name=ska"})
+	})
+	if err != nil || out != "Hello, ska!\n" {
+		t.Errorf("render-file printed %q, %v; want the rendered template", out, err)
+	}
+
+	_, err = captureStdout(t, func() error {
+		return cmd.Run(context.Background(), []string{"render-file", "--file", file})
+	})
+	if err == nil || !strings.Contains(err.Error(), "Name") {
+		t.Errorf("render-file error = %v, want Name reported missing", err)
+	}
+}
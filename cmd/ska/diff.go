@@ -0,0 +1,143 @@
+/*
+Copyright 2025 - Scott Hussey, Jerrod Early
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/sthussey/ska/graph"
+	"github.com/sthussey/ska/source/fs"
+	"github.com/urfave/cli/v3"
+)
+
+// diffCommand returns the `graph diff` command. It takes no hash cache: the
+// two directories are built independently, so a change in one can never be
+// hidden by an entry recorded for the other.
+func diffCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "diff",
+		Usage: "Show files added, removed or changed between two directories",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "path-a",
+				Aliases:  []string{"a"},
+				Usage:    "Path to the original directory",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "path-b",
+				Aliases:  []string{"b"},
+				Usage:    "Path to the directory to compare against it",
+				Required: true,
+			},
+			includeFlag(),
+			ignoreFlag(),
+			gitignoreFlag(),
+			manifestFlag(),
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			opts, err := buildOptions(cmd)
+			if err != nil {
+				return err
+			}
+
+			a, err := fs.BuildGraphWithOptions(ctx, cmd.String("path-a"), opts)
+			if err != nil {
+				return fmt.Errorf("failed to build graph: %w", err)
+			}
+			b, err := fs.BuildGraphWithOptions(ctx, cmd.String("path-b"), opts)
+			if err != nil {
+				return fmt.Errorf("failed to build graph: %w", err)
+			}
+
+			changes, err := diffGraphs(a, b)
+			if err != nil {
+				return err
+			}
+
+			for _, c := range changes {
+				fmt.Printf("%c %s\n", c.marker, c.path)
+			}
+			if len(changes) > 0 {
+				return cli.Exit(fmt.Sprintf("%d differences found", len(changes)), 1)
+			}
+			return nil
+		},
+	}
+}
+
+// change is a single line of `graph diff` output.
+type change struct {
+	marker byte   // '-' removed, '+' added or '~' changed
+	path   string // Slash-separated path, ending in "/" for directories
+}
+
+// diffGraphs lists the paths removed from a, added in b and changed between
+// them, sorted by path. A directory missing from one side is reported once
+// rather than file by file, and a path whose type changed is reported as
+// removed and added.
+func diffGraphs(a, b graph.SkaffoldNode) ([]change, error) {
+	changes := make([]change, 0)
+
+	removed, err := graph.Difference(a, b)
+	if err != nil {
+		return nil, err
+	}
+	err = graph.Walk(removed, func(node graph.SkaffoldNode, depth int, segments []string) error {
+		if depth == 0 {
+			return nil
+		}
+		p := path.Join(segments...)
+		other, err := graph.FindByPath(b, p)
+		if err != nil || other.Type() != node.Type() {
+			changes = append(changes, change{marker: '-', path: displayPath(node, p)})
+			return graph.SkipChildren
+		}
+		if node.Type() != graph.NODETYPE_DIRECTORY {
+			changes = append(changes, change{marker: '~', path: p})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Changed files were found above, so only additions remain
+	added, err := graph.Difference(b, a)
+	if err != nil {
+		return nil, err
+	}
+	err = graph.Walk(added, func(node graph.SkaffoldNode, depth int, segments []string) error {
+		if depth == 0 {
+			return nil
+		}
+		p := path.Join(segments...)
+		other, err := graph.FindByPath(a, p)
+		if err != nil || other.Type() != node.Type() {
+			changes = append(changes, change{marker: '+', path: displayPath(node, p)})
+			return graph.SkipChildren
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].path < changes[j].path
+	})
+	return changes, nil
+}
+
+// displayPath marks directory paths with a trailing slash.
+func displayPath(node graph.SkaffoldNode, p string) string {
+	if node.Type() == graph.NODETYPE_DIRECTORY {
+		return p + "/"
+	}
+	return p
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// runDiff runs the diff command with args, returning its exit error rather
+// than exiting.
+func runDiff(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	cmd := diffCommand()
+	cmd.ExitErrHandler = func(context.Context, *cli.Command, error) {}
+	return captureStdout(t, func() error {
+		return cmd.Run(context.Background(), append([]string{"diff"}, args...))
+	})
+}
+
+// writeTree writes files, keyed by slash-separated path, under a new
+// temporary directory with a fixed modification time and returns it.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for p, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDiffCommand(t *testing.T) {
+	// a.txt differs only in content, with the same size and modification
+	// time on both sides
+	a := writeTree(t, map[string]string{"a.txt": "one", "c.txt": "gone", "same/x.txt": "x"})
+	b := writeTree(t, map[string]string{"a.txt": "two", "b.txt": "new", "same/x.txt": "x"})
+
+	out, err := runDiff(t, "--path-a", a, "--path-b", b)
+	if want := "~ a.txt\n+ b.txt\n- c.txt\n"; out != want {
+		t.Errorf("diff printed %q, want %q", out, want)
+	}
+	if err == nil || !strings.Contains(err.Error(), "3 differences found") {
+		t.Errorf("diff error = %v, want 3 differences reported", err)
+	}
+
+	out, err = runDiff(t, "--path-a", a, "--path-b", a)
+	if err != nil || out != "" {
+		t.Errorf("diff of a directory with itself = %q, %v; want no differences", out, err)
+	}
+}
+
+func TestDiffCommandTakesNoCache(t *testing.T) {
+	a := writeTree(t, map[string]string{"a.txt": "one"})
+	cache := filepath.Join(t.TempDir(), "cache.json")
+	_, err := runDiff(t, "--path-a", a, "--path-b", a, "--cache", cache)
+	if err == nil {
+		t.Error("diff accepted a hash cache shared by both sides")
+	}
+	if _, err := os.Stat(cache); !os.IsNotExist(err) {
+		t.Errorf("diff wrote a hash cache: %v", err)
+	}
+}
@@ -9,8 +9,15 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 
-	"github.com/sthussey/ska"
+	"github.com/sthussey/ska/graph"
+	"github.com/sthussey/ska/render"
+	"github.com/sthussey/ska/sink/console"
+	"github.com/sthussey/ska/sink/dot"
+	"github.com/sthussey/ska/sink/jsonl"
+	"github.com/sthussey/ska/source/fs"
+	"github.com/sthussey/ska/source/multi"
 	"github.com/urfave/cli/v3"
 )
 
@@ -33,11 +40,24 @@ func main() {
 								Usage:    "Path to the directory to build the graph from",
 								Required: true,
 							},
+							templateFlag(),
+							cacheFlag(),
+							includeFlag(),
+							ignoreFlag(),
+							gitignoreFlag(),
+							manifestFlag(),
+							maxDepthFlag(),
+							maxFileSizeFlag(),
 						},
 						Action: func(ctx context.Context, cmd *cli.Command) error {
 							rootPath := cmd.String("path")
-							
-							root, err := ska.BuildGraph(rootPath)
+
+							opts, err := buildOptions(cmd)
+							if err != nil {
+								return err
+							}
+
+							root, err := buildGraph(ctx, cmd, opts)
 							if err != nil {
 								return fmt.Errorf("failed to build graph: %w", err)
 							}
@@ -58,25 +78,593 @@ func main() {
 								Usage:    "Path to the directory to print the graph for",
 								Required: true,
 							},
+							templateFlag(),
+							cacheFlag(),
+							includeFlag(),
+							ignoreFlag(),
+							gitignoreFlag(),
+							manifestFlag(),
+							maxDepthFlag(),
+							maxFileSizeFlag(),
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Output format: text for an indented tree, tree for a tree(1)-style view, paths for one parseable entry per line, jsonl for one JSON object per node, dot for a Graphviz diagram",
+								Value: "text",
+							},
+							&cli.BoolFlag{
+								Name:  "null",
+								Usage: "Terminate entries with NUL instead of newline (paths format only)",
+							},
+							&cli.BoolFlag{
+								Name:  "sizes",
+								Usage: "Show file sizes and aggregate directory sizes (text format only)",
+							},
+							&cli.BoolFlag{
+								Name:  "hashes",
+								Usage: "Show the content hash of each file (text format only)",
+							},
+							&cli.BoolFlag{
+								Name:  "content-types",
+								Usage: "Show the content type of each file (text format only)",
+							},
+						},
+						Action: func(ctx context.Context, cmd *cli.Command) error {
+							opts, err := buildOptions(cmd)
+							if err != nil {
+								return err
+							}
+
+							root, err := buildGraph(ctx, cmd, opts)
+							if err != nil {
+								return fmt.Errorf("failed to build graph: %w", err)
+							}
+
+							switch cmd.String("format") {
+							case "text":
+								if cmd.Bool("null") {
+									return fmt.Errorf("--null requires --format paths")
+								}
+								printOpts := console.PrintOptions{
+									Sizes:        cmd.Bool("sizes"),
+									ContentTypes: cmd.Bool("content-types"),
+									Hashes:       cmd.Bool("hashes"),
+								}
+								if printOpts.Sizes {
+									graph.ComputeSizes(root)
+								}
+								return console.PrintGraphWithOptions(root, os.Stdout, 0, printOpts)
+							case "tree":
+								if cmd.Bool("null") || cmd.Bool("sizes") || cmd.Bool("hashes") || cmd.Bool("content-types") {
+									return fmt.Errorf("--null, --sizes, --hashes and --content-types are not supported with --format tree")
+								}
+								return console.PrintGraphTree(root, os.Stdout)
+							case "paths":
+								if cmd.Bool("sizes") || cmd.Bool("hashes") || cmd.Bool("content-types") {
+									return fmt.Errorf("--sizes, --hashes and --content-types require --format text")
+								}
+								sep := byte('\n')
+								if cmd.Bool("null") {
+									sep = 0
+								}
+								return console.PrintPaths(root, os.Stdout, sep)
+							case "jsonl":
+								if cmd.Bool("null") || cmd.Bool("sizes") || cmd.Bool("hashes") || cmd.Bool("content-types") {
+									return fmt.Errorf("--null, --sizes, --hashes and --content-types are not supported with --format jsonl")
+								}
+								return jsonl.WriteGraph(root, os.Stdout)
+							case "dot":
+								if cmd.Bool("null") || cmd.Bool("sizes") || cmd.Bool("hashes") || cmd.Bool("content-types") {
+									return fmt.Errorf("--null, --sizes, --hashes and --content-types are not supported with --format dot")
+								}
+								return dot.WriteGraph(root, os.Stdout)
+							default:
+								return fmt.Errorf("unknown format %s", cmd.String("format"))
+							}
+						},
+					},
+					{
+						Name:  "estimate",
+						Usage: "Estimate the size of a graph without reading file content",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "path",
+								Aliases:  []string{"p"},
+								Usage:    "Path to the directory to estimate",
+								Required: true,
+							},
+							includeFlag(),
+							ignoreFlag(),
+							gitignoreFlag(),
+							manifestFlag(),
+							maxDepthFlag(),
+							maxFileSizeFlag(),
+						},
+						Action: func(ctx context.Context, cmd *cli.Command) error {
+							opts, err := buildOptions(cmd)
+							if err != nil {
+								return err
+							}
+
+							estimate, err := fs.EstimateGraph(cmd.String("path"), opts)
+							if err != nil {
+								return fmt.Errorf("failed to estimate graph: %w", err)
+							}
+
+							fmt.Printf("Directories: %d\n", estimate.Directories)
+							fmt.Printf("Files: %d\n", estimate.Files)
+							fmt.Printf("Symlinks: %d\n", estimate.Symlinks)
+							fmt.Printf("Total bytes: %d\n", estimate.TotalBytes)
+							return nil
+						},
+					},
+					{
+						Name:  "stats",
+						Usage: "Summarize the nodes, actions and content types of a graph",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "path",
+								Aliases:  []string{"p"},
+								Usage:    "Path to the directory to summarize",
+								Required: true,
+							},
+							templateFlag(),
+							cacheFlag(),
+							includeFlag(),
+							ignoreFlag(),
+							gitignoreFlag(),
+							manifestFlag(),
+							maxDepthFlag(),
+							maxFileSizeFlag(),
+						},
+						Action: func(ctx context.Context, cmd *cli.Command) error {
+							opts, err := buildOptions(cmd)
+							if err != nil {
+								return err
+							}
+
+							root, err := buildGraph(ctx, cmd, opts)
+							if err != nil {
+								return fmt.Errorf("failed to build graph: %w", err)
+							}
+
+							printStats(graph.Stats(root))
+							return nil
+						},
+					},
+					{
+						Name:  "hash",
+						Usage: "Print a fingerprint of a graph's structure and content",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "path",
+								Aliases:  []string{"p"},
+								Usage:    "Path to the directory to fingerprint",
+								Required: true,
+							},
+							templateFlag(),
+							cacheFlag(),
+							includeFlag(),
+							ignoreFlag(),
+							gitignoreFlag(),
+							manifestFlag(),
+							maxDepthFlag(),
+						},
+						Action: func(ctx context.Context, cmd *cli.Command) error {
+							opts, err := buildOptions(cmd)
+							if err != nil {
+								return err
+							}
+
+							root, err := buildGraph(ctx, cmd, opts)
+							if err != nil {
+								return fmt.Errorf("failed to build graph: %w", err)
+							}
+
+							sum, err := graph.GraphHash(root)
+							if err != nil {
+								return err
+							}
+							fmt.Printf("%x\n", sum)
+							return nil
+						},
+					},
+					{
+						Name:  "validate",
+						Usage: "Check a directory for problems such as unparseable templates",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "path",
+								Aliases:  []string{"p"},
+								Usage:    "Path to the directory to validate",
+								Required: true,
+							},
+							templateFlag(),
+							cacheFlag(),
+							includeFlag(),
+							ignoreFlag(),
+							gitignoreFlag(),
+							manifestFlag(),
+							maxDepthFlag(),
+							maxFileSizeFlag(),
 						},
 						Action: func(ctx context.Context, cmd *cli.Command) error {
 							rootPath := cmd.String("path")
-							
-							root, err := ska.BuildGraph(rootPath)
+
+							opts, err := buildOptions(cmd)
+							if err != nil {
+								return err
+							}
+							opts.ValidateTemplates = true
+
+							root, err := buildGraph(ctx, cmd, opts)
 							if err != nil {
 								return fmt.Errorf("failed to build graph: %w", err)
 							}
-							
-							ska.PrintGraph(root, 0)
+
+							if err := graph.Validate(root); err != nil {
+								return err
+							}
+
+							fmt.Printf("%s is valid\n", rootPath)
 							return nil
 						},
 					},
+					{
+						Name:  "verify",
+						Usage: "Verify a directory against a sha256sum, sha1sum or md5sum checksum manifest",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "path",
+								Aliases:  []string{"p"},
+								Usage:    "Path to the directory to verify",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "manifest",
+								Aliases:  []string{"m"},
+								Usage:    "Path to the checksum manifest",
+								Required: true,
+							},
+							templateFlag(),
+							cacheFlag(),
+							includeFlag(),
+							ignoreFlag(),
+							gitignoreFlag(),
+							manifestFlag(),
+							maxDepthFlag(),
+							maxFileSizeFlag(),
+						},
+						Action: func(ctx context.Context, cmd *cli.Command) error {
+							rootPath := cmd.String("path")
+
+							opts, err := buildOptions(cmd)
+							if err != nil {
+								return err
+							}
+
+							root, err := buildGraph(ctx, cmd, opts)
+							if err != nil {
+								return fmt.Errorf("failed to build graph: %w", err)
+							}
+
+							manifest, err := os.Open(cmd.String("manifest"))
+							if err != nil {
+								return fmt.Errorf("failed to open manifest: %w", err)
+							}
+							defer manifest.Close()
+
+							mismatches, err := graph.VerifyAgainstManifest(root, manifest)
+							if err != nil {
+								return err
+							}
+
+							for _, m := range mismatches {
+								fmt.Printf("%s %s\n", m.Kind, m.Path)
+							}
+							if len(mismatches) > 0 {
+								return cli.Exit(fmt.Sprintf("%d files do not match the manifest", len(mismatches)), 1)
+							}
+
+							fmt.Printf("%s matches the manifest\n", rootPath)
+							return nil
+						},
+					},
+					diffCommand(),
+				},
+			},
+			{
+				Name:  "apply",
+				Usage: "Materialize a directory or template into a destination directory",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "path",
+						Aliases:  []string{"p"},
+						Usage:    "Path to the directory to apply",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "dest",
+						Aliases:  []string{"d"},
+						Usage:    "Directory to write the graph into",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Print what would be written without touching disk",
+					},
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "Overwrite existing files in the destination",
+					},
+					&cli.BoolFlag{
+						Name:  "skip-existing",
+						Usage: "Leave existing files in the destination untouched",
+					},
+					&cli.BoolFlag{
+						Name:  "skip-unchanged",
+						Usage: "Leave existing files whose content already matches untouched",
+					},
+					&cli.BoolFlag{
+						Name:  "render",
+						Usage: "Render TEMPLATE files and templated names, prompting for the variables they use",
+					},
+					&cli.BoolFlag{
+						Name:  "no-input",
+						Usage: "Fail instead of prompting when a variable has no value (with --render)",
+					},
+					&cli.StringFlag{
+						Name:  "values",
+						Usage: "YAML or JSON file of template variables, overridden by " + render.EnvPrefix + "* environment variables (implies --render)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "var",
+						Usage: "Set a template variable as Name=value, overriding --values and the environment (repeatable, implies --render)",
+					},
+					templateFlag(),
+					cacheFlag(),
+					includeFlag(),
+					ignoreFlag(),
+					gitignoreFlag(),
+					manifestFlag(),
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					opts, err := buildOptions(cmd)
+					if err != nil {
+						return err
+					}
+
+					root, err := buildGraph(ctx, cmd, opts)
+					if err != nil {
+						return fmt.Errorf("failed to build graph: %w", err)
+					}
+
+					if cmd.Bool("render") || cmd.IsSet("values") || cmd.IsSet("var") {
+						root, err = renderGraph(ctx, root, cmd, !cmd.Bool("no-input"))
+						if err != nil {
+							return err
+						}
+					}
+
+					return writeDest(ctx, cmd, root, "Applied")
+				},
+			},
+			{
+				Name:  "template",
+				Usage: "Operations on template catalogs",
+				Commands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List the templates available in a catalog",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "path",
+								Aliases:  []string{"p"},
+								Usage:    "Path to the template catalog",
+								Required: true,
+							},
+						},
+						Action: func(ctx context.Context, cmd *cli.Command) error {
+							names, err := multi.ListTemplates(cmd.String("path"))
+							if err != nil {
+								return err
+							}
+							for _, name := range names {
+								fmt.Println(name)
+							}
+							return nil
+						},
+					},
+					{
+						Name:  "render",
+						Usage: "Render a template directory with a set of variables into a destination directory",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "path",
+								Aliases:  []string{"p"},
+								Usage:    "Path to the template directory or catalog",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "dest",
+								Aliases:  []string{"d"},
+								Usage:    "Directory to write the rendered graph into",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "values",
+								Usage: "YAML or JSON file of template variables, overridden by " + render.EnvPrefix + "* environment variables",
+							},
+							&cli.StringSliceFlag{
+								Name:  "var",
+								Usage: "Set a template variable as Name=value, overriding --values and the environment (repeatable)",
+							},
+							&cli.BoolFlag{
+								Name:  "dry-run",
+								Usage: "Print what would be written without touching disk",
+							},
+							&cli.BoolFlag{
+								Name:  "force",
+								Usage: "Overwrite existing files in the destination",
+							},
+							&cli.BoolFlag{
+								Name:  "skip-existing",
+								Usage: "Leave existing files in the destination untouched",
+							},
+							&cli.BoolFlag{
+								Name:  "skip-unchanged",
+								Usage: "Leave existing files whose content already matches untouched",
+							},
+							templateFlag(),
+							includeFlag(),
+							ignoreFlag(),
+							gitignoreFlag(),
+							manifestFlag(),
+						},
+						Action: func(ctx context.Context, cmd *cli.Command) error {
+							opts, err := buildOptions(cmd)
+							if err != nil {
+								return err
+							}
+
+							root, err := buildGraph(ctx, cmd, opts)
+							if err != nil {
+								return fmt.Errorf("failed to build graph: %w", err)
+							}
+
+							// Undefined variables fail the render rather than prompting
+							root, err = renderGraph(ctx, root, cmd, false)
+							if err != nil {
+								return err
+							}
+
+							return writeDest(ctx, cmd, root, "Rendered")
+						},
+					},
+					{
+						Name:  "render-file",
+						Usage: "Render a single template file to stdout, as it would render within a template directory",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "file",
+								Aliases:  []string{"f"},
+								Usage:    "Path to the template file",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "values",
+								Usage: "YAML or JSON file of template variables, overridden by " + render.EnvPrefix + "* environment variables",
+							},
+							&cli.StringSliceFlag{
+								Name:  "var",
+								Usage: "Set a template variable as Name=value, overriding --values and the environment (repeatable)",
+							},
+						},
+						Action: func(ctx context.Context, cmd *cli.Command) error {
+							return renderFile(ctx, cmd)
+						},
+					},
 				},
 			},
 		},
 	}
 
-	if err := app.Run(context.Background(), os.Args); err != nil {
+	// Interrupting stops a build or write between entries
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := app.Run(ctx, os.Args); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// templateFlag selects a named template when --path points at a template catalog.
+func templateFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "template",
+		Usage: "Name of a template to use when --path is a template catalog",
+	}
+}
+
+// cacheFlag enables the on-disk hash cache.
+func cacheFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "cache",
+		Usage: "Path to a hash cache file reused across builds",
+	}
+}
+
+// includeFlag limits a build to files matching glob patterns.
+func includeFlag() cli.Flag {
+	return &cli.StringSliceFlag{
+		Name:  "include",
+		Usage: "Only include files matching this glob pattern (repeatable)",
+	}
+}
+
+// ignoreFlag excludes paths matching gitignore-style patterns.
+func ignoreFlag() cli.Flag {
+	return &cli.StringSliceFlag{
+		Name:  "ignore",
+		Usage: "Exclude paths matching this gitignore-style pattern (repeatable)",
+	}
+}
+
+// gitignoreFlag reads ignore patterns from the root .gitignore.
+func gitignoreFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  "gitignore",
+		Usage: "Exclude paths matched by the .gitignore at the root of --path",
+	}
+}
+
+// manifestFlag applies the manifest at the root of the build.
+func manifestFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  "ska-yaml",
+		Usage: "Apply the file rules in the .ska.yaml at the root of --path",
+	}
+}
+
+// maxDepthFlag limits how deep a build descends.
+func maxDepthFlag() cli.Flag {
+	return &cli.IntFlag{
+		Name:  "max-depth",
+		Usage: "Do not descend into directories deeper than this (0 for no limit)",
+	}
+}
+
+// maxFileSizeFlag skips reading files above a size.
+func maxFileSizeFlag() cli.Flag {
+	return &cli.Int64Flag{
+		Name:  "max-file-size",
+		Usage: "Do not read files larger than this many bytes (0 for no limit)",
+	}
+}
+
+// buildGraph builds the graph selected by the --path and --template flags.
+func buildGraph(ctx context.Context, cmd *cli.Command, opts fs.BuildOptions) (graph.SkaffoldNode, error) {
+	if name := cmd.String("template"); name != "" {
+		return multi.BuildTemplateWithOptions(ctx, cmd.String("path"), name, opts)
+	}
+	return fs.BuildGraphWithOptions(ctx, cmd.String("path"), opts)
+}
+
+// buildOptions assembles graph build options from the common command flags.
+func buildOptions(cmd *cli.Command) (fs.BuildOptions, error) {
+	opts := fs.BuildOptions{
+		Include:      cmd.StringSlice("include"),
+		Ignore:       cmd.StringSlice("ignore"),
+		UseGitignore: cmd.Bool("gitignore"),
+		UseManifest:  cmd.Bool("ska-yaml"),
+		MaxDepth:     int(cmd.Int("max-depth")),
+		MaxFileSize:  cmd.Int64("max-file-size"),
+	}
+	if cachePath := cmd.String("cache"); cachePath != "" {
+		cache, err := fs.NewHashCache(cachePath)
+		if err != nil {
+			return opts, err
+		}
+		opts.Cache = cache
+	}
+	return opts, nil
+}
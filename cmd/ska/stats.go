@@ -0,0 +1,37 @@
+/*
+Copyright 2025 - Scott Hussey, Jerrod Early
+*/
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// printStats prints the counts in stats, with the breakdowns sorted by key.
+func printStats(stats graph.Statistics) {
+	fmt.Printf("Directories: %d\n", stats.Directories)
+	fmt.Printf("Files: %d\n", stats.Files)
+	for _, action := range sortedKeys(stats.FilesByAction) {
+		fmt.Printf("  %s: %d\n", action, stats.FilesByAction[action])
+	}
+	fmt.Printf("Symlinks: %d\n", stats.Symlinks)
+	fmt.Printf("Total bytes: %d\n", stats.TotalBytes)
+	fmt.Println("Content types:")
+	for _, contentType := range sortedKeys(stats.ContentTypes) {
+		fmt.Printf("  %s: %d\n", contentType, stats.ContentTypes[contentType])
+	}
+}
+
+// sortedKeys returns the keys of counts in order.
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -2,4 +2,7 @@ module github.com/sthussey/ska
 
 go 1.22.4
 
-require github.com/urfave/cli/v3 v3.3.2
+require (
+	github.com/urfave/cli/v3 v3.3.2
+	gopkg.in/yaml.v3 v3.0.1
+)
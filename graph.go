@@ -1,238 +1,112 @@
+/*
+Copyright 2025 - Scott Hussey, Jerrod Early
+*/
+
 package ska
 
 import (
+	"context"
 	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
-)
+	"io"
 
-const NODETYPE_DIRECTORY = "DIRECTORY" //nolint:revive // ignore ST1003
-const NODETYPE_FILE = "FILE"
+	"github.com/sthussey/ska/graph"
+	"github.com/sthussey/ska/render"
+	"github.com/sthussey/ska/sink/console"
+	sinkfs "github.com/sthussey/ska/sink/fs"
+	"github.com/sthussey/ska/source/fs"
+)
 
-type SkaffoldNode interface {
-	Children() []SkaffoldNode
-	AddChild(child SkaffoldNode) error
-	Parent() (SkaffoldNode, error)
-	SetParent(parent SkaffoldNode) error
-	Key() string
-	Type() string
+// Graph is a scaffold graph built for the common workflow of building,
+// inspecting, merging, rendering and writing a scaffold with a single import. Root
+// gives access to the nodes for anything more specialised.
+type Graph struct {
+	root SkaffoldNode
 }
 
-type DirectoryNode struct {
-	name     string         // Name of the file or directory
-	children []SkaffoldNode // Child nodes (nil for files, populated for directories)
-	parent   SkaffoldNode   // Optional: Pointer to the parent node, might be useful later
+// Build walks the directory tree at path and returns its graph.
+func Build(path string) (Graph, error) {
+	return BuildWithOptions(context.Background(), path, BuildOptions{})
 }
 
-// NewDirectoryNode creates a new DirectoryNode.
-func NewDirectoryNode(name string) *DirectoryNode {
-	return &DirectoryNode{
-		name:     name,
-		children: make([]SkaffoldNode, 0), // Initialize slice
+// BuildWithOptions walks the directory tree at path using the provided
+// options, stopping once ctx is done.
+func BuildWithOptions(ctx context.Context, path string, opts BuildOptions) (Graph, error) {
+	root, err := fs.BuildGraphWithOptions(ctx, path, opts)
+	if err != nil {
+		return Graph{}, err
 	}
+	return Graph{root: root}, nil
 }
 
-func NewDirectoryNodeWithParent(name string, parent SkaffoldNode) *DirectoryNode {
-	n := NewDirectoryNode(name)
-	n.parent = parent
-	return n
+// NewGraph wraps the graph rooted at root.
+func NewGraph(root SkaffoldNode) Graph {
+	return Graph{root: root}
 }
 
-func (d *DirectoryNode) Children() []SkaffoldNode {
-	return d.children
+// Root returns the root node of the graph, nil for the zero Graph.
+func (g Graph) Root() SkaffoldNode {
+	return g.root
 }
 
-func (d *DirectoryNode) AddChild(child SkaffoldNode) error {
-	d.children = append(d.children, child)
-	return nil
-}
-
-func (d *DirectoryNode) Parent() (SkaffoldNode, error) {
-	if d.parent == nil {
-		return nil, fmt.Errorf("node %s has no parent", d.name)
+// Print writes the graph to w as an indented tree, as console.PrintGraph does.
+func (g Graph) Print(w io.Writer) error {
+	if g.root == nil {
+		return fmt.Errorf("cannot print an empty graph")
 	}
-	return d.parent, nil
-}
-
-func (d *DirectoryNode) SetParent(parent SkaffoldNode) error {
-	d.parent = parent
-	return nil
-}
-
-func (d *DirectoryNode) Key() string {
-	return d.name // Assuming Name is unique enough for a key within its context
-}
-
-func (d *DirectoryNode) Type() string {
-	return NODETYPE_DIRECTORY
-}
-
-const FILEACTION_COPY = "COPY"
-const FILEACTION_TEMPLATE = "TEMPLATE"
-
-type FileNode struct {
-	name         string
-	action       string
-	data         []byte
-	content_type string
-	parent       SkaffoldNode
+	return console.PrintGraphWithOptions(g.root, w, 0, console.PrintOptions{})
 }
 
-// NewFileNode creates a new FileNode.
-func NewFileNode(name string) *FileNode {
-	// Default action to COPY, can be overridden
-	action := FILEACTION_COPY
-	// Simple check for template files
-	if strings.HasSuffix(name, ".tmpl") {
-		action = FILEACTION_TEMPLATE
+// Render returns a copy of the graph with its TEMPLATE files and templated
+// names executed against vars, as render.Render does.
+func (g Graph) Render(vars map[string]any) (Graph, error) {
+	if g.root == nil {
+		return Graph{}, fmt.Errorf("cannot render an empty graph")
 	}
-	return &FileNode{
-		name:   name,
-		action: action,
+	root, err := render.Render(g.root, vars)
+	if err != nil {
+		return Graph{}, err
 	}
+	return Graph{root: root}, nil
 }
 
-func NewFileNodeWithParent(name string, parent SkaffoldNode) *FileNode {
-	n := NewFileNode(name)
-	n.parent = parent
-	return n
-}
-
-func (f *FileNode) Children() []SkaffoldNode {
-	return []SkaffoldNode{}
+// WriteToDir writes the graph under destRoot, which stands in for the graph
+// root, failing if any target file already exists.
+func (g Graph) WriteToDir(destRoot string) error {
+	return g.WriteToDirWithOptions(context.Background(), destRoot, WriteOptions{})
 }
 
-func (f *FileNode) AddChild(child SkaffoldNode) error {
-	return fmt.Errorf("cannot add child to a file node %s", f.name)
-}
-
-func (f *FileNode) Parent() (SkaffoldNode, error) {
-	if f.parent == nil {
-		return nil, fmt.Errorf("node %s has no parent", f.name)
+// WriteToDirWithOptions writes the graph under destRoot using the provided
+// options, stopping once ctx is done.
+func (g Graph) WriteToDirWithOptions(ctx context.Context, destRoot string, opts WriteOptions) error {
+	if g.root == nil {
+		return fmt.Errorf("cannot write an empty graph to %s", destRoot)
 	}
-	return f.parent, nil
-}
-
-func (f *FileNode) SetParent(parent SkaffoldNode) error {
-	f.parent = parent
-	return nil
-}
-
-func (f *FileNode) Key() string {
-	return f.name // Assuming Name is unique enough for a key within its context
+	return sinkfs.WriteGraphWithOptions(ctx, g.root, destRoot, opts)
 }
 
-func (f *FileNode) Type() string {
-	return NODETYPE_FILE
+// Merge returns the union of control and add, failing on any file whose
+// content differs between them. See graph.Union for how graphs are merged.
+func Merge(control Graph, add ...Graph) (Graph, error) {
+	return MergeWithOptions(control, MergeOptions{}, add...)
 }
 
-func (f *FileNode) Action() string {
-	return f.action
-}
-
-func (f *FileNode) SetAction(action string) error {
-	if action != FILEACTION_COPY && action != FILEACTION_TEMPLATE {
-		return fmt.Errorf("invalid action %s for file %s", action, f.name)
+// MergeWithOptions returns the union of control and add using the provided
+// options. None of the graphs are modified.
+func MergeWithOptions(control Graph, opts MergeOptions, add ...Graph) (Graph, error) {
+	if control.root == nil {
+		return Graph{}, fmt.Errorf("cannot merge into an empty graph")
 	}
-	f.action = action
-	return nil
-}
-
-func (f *FileNode) ContentType() string {
-	return f.content_type
-}
-
-// BuildGraph walks the directory tree starting at rootPath and builds a graph.
-func BuildGraph(rootPath string) (SkaffoldNode, error) {
-	absRootPath, err := filepath.Abs(rootPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path for %s: %w", rootPath, err)
-	}
-
-	// Get info about the root path
-	info, err := os.Stat(absRootPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to stat root path %s: %w", absRootPath, err)
-	}
-	if !info.IsDir() {
-		return nil, fmt.Errorf("root path %s is not a directory", absRootPath)
-	}
-
-	// Create the root node using the base name of the absolute path
-	rootNode := NewDirectoryNode(filepath.Base(absRootPath))
-
-	// Start the recursive walk
-	err = walkDir(absRootPath, rootNode)
-	if err != nil {
-		return nil, err // Error already contains context from walkDir
-	}
-
-	return rootNode, nil
-}
-
-// walkDir recursively walks the directory structure under dirPath
-// and adds nodes to the parentNode.
-func walkDir(dirPath string, parentNode *DirectoryNode) error {
-	entries, err := os.ReadDir(dirPath)
-	if err != nil {
-		return fmt.Errorf("failed to read directory %s: %w", dirPath, err)
-	}
-
-	for _, entry := range entries {
-		// Construct the full path for the current entry
-		fullPath := filepath.Join(dirPath, entry.Name())
-
-		if entry.IsDir() {
-			// Create a new directory node
-			dirNode := NewDirectoryNode(entry.Name())
-
-			// Set parent relationship (error ignored as SetParent currently always returns nil)
-			_ = dirNode.SetParent(parentNode)
-			_ = parentNode.AddChild(dirNode)
-
-			// Recursively walk the subdirectory
-			err = walkDir(fullPath, dirNode)
-			if err != nil {
-				return err // Propagate errors from deeper levels
-			}
-		} else {
-			// Create a new file node
-			fileNode := NewFileNode(entry.Name())
-
-			// Set parent relationship (error ignored as SetParent currently always returns nil)
-			_ = fileNode.SetParent(parentNode)
-			_ = parentNode.AddChild(fileNode)
-
-			// Action is already set in NewFileNode based on extension
-			// You could add more logic here later if needed (e.g., read content type)
+	roots := make([]SkaffoldNode, 0, len(add))
+	for _, g := range add {
+		if g.root == nil {
+			return Graph{}, fmt.Errorf("cannot merge an empty graph")
 		}
+		roots = append(roots, g.root)
 	}
-	return nil
-}
 
-// PrintGraph recursively prints a graph node and its children with indentation
-func PrintGraph(node SkaffoldNode, level int) {
-	// Create indentation based on level
-	indent := strings.Repeat("  ", level)
-	
-	// Print current node
-	nodeType := ""
-	if node.Type() == NODETYPE_DIRECTORY {
-		nodeType = "[DIR]"
-	} else if node.Type() == NODETYPE_FILE {
-		// Try to cast to FileNode to get action
-		if fileNode, ok := node.(interface{ Action() string }); ok {
-			nodeType = fmt.Sprintf("[FILE:%s]", fileNode.Action())
-		} else {
-			nodeType = "[FILE]"
-		}
-	}
-	
-	fmt.Printf("%s%s %s\n", indent, nodeType, node.Key())
-	
-	// Print children recursively
-	for _, child := range node.Children() {
-		PrintGraph(child, level+1)
+	root, err := graph.Union(control.root, opts, roots...)
+	if err != nil {
+		return Graph{}, err
 	}
+	return Graph{root: root}, nil
 }
@@ -0,0 +1,122 @@
+package graph
+
+import "os"
+
+// Builder assembles a graph in code:
+//
+//	root, err := graph.NewBuilder("project").
+//		Dir("cmd", func(b *graph.Builder) {
+//			b.File("main.go.tmpl").Content([]byte("package {{.Package}}\n"))
+//		}).
+//		File("LICENSE").Copy().
+//		Symlink("docs", "cmd").
+//		Build()
+//
+// Errors such as duplicate keys are recorded as the graph is assembled and
+// the first one is returned by Build.
+type Builder struct {
+	dir   *DirectoryNode
+	state *builderState
+}
+
+// builderState is shared by a Builder and the builders of its directories.
+type builderState struct {
+	root *DirectoryNode
+	err  error
+}
+
+// NewBuilder returns a Builder for a graph whose root directory is named name.
+func NewBuilder(name string) *Builder {
+	root := NewDirectoryNode(name)
+	return &Builder{dir: root, state: &builderState{root: root}}
+}
+
+// Dir adds a directory named name and calls fn, if not nil, with a Builder
+// for its contents.
+func (b *Builder) Dir(name string, fn func(b *Builder)) *Builder {
+	dir := NewDirectoryNode(name)
+	b.add(dir)
+	if fn != nil {
+		fn(&Builder{dir: dir, state: b.state})
+	}
+	return b
+}
+
+// File adds an empty file named name, with its action derived from the name
+// as NewFileNode does, and returns a FileBuilder to set it up further.
+func (b *Builder) File(name string) *FileBuilder {
+	file := NewFileNode(name)
+	b.add(file)
+	return &FileBuilder{Builder: b, file: file}
+}
+
+// Symlink adds a symlink named name pointing at target.
+func (b *Builder) Symlink(name, target string) *Builder {
+	b.add(NewSymlinkNode(name, target))
+	return b
+}
+
+// Build returns the root of the assembled graph, or the first error recorded
+// while assembling it.
+func (b *Builder) Build() (SkaffoldNode, error) {
+	if b.state.err != nil {
+		return nil, b.state.err
+	}
+	return b.state.root, nil
+}
+
+// add appends node to the directory being built, recording any error.
+func (b *Builder) add(node SkaffoldNode) {
+	if err := b.dir.AddChild(node); err != nil {
+		b.record(err)
+	}
+}
+
+// record keeps err if it is the first error of the build.
+func (b *Builder) record(err error) {
+	if b.state.err == nil {
+		b.state.err = err
+	}
+}
+
+// FileBuilder sets up a file added by Builder.File. It embeds the Builder of
+// the enclosing directory, so further siblings can be added by chaining.
+type FileBuilder struct {
+	*Builder
+	file *FileNode
+}
+
+// Content sets the content of the file.
+func (f *FileBuilder) Content(data []byte) *FileBuilder {
+	f.file.SetContent(data)
+	return f
+}
+
+// Template marks the file as a TEMPLATE.
+func (f *FileBuilder) Template() *FileBuilder {
+	return f.action(FILEACTION_TEMPLATE)
+}
+
+// Copy marks the file as a COPY.
+func (f *FileBuilder) Copy() *FileBuilder {
+	return f.action(FILEACTION_COPY)
+}
+
+// Mode sets the permission bits of the file.
+func (f *FileBuilder) Mode(mode os.FileMode) *FileBuilder {
+	f.file.SetMode(mode)
+	return f
+}
+
+// Metadata attaches an annotation to the file.
+func (f *FileBuilder) Metadata(key, value string) *FileBuilder {
+	f.file.SetMetadata(key, value)
+	return f
+}
+
+func (f *FileBuilder) action(action string) *FileBuilder {
+	if err := f.file.SetAction(action); err != nil {
+		f.record(err)
+	}
+	return f
+}
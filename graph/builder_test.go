@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	root, err := NewBuilder("project").
+		Dir("cmd", func(b *Builder) {
+			b.File("main.go.tmpl").Content([]byte("package {{.Package}}\n")).Mode(0o755)
+		}).
+		File("LICENSE").Copy().Metadata("license", "MIT").
+		File("config.yaml").Template().
+		Symlink("docs", "cmd").
+		Dir("empty", nil).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if root.Key() != "project" {
+		t.Errorf("root key = %s, want project", root.Key())
+	}
+	want := []string{"LICENSE", "cmd/", "cmd/main.go.tmpl", "config.yaml", "docs", "empty/"}
+	if got := listPaths(root); !reflect.DeepEqual(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+	if got := childKeys(root); !reflect.DeepEqual(got, []string{"cmd", "LICENSE", "config.yaml", "docs", "empty"}) {
+		t.Errorf("children = %v, want them in the order added", got)
+	}
+
+	tests := []struct {
+		path   string
+		action string
+	}{
+		{path: "cmd/main.go.tmpl", action: FILEACTION_TEMPLATE},
+		{path: "LICENSE", action: FILEACTION_COPY},
+		{path: "config.yaml", action: FILEACTION_TEMPLATE},
+	}
+	for _, tt := range tests {
+		node, err := FindByPath(root, tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := node.(*FileNode).Action(); got != tt.action {
+			t.Errorf("%s action = %s, want %s", tt.path, got, tt.action)
+		}
+	}
+
+	node, _ := FindByPath(root, "cmd/main.go.tmpl")
+	main := node.(*FileNode)
+	if main.Size() != int64(len("package {{.Package}}\n")) || main.Mode() != 0o755 {
+		t.Errorf("main.go.tmpl has size %d and mode %v", main.Size(), main.Mode())
+	}
+	parent, err := main.Parent()
+	if err != nil || parent.Key() != "cmd" {
+		t.Errorf("main.go.tmpl parent = %v, %v; want cmd", parent, err)
+	}
+	node, _ = FindByPath(root, "LICENSE")
+	if got := node.(*FileNode).Metadata(); !reflect.DeepEqual(got, map[string]string{"license": "MIT"}) {
+		t.Errorf("LICENSE metadata = %v", got)
+	}
+	node, _ = FindByPath(root, "docs")
+	if got := node.(*SymlinkNode).Target(); got != "cmd" {
+		t.Errorf("docs target = %s, want cmd", got)
+	}
+}
+
+func TestBuilderRecordsFirstError(t *testing.T) {
+	_, err := NewBuilder("project").
+		File("a.txt").
+		Dir("sub", func(b *Builder) {
+			b.File("b.txt").File("b.txt")
+		}).
+		Symlink("a.txt", "sub").
+		Build()
+	if !errors.Is(err, ErrDuplicateChild) {
+		t.Fatalf("Build error = %v, want ErrDuplicateChild", err)
+	}
+	if want := "b.txt in directory sub"; !strings.Contains(err.Error(), want) {
+		t.Errorf("Build error = %v, want the first duplicate, %s", err, want)
+	}
+}
@@ -0,0 +1,125 @@
+package graph
+
+import (
+	"container/list"
+	"io"
+	"sync"
+)
+
+// ContentCache is a bounded cache of file content read through content
+// providers, evicting the least recently used content once it holds more
+// than its byte or entry limit. It lets content that is read repeatedly, as
+// by a render followed by a verify, stay in memory without memory growing
+// with the size of the graph. A ContentCache is safe for concurrent use and
+// may be shared by nodes across graphs.
+type ContentCache struct {
+	mu         sync.Mutex
+	maxBytes   int64
+	maxEntries int
+	size       int64
+	entries    map[string]*list.Element
+	order      *list.List // Front is the most recently used entry
+}
+
+type contentCacheEntry struct {
+	key  string
+	data []byte
+}
+
+// NewContentCache creates a ContentCache holding at most maxBytes bytes of
+// content in at most maxEntries entries. A limit that is not positive does
+// not apply.
+func NewContentCache(maxBytes int64, maxEntries int) *ContentCache {
+	return &ContentCache{
+		maxBytes:   maxBytes,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// Provider returns a ContentProvider that serves the content of provider
+// from the cache under key, reading it through provider and caching it when
+// it is not there. Keys identify content, so providers of the same content
+// may share a key, such as its hash algorithm and hash. Content larger than
+// the byte limit is streamed from provider without being cached.
+func (c *ContentCache) Provider(key string, provider ContentProvider) ContentProvider {
+	return &cachedProvider{cache: c, key: key, provider: provider}
+}
+
+// Len returns the number of entries in the cache.
+func (c *ContentCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Size returns the number of bytes of content in the cache.
+func (c *ContentCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// get returns the content cached under key, marking it as recently used.
+func (c *ContentCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*contentCacheEntry).data, true
+}
+
+// put caches data under key, evicting the least recently used entries until
+// the cache is within its limits.
+func (c *ContentCache) put(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&contentCacheEntry{key: key, data: data})
+	c.size += int64(len(data))
+	for (c.maxBytes > 0 && c.size > c.maxBytes) || (c.maxEntries > 0 && c.order.Len() > c.maxEntries) {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*contentCacheEntry)
+		c.order.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.size -= int64(len(entry.data))
+	}
+}
+
+// cachedProvider is a ContentProvider reading through a ContentCache.
+type cachedProvider struct {
+	cache    *ContentCache
+	key      string
+	provider ContentProvider
+}
+
+func (p *cachedProvider) Open() (io.ReadCloser, error) {
+	if data, ok := p.cache.get(p.key); ok {
+		return BytesProvider(data).Open()
+	}
+	if p.cache.maxBytes > 0 && p.provider.Size() > p.cache.maxBytes {
+		return p.provider.Open()
+	}
+	rc, err := p.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	p.cache.put(p.key, data)
+	return BytesProvider(data).Open()
+}
+
+func (p *cachedProvider) Size() int64 {
+	return p.provider.Size()
+}
@@ -0,0 +1,117 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+// readProvider reads all of the content of provider.
+func readProvider(t *testing.T, provider ContentProvider) string {
+	t.Helper()
+	rc, err := provider.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestContentCache(t *testing.T) {
+	cache := NewContentCache(10, 0)
+	a := &countingProvider{data: []byte("aaaa")}
+	b := &countingProvider{data: []byte("bbbb")}
+	c := &countingProvider{data: []byte("cccc")}
+	pa, pb, pc := cache.Provider("a", a), cache.Provider("b", b), cache.Provider("c", c)
+
+	for i := 0; i < 2; i++ {
+		if got := readProvider(t, pa); got != "aaaa" {
+			t.Fatalf("content = %q, want aaaa", got)
+		}
+	}
+	if a.opens != 1 {
+		t.Errorf("a opened %d times, want 1", a.opens)
+	}
+
+	// Reading b and then a again leaves b the least recently used, so
+	// caching c evicts it
+	readProvider(t, pb)
+	readProvider(t, pa)
+	readProvider(t, pc)
+	if cache.Len() != 2 || cache.Size() != 8 {
+		t.Errorf("cache holds %d entries of %d bytes, want 2 of 8", cache.Len(), cache.Size())
+	}
+	readProvider(t, pa)
+	readProvider(t, pb)
+	if a.opens != 1 || b.opens != 2 {
+		t.Errorf("a opened %d times and b %d, want 1 and 2", a.opens, b.opens)
+	}
+	if pa.Size() != 4 {
+		t.Errorf("Size = %d, want 4", pa.Size())
+	}
+}
+
+func TestContentCacheEntryLimit(t *testing.T) {
+	cache := NewContentCache(0, 2)
+	providers := make([]*countingProvider, 3)
+	for i := range providers {
+		providers[i] = &countingProvider{data: []byte{byte('a' + i)}}
+		readProvider(t, cache.Provider(fmt.Sprint(i), providers[i]))
+	}
+	if cache.Len() != 2 {
+		t.Errorf("cache holds %d entries, want 2", cache.Len())
+	}
+	readProvider(t, cache.Provider("0", providers[0]))
+	readProvider(t, cache.Provider("2", providers[2]))
+	if providers[0].opens != 2 || providers[2].opens != 1 {
+		t.Errorf("providers opened %d and %d times, want 2 and 1", providers[0].opens, providers[2].opens)
+	}
+}
+
+func TestContentCacheSkipsOversizedContent(t *testing.T) {
+	cache := NewContentCache(4, 0)
+	big := &countingProvider{data: []byte("too big")}
+	provider := cache.Provider("big", big)
+	for i := 0; i < 2; i++ {
+		if got := readProvider(t, provider); got != "too big" {
+			t.Fatalf("content = %q, want too big", got)
+		}
+	}
+	if big.opens != 2 || cache.Len() != 0 {
+		t.Errorf("provider opened %d times with %d cache entries, want 2 and 0", big.opens, cache.Len())
+	}
+}
+
+func TestContentCacheConcurrent(t *testing.T) {
+	cache := NewContentCache(64, 8)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprint((i + j) % 20)
+				rc, err := cache.Provider(key, BytesProvider(key+"-content")).Open()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				data, err := io.ReadAll(rc)
+				rc.Close()
+				if err != nil || string(data) != key+"-content" {
+					t.Errorf("content = %q, %v; want %s-content", data, err, key)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if cache.Len() > 8 || cache.Size() > 64 {
+		t.Errorf("cache holds %d entries of %d bytes, over its limits", cache.Len(), cache.Size())
+	}
+}
@@ -0,0 +1,45 @@
+package graph
+
+import "fmt"
+
+// ActionRule assigns Action to files whose slash-separated path matches
+// Pattern, using MatchGlob syntax.
+type ActionRule struct {
+	Pattern string
+	Action  string // FILEACTION_COPY or FILEACTION_TEMPLATE
+}
+
+// Classifier derives the action of new file nodes from their path. The last
+// matching rule wins, and files matching none are COPY. Like an action
+// derived from the name, a classified action gives way to a directory
+// default applied by ApplyDefaultActions.
+type Classifier []ActionRule
+
+// DefaultClassifier marks files named with a .tmpl suffix as templates. It
+// classifies the nodes created by NewFileNode.
+var DefaultClassifier = Classifier{{Pattern: "*.tmpl", Action: FILEACTION_TEMPLATE}}
+
+// Classify returns the action for the file at relPath.
+func (c Classifier) Classify(relPath string) string {
+	action := FILEACTION_COPY
+	for _, rule := range c {
+		if MatchGlob(rule.Pattern, relPath) {
+			action = rule.Action
+		}
+	}
+	return action
+}
+
+// Validate returns an error for the first rule with a malformed pattern or
+// an unknown action.
+func (c Classifier) Validate() error {
+	for _, rule := range c {
+		if err := ValidateGlob(rule.Pattern); err != nil {
+			return err
+		}
+		if rule.Action != FILEACTION_COPY && rule.Action != FILEACTION_TEMPLATE {
+			return fmt.Errorf("invalid action %s for pattern %s", rule.Action, rule.Pattern)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	classifier := append(Classifier{
+		{Pattern: "templates/**", Action: FILEACTION_TEMPLATE},
+		{Pattern: "templates/static/**", Action: FILEACTION_COPY},
+	}, DefaultClassifier...)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "README.md", want: FILEACTION_COPY},
+		{path: "main.go.tmpl", want: FILEACTION_TEMPLATE},
+		{path: "templates/config.yaml", want: FILEACTION_TEMPLATE},
+		{path: "templates/static/logo.png", want: FILEACTION_COPY},
+		// The later .tmpl rule wins over the static directory
+		{path: "templates/static/index.html.tmpl", want: FILEACTION_TEMPLATE},
+	}
+	for _, tt := range tests {
+		if got := classifier.Classify(tt.path); got != tt.want {
+			t.Errorf("Classify(%s) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+
+	if got := Classifier(nil).Classify("main.go.tmpl"); got != FILEACTION_COPY {
+		t.Errorf("empty classifier gave %s, want %s", got, FILEACTION_COPY)
+	}
+}
+
+func TestNewFileNodeWithClassifier(t *testing.T) {
+	classifier := Classifier{{Pattern: "templates/*", Action: FILEACTION_TEMPLATE}}
+	if got := NewFileNodeWithClassifier("a.txt", "templates/a.txt", classifier).Action(); got != FILEACTION_TEMPLATE {
+		t.Errorf("action = %s, want it classified by path", got)
+	}
+	if got := NewFileNode("a.txt.tmpl").Action(); got != FILEACTION_TEMPLATE {
+		t.Errorf("NewFileNode action = %s, want the default classifier applied", got)
+	}
+}
+
+func TestClassifierValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		classifier Classifier
+		wantErr    string
+	}{
+		{name: "default", classifier: DefaultClassifier},
+		{name: "bad pattern", classifier: Classifier{{Pattern: "[", Action: FILEACTION_COPY}}, wantErr: "["},
+		{name: "bad action", classifier: Classifier{{Pattern: "*.txt", Action: "MOVE"}}, wantErr: "invalid action MOVE for pattern *.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.classifier.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package graph
+
+import "maps"
+
+// Clone returns a deep copy of the graph rooted at node. The copy has no
+// parent; file content is shared since it is never modified in place.
+// Nodes of types other than DirectoryNode, FileNode and SymlinkNode are
+// returned as-is.
+func Clone(node SkaffoldNode) SkaffoldNode {
+	switch n := node.(type) {
+	case *DirectoryNode:
+		c := shallowCopyDir(n)
+		for _, child := range n.children {
+			childCopy := Clone(child)
+			_ = childCopy.SetParent(c)
+			c.children = append(c.children, childCopy)
+		}
+		return c
+	case *FileNode:
+		c := *n
+		c.parent = nil
+		c.xattrs = maps.Clone(n.xattrs)
+		c.metadata = maps.Clone(n.metadata)
+		return &c
+	case *SymlinkNode:
+		c := *n
+		c.parent = nil
+		return &c
+	default:
+		return node
+	}
+}
+
+// shallowCopyDir returns a copy of d with no parent and no children.
+func shallowCopyDir(d *DirectoryNode) *DirectoryNode {
+	c := *d
+	c.parent = nil
+	c.xattrs = maps.Clone(d.xattrs)
+	c.metadata = maps.Clone(d.metadata)
+	c.children = make([]SkaffoldNode, 0)
+	return &c
+}
@@ -0,0 +1,140 @@
+package graph
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ContentProvider supplies file content on demand, so large trees need not
+// hold every file in memory. Each call to Open returns a fresh reader over
+// the whole content, which is Size bytes long.
+type ContentProvider interface {
+	Open() (io.ReadCloser, error)
+	Size() int64
+}
+
+// BytesProvider is a ContentProvider over content held in memory.
+type BytesProvider []byte
+
+func (b BytesProvider) Open() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (b BytesProvider) Size() int64 {
+	return int64(len(b))
+}
+
+// funcProvider adapts a function returning the whole content, as given to
+// SetContentResolver.
+type funcProvider struct {
+	resolve func() ([]byte, error)
+	size    int64
+}
+
+func (p funcProvider) Open() (io.ReadCloser, error) {
+	data, err := p.resolve()
+	if err != nil {
+		return nil, err
+	}
+	return BytesProvider(data).Open()
+}
+
+func (p funcProvider) Size() int64 {
+	return p.size
+}
+
+// errProvider fails every Open, as for content that was deliberately skipped.
+type errProvider struct {
+	err  error
+	size int64
+}
+
+func (p errProvider) Open() (io.ReadCloser, error) {
+	return nil, p.err
+}
+
+func (p errProvider) Size() int64 {
+	return p.size
+}
+
+// ContentProvider returns the provider of the file content. Content held in
+// memory is returned as a BytesProvider, so the result is never nil.
+func (f *FileNode) ContentProvider() ContentProvider {
+	if f.data != nil || f.provider == nil {
+		return BytesProvider(f.data)
+	}
+	return f.provider
+}
+
+// SetContentProvider sets the provider that supplies the file content on
+// demand, discarding any content held in memory. The provider should supply
+// the content described by the node's current hash and size.
+func (f *FileNode) SetContentProvider(provider ContentProvider) {
+	f.data = nil
+	f.provider = provider
+	f.content_skipped = false
+}
+
+// SetContentResolver sets a function that supplies the whole file content on
+// demand. It is shorthand for a provider that reads through resolve.
+func (f *FileNode) SetContentResolver(resolve func() ([]byte, error)) {
+	f.SetContentProvider(funcProvider{resolve: resolve, size: f.size})
+}
+
+// Open returns a reader over the file content, streaming it from the content
+// provider when it is not held in memory.
+func (f *FileNode) Open() (io.ReadCloser, error) {
+	return f.ContentProvider().Open()
+}
+
+// Content returns the file content, reading it through the content provider
+// when it is not held in memory. It returns nil if the file has no content.
+func (f *FileNode) Content() ([]byte, error) {
+	if f.data != nil || f.provider == nil {
+		return f.data, nil
+	}
+	rc, err := f.provider.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// LoadContent reads content supplied by a provider into memory, so the node
+// no longer depends on the provider's source remaining available.
+func (f *FileNode) LoadContent() error {
+	if f.data != nil || f.provider == nil {
+		return nil
+	}
+	data, err := f.Content()
+	if err != nil {
+		return err
+	}
+	f.data = data
+	f.provider = nil
+	return nil
+}
+
+// OpenContent returns a reader over the content of the file at the
+// slash-separated path p relative to root, streamed from its content provider
+// when it is not held in memory, so a single file can be served without
+// writing the graph out. It fails if nothing exists at p, wrapping
+// ErrNodeNotFound, or if p names a directory or symlink.
+func OpenContent(root SkaffoldNode, p string) (io.ReadCloser, error) {
+	node, err := FindByPath(root, p)
+	if err != nil {
+		return nil, err
+	}
+	file, ok := node.(*FileNode)
+	if !ok {
+		return nil, fmt.Errorf("cannot open %s: it is a %s, not a file", p, strings.ToLower(node.Type()))
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open content of %s: %w", p, err)
+	}
+	return rc, nil
+}
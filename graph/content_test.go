@@ -0,0 +1,158 @@
+package graph
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"io"
+	"testing"
+)
+
+// countingProvider is a ContentProvider recording how often it is opened.
+type countingProvider struct {
+	data  []byte
+	opens int
+}
+
+func (p *countingProvider) Open() (io.ReadCloser, error) {
+	p.opens++
+	return BytesProvider(p.data).Open()
+}
+
+func (p *countingProvider) Size() int64 {
+	return int64(len(p.data))
+}
+
+func TestContentProvider(t *testing.T) {
+	data := []byte("streamed content\n")
+	provider := &countingProvider{data: data}
+	sum := sha256.Sum256(data)
+
+	f := NewFileNode("a.txt")
+	f.SetContentInfo(sum[:], HASHALGORITHM_SHA256, int64(len(data)), "text/plain")
+	f.SetContentProvider(provider)
+
+	content, err := f.Content()
+	if err != nil || string(content) != string(data) {
+		t.Fatalf("Content = %q, %v; want %q", content, err, data)
+	}
+	if provider.opens != 1 {
+		t.Errorf("provider opened %d times, want 1", provider.opens)
+	}
+
+	// Loading the content detaches the node from its provider
+	if err := f.LoadContent(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Content(); err != nil {
+		t.Fatal(err)
+	}
+	if provider.opens != 2 {
+		t.Errorf("provider opened %d times after LoadContent, want 2", provider.opens)
+	}
+	if _, ok := f.ContentProvider().(BytesProvider); !ok {
+		t.Errorf("ContentProvider after LoadContent is %T, want BytesProvider", f.ContentProvider())
+	}
+}
+
+func TestSameContentStreamsRehash(t *testing.T) {
+	data := []byte("shared content\n")
+	md5Sum := md5.Sum(data)
+	sha256Sum := sha256.Sum256(data)
+
+	a := NewFileNode("a.txt")
+	a.SetContentInfo(md5Sum[:], HASHALGORITHM_MD5, int64(len(data)), "text/plain")
+	provider := &countingProvider{data: data}
+	b := NewFileNode("a.txt")
+	b.SetContentInfo(sha256Sum[:], HASHALGORITHM_SHA256, int64(len(data)), "text/plain")
+	b.SetContentProvider(provider)
+
+	same, err := sameContent(a, b)
+	if err != nil || !same {
+		t.Errorf("sameContent = %v, %v; want the rehashed content to match", same, err)
+	}
+	if provider.opens != 1 {
+		t.Errorf("provider opened %d times, want b streamed once for rehashing", provider.opens)
+	}
+
+	// Hashes with the same algorithm are compared without reading content
+	provider.opens = 0
+	if same, err := sameContent(b, b); err != nil || !same || provider.opens != 0 {
+		t.Errorf("sameContent(b, b) = %v, %v after %d opens; want true without reading", same, err, provider.opens)
+	}
+}
+
+func TestOpenContent(t *testing.T) {
+	root, err := NewBuilder("root").
+		Dir("dir", func(b *Builder) { b.File("a.txt").Content([]byte("hello")) }).
+		Symlink("link", "dir").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := OpenContent(root, "dir/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil || string(content) != "hello" {
+		t.Fatalf("content = %q, %v; want hello", content, err)
+	}
+
+	for _, p := range []string{"dir", "link", "missing", "dir/a.txt/x", "../x"} {
+		if _, err := OpenContent(root, p); err == nil {
+			t.Errorf("OpenContent(%q) succeeded", p)
+		}
+	}
+}
+
+func TestSetContentInfoWithoutContent(t *testing.T) {
+	f := NewFileNode("a.txt")
+	f.SetContent([]byte("hello"))
+	hash := f.DataHash()
+
+	f.SetContentInfo(hash, f.HashAlgorithm(), 5, "text/plain")
+	if _, err := f.Content(); err == nil {
+		t.Fatal("Content succeeded for a file whose content is not available")
+	}
+	if _, err := f.Open(); err == nil {
+		t.Fatal("Open succeeded for a file whose content is not available")
+	}
+	if err := f.LoadContent(); err == nil {
+		t.Fatal("LoadContent succeeded for a file whose content is not available")
+	}
+
+	// A provider set afterwards supplies the content
+	f.SetContentProvider(BytesProvider("hello"))
+	content, err := f.Content()
+	if err != nil || string(content) != "hello" {
+		t.Fatalf("Content = %q, %v; want hello", content, err)
+	}
+}
+
+func TestSetContentInfoEmptyFile(t *testing.T) {
+	empty := NewFileNode("empty")
+	empty.SetContent(nil)
+
+	for _, hash := range [][]byte{nil, empty.DataHash()} {
+		f := NewFileNode("empty")
+		f.SetContentInfo(hash, empty.HashAlgorithm(), 0, "")
+		content, err := f.Content()
+		if err != nil || len(content) != 0 {
+			t.Errorf("Content with hash %x = %q, %v; want empty content", hash, content, err)
+		}
+	}
+}
+
+func TestSetContentInfoRehashFails(t *testing.T) {
+	a := NewFileNode("a")
+	a.SetContent([]byte("one"))
+	b := NewFileNode("a")
+	b.SetContentInfo(bytes.Repeat([]byte{1}, 16), HASHALGORITHM_MD5, 3, a.ContentType())
+
+	if _, err := sameContent(a, b); err == nil {
+		t.Fatal("sameContent succeeded without the content needed to rehash")
+	}
+}
@@ -0,0 +1,59 @@
+package graph
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// ContentTyper detects the content type of a file from its name and up to the
+// first 512 bytes of its content.
+type ContentTyper interface {
+	ContentType(name string, head []byte) string
+}
+
+// ContentTyperFunc adapts an ordinary function to the ContentTyper interface.
+type ContentTyperFunc func(name string, head []byte) string
+
+func (f ContentTyperFunc) ContentType(name string, head []byte) string {
+	return f(name, head)
+}
+
+// DefaultContentTyper is used by SetContent and by filesystem builds that do
+// not set fs.BuildOptions.ContentTyper. It sniffs content with net/http's
+// detection algorithm and may be replaced to change detection package-wide.
+// Content sniffed as plain text is refined by file extension, ignoring any
+// .tmpl suffix, so Go source is text/x-go rather than text/plain.
+var DefaultContentTyper ContentTyper = ContentTyperFunc(func(name string, head []byte) string {
+	sniffed := http.DetectContentType(head)
+	if !strings.HasPrefix(sniffed, "text/plain") {
+		return sniffed
+	}
+	ext := strings.ToLower(path.Ext(strings.TrimSuffix(name, ".tmpl")))
+	if byExtension, ok := textContentTypes[ext]; ok {
+		return byExtension
+	}
+	return sniffed
+})
+
+// textContentTypes maps the extensions of common text formats, which content
+// sniffing reports as plain text, to their content types.
+var textContentTypes = map[string]string{
+	".c":    "text/x-c; charset=utf-8",
+	".css":  "text/css; charset=utf-8",
+	".csv":  "text/csv; charset=utf-8",
+	".go":   "text/x-go; charset=utf-8",
+	".h":    "text/x-c; charset=utf-8",
+	".java": "text/x-java; charset=utf-8",
+	".js":   "text/javascript; charset=utf-8",
+	".json": "application/json",
+	".md":   "text/markdown; charset=utf-8",
+	".py":   "text/x-python; charset=utf-8",
+	".rs":   "text/x-rust; charset=utf-8",
+	".sh":   "text/x-shellscript; charset=utf-8",
+	".toml": "application/toml",
+	".ts":   "text/x-typescript; charset=utf-8",
+	".xml":  "text/xml; charset=utf-8",
+	".yaml": "application/yaml",
+	".yml":  "application/yaml",
+}
@@ -0,0 +1,36 @@
+package graph
+
+import "testing"
+
+func TestDefaultContentTyper(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		{"main.go", "package main\n", "text/x-go; charset=utf-8"},
+		{"main.go.tmpl", "package {{.Name}}\n", "text/x-go; charset=utf-8"},
+		{"README.MD", "# Title\n", "text/markdown; charset=utf-8"},
+		{"config.yml", "key: value\n", "application/yaml"},
+		{"notes.txt", "plain notes\n", "text/plain; charset=utf-8"},
+		{"Makefile", "all:\n", "text/plain; charset=utf-8"},
+		// Sniffed types other than plain text are kept whatever the extension
+		{"page.go", "<!DOCTYPE html><html></html>", "text/html; charset=utf-8"},
+		{"data.json", "\x00\x01\x02\x03", "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DefaultContentTyper.ContentType(tt.name, []byte(tt.head)); got != tt.want {
+				t.Errorf("ContentType(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetContentUsesContentTyper(t *testing.T) {
+	f := NewFileNode("main.go")
+	f.SetContent([]byte("package main\n"))
+	if got := f.ContentType(); got != "text/x-go; charset=utf-8" {
+		t.Errorf("ContentType = %q, want the type for the .go extension", got)
+	}
+}
@@ -0,0 +1,41 @@
+package graph
+
+import "bytes"
+
+// Deduplicate makes the files under root whose content is held in memory
+// share a single copy of it when their content is identical, as with
+// repeated license headers or empty package files. It returns the number of
+// bytes no longer held. Files sharing content read it through a common
+// BytesProvider, so Content returns each caller its own copy and setting the
+// content of one file leaves the others unchanged. Files whose content is
+// supplied lazily or has not been hashed are left as they are.
+func Deduplicate(root SkaffoldNode) (int, error) {
+	shared := make(map[string][]BytesProvider)
+	saved := 0
+	err := Walk(root, func(node SkaffoldNode, depth int, segments []string) error {
+		f, ok := node.(*FileNode)
+		if !ok || f.data == nil || f.datahash == nil {
+			return nil
+		}
+
+		key := f.hash_algorithm + ":" + string(f.datahash)
+		for _, p := range shared[key] {
+			if bytes.Equal(p, f.data) {
+				saved += len(f.data)
+				f.SetContentProvider(p)
+				return nil
+			}
+		}
+
+		// The first file with this content keeps holding it, through the
+		// provider the others share
+		p := BytesProvider(f.data)
+		shared[key] = append(shared[key], p)
+		f.SetContentProvider(p)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return saved, nil
+}
@@ -0,0 +1,58 @@
+package graph
+
+import "testing"
+
+func TestDeduplicate(t *testing.T) {
+	license := "Licensed under the MIT license.\n"
+	root := buildTree(t, map[string]string{
+		"a/LICENSE":  license,
+		"b/LICENSE":  license,
+		"c/LICENSE":  license,
+		"README.md":  "readme",
+		"empty/x.go": "",
+	})
+	lazy := NewFileNode("lazy.txt")
+	lazy.SetContentResolver(func() ([]byte, error) { return []byte(license), nil })
+	_ = root.AddChild(lazy)
+
+	saved, err := Deduplicate(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 2 * len(license); saved != want {
+		t.Errorf("Deduplicate saved %d bytes, want %d", saved, want)
+	}
+
+	files := make(map[string]*FileNode)
+	for _, p := range []string{"a/LICENSE", "b/LICENSE", "c/LICENSE"} {
+		node, err := FindByPath(root, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[p] = node.(*FileNode)
+		content, err := files[p].Content()
+		if err != nil || string(content) != license {
+			t.Errorf("%s content = %q, %v after Deduplicate", p, content, err)
+		}
+	}
+	first := files["a/LICENSE"].ContentProvider().(BytesProvider)
+	for p, f := range files {
+		if shared, ok := f.ContentProvider().(BytesProvider); !ok || &shared[0] != &first[0] {
+			t.Errorf("%s does not share the content of a/LICENSE", p)
+		}
+	}
+	if _, ok := lazy.ContentProvider().(BytesProvider); ok {
+		t.Error("Deduplicate loaded lazily supplied content")
+	}
+
+	// Changing one copy leaves the others alone
+	files["b/LICENSE"].SetContent([]byte("changed"))
+	content, _ := files["a/LICENSE"].Content()
+	if string(content) != license {
+		t.Errorf("a/LICENSE = %q after changing b/LICENSE", content)
+	}
+	content[0] = 'X'
+	if again, _ := files["c/LICENSE"].Content(); string(again) != license {
+		t.Error("modifying returned content changed the shared copy")
+	}
+}
@@ -0,0 +1,80 @@
+package graph
+
+import "fmt"
+
+// Difference returns the nodes of a that are missing from b, or whose file
+// content or symlink target differs, matched by key at each path. Directories
+// with no differences beneath them are pruned, and the returned root is a
+// copy of a's root.
+func Difference(a, b SkaffoldNode) (SkaffoldNode, error) {
+	if a.Type() != NODETYPE_DIRECTORY || b.Type() != NODETYPE_DIRECTORY {
+		return nil, fmt.Errorf("cannot difference %s and %s: roots must be directories", a.Key(), b.Key())
+	}
+
+	result, err := differenceDir(a, b)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// differenceDir returns a copy of directory a containing only its children
+// that differ from those of directory b.
+func differenceDir(a, b SkaffoldNode) (*DirectoryNode, error) {
+	aDir, ok := a.(*DirectoryNode)
+	if !ok {
+		return nil, fmt.Errorf("directory %s has unsupported implementation %T", a.Key(), a)
+	}
+
+	result := shallowCopyDir(aDir)
+
+	bChildren := make(map[string]SkaffoldNode, len(b.Children()))
+	for _, child := range b.Children() {
+		bChildren[child.Key()] = child
+	}
+
+	for _, aChild := range a.Children() {
+		var diff SkaffoldNode
+
+		bChild, ok := bChildren[aChild.Key()]
+		switch {
+		case !ok || bChild.Type() != aChild.Type():
+			// Missing from b entirely, or replaced by a different kind of node
+			diff = Clone(aChild)
+		case aChild.Type() == NODETYPE_DIRECTORY:
+			sub, err := differenceDir(aChild, bChild)
+			if err != nil {
+				return nil, err
+			}
+			if len(sub.Children()) > 0 {
+				diff = sub
+			}
+		case aChild.Type() == NODETYPE_SYMLINK:
+			aLink, aOk := aChild.(*SymlinkNode)
+			bLink, bOk := bChild.(*SymlinkNode)
+			if !aOk || !bOk || aLink.Target() != bLink.Target() {
+				diff = Clone(aChild)
+			}
+		default:
+			aFile, aOk := aChild.(*FileNode)
+			bFile, bOk := bChild.(*FileNode)
+			if !aOk || !bOk {
+				diff = Clone(aChild)
+				break
+			}
+			same, err := sameContent(aFile, bFile)
+			if err != nil {
+				return nil, fmt.Errorf("cannot compare %s: %w", aChild.Key(), err)
+			}
+			if !same {
+				diff = Clone(aChild)
+			}
+		}
+
+		if diff != nil {
+			_ = diff.SetParent(result)
+			result.children = append(result.children, diff)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,150 @@
+package graph
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// buildTree returns a directory named root holding files at the given
+// slash-separated paths, creating intermediate directories as needed.
+func buildTree(t *testing.T, files map[string]string) *DirectoryNode {
+	t.Helper()
+	root := NewDirectoryNode("root")
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		segments := strings.Split(p, "/")
+		dir := root
+		for _, name := range segments[:len(segments)-1] {
+			var next *DirectoryNode
+			for _, child := range dir.Children() {
+				if d, ok := child.(*DirectoryNode); ok && d.Key() == name {
+					next = d
+				}
+			}
+			if next == nil {
+				next = NewDirectoryNode(name)
+				_ = next.SetParent(dir)
+				if err := dir.AddChild(next); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dir = next
+		}
+		file, err := NewFileNodeFull(segments[len(segments)-1], []byte(files[p]), FILEACTION_COPY)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = file.SetParent(dir)
+		if err := dir.AddChild(file); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// listPaths returns the sorted paths below root, with directories marked by a
+// trailing slash.
+func listPaths(root SkaffoldNode) []string {
+	var paths []string
+	var walk func(node SkaffoldNode, prefix string)
+	walk = func(node SkaffoldNode, prefix string) {
+		for _, child := range node.Children() {
+			p := prefix + child.Key()
+			if child.Type() == NODETYPE_DIRECTORY {
+				paths = append(paths, p+"/")
+				walk(child, p+"/")
+				continue
+			}
+			paths = append(paths, p)
+		}
+	}
+	walk(root, "")
+	sort.Strings(paths)
+	return paths
+}
+
+func TestDifference(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b map[string]string
+		want []string
+	}{
+		{
+			name: "identical",
+			a:    map[string]string{"README.md": "x", "src/main.go": "y"},
+			b:    map[string]string{"README.md": "x", "src/main.go": "y"},
+			want: nil,
+		},
+		{
+			name: "missing from b",
+			a:    map[string]string{"README.md": "x", "src/main.go": "y", "docs/index.md": "z"},
+			b:    map[string]string{"README.md": "x"},
+			want: []string{"docs/", "docs/index.md", "src/", "src/main.go"},
+		},
+		{
+			name: "changed content keeps parents",
+			a:    map[string]string{"src/pkg/a.go": "new", "src/pkg/b.go": "same"},
+			b:    map[string]string{"src/pkg/a.go": "old", "src/pkg/b.go": "same"},
+			want: []string{"src/", "src/pkg/", "src/pkg/a.go"},
+		},
+		{
+			name: "only in b is ignored",
+			a:    map[string]string{"README.md": "x"},
+			b:    map[string]string{"README.md": "x", "extra.txt": "y"},
+			want: nil,
+		},
+		{
+			name: "file replaced by directory",
+			a:    map[string]string{"config": "x"},
+			b:    map[string]string{"config/app.yaml": "y"},
+			want: []string{"config"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := Difference(buildTree(t, tt.a), buildTree(t, tt.b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff.Key() != "root" {
+				t.Errorf("root key = %q, want root", diff.Key())
+			}
+			if got := listPaths(diff); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Difference paths = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDifferenceLeavesInputsUnchanged(t *testing.T) {
+	a := buildTree(t, map[string]string{"src/a.go": "new"})
+	b := buildTree(t, map[string]string{"src/a.go": "old"})
+
+	diff, err := Difference(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff == SkaffoldNode(a) || diff.Children()[0] == a.Children()[0] {
+		t.Error("Difference shares nodes with its input")
+	}
+	if got := listPaths(a); !reflect.DeepEqual(got, []string{"src/", "src/a.go"}) {
+		t.Errorf("input changed to %v", got)
+	}
+}
+
+func TestDifferenceRejectsFileRoots(t *testing.T) {
+	file, err := NewFileNodeFull("README.md", []byte("x"), FILEACTION_COPY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Difference(file, NewDirectoryNode("root")); err == nil {
+		t.Error("Difference accepted a file root")
+	}
+}
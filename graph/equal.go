@@ -0,0 +1,110 @@
+package graph
+
+import (
+	"path"
+	"sort"
+)
+
+// EqualOptions controls what EqualWithOptions and EqualDetailedWithOptions
+// compare.
+type EqualOptions struct {
+	// IgnoreActions compares files regardless of their action, as when a
+	// graph built from disk is compared with one read back from a document.
+	IgnoreActions bool
+}
+
+// Equal reports whether two graphs are structurally equivalent. See
+// EqualDetailed for what is compared.
+func Equal(a, b SkaffoldNode) bool {
+	return EqualWithOptions(a, b, EqualOptions{})
+}
+
+// EqualWithOptions reports whether two graphs are structurally equivalent
+// as Equal does, using the provided options.
+func EqualWithOptions(a, b SkaffoldNode, opts EqualOptions) bool {
+	equal, _ := EqualDetailedWithOptions(a, b, opts)
+	return equal
+}
+
+// EqualDetailed compares two graphs recursively, matching children by key
+// without regard to their order. Nodes must agree on key and type, files on
+// action, content type and content hash, and symlinks on target. When the
+// graphs differ it returns false and the slash-separated path of the first
+// differing node in key order, or "." if the roots themselves differ. Files
+// hashed with different algorithms are compared by rehashing their content,
+// and content that cannot be read counts as a difference.
+func EqualDetailed(a, b SkaffoldNode) (bool, string) {
+	return EqualDetailedWithOptions(a, b, EqualOptions{})
+}
+
+// EqualDetailedWithOptions compares two graphs as EqualDetailed does, using
+// the provided options.
+func EqualDetailedWithOptions(a, b SkaffoldNode, opts EqualOptions) (bool, string) {
+	if a.Key() != b.Key() {
+		return false, "."
+	}
+	return equalNodes(a, b, nil, opts)
+}
+
+// equalNodes compares two nodes with the same key, where segments is their path.
+func equalNodes(a, b SkaffoldNode, segments []string, opts EqualOptions) (bool, string) {
+	if a.Type() != b.Type() || !equalLeaves(a, b, opts) {
+		return false, displayPath(segments)
+	}
+
+	aChildren := childrenByKey(a)
+	bChildren := childrenByKey(b)
+
+	// Visit every key from either side in order so the reported path is stable
+	keys := make([]string, 0, len(aChildren)+len(bChildren))
+	for key := range aChildren {
+		keys = append(keys, key)
+	}
+	for key := range bChildren {
+		if _, ok := aChildren[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := append(segments[:len(segments):len(segments)], key)
+		aChild, aOk := aChildren[key]
+		bChild, bOk := bChildren[key]
+		if !aOk || !bOk {
+			return false, path.Join(childPath...)
+		}
+		if equal, diffPath := equalNodes(aChild, bChild, childPath, opts); !equal {
+			return false, diffPath
+		}
+	}
+	return true, ""
+}
+
+// equalLeaves compares the file or symlink attributes of two nodes of the
+// same type. Directories and unknown node types always compare equal here.
+func equalLeaves(a, b SkaffoldNode, opts EqualOptions) bool {
+	switch aNode := a.(type) {
+	case *FileNode:
+		bNode, ok := b.(*FileNode)
+		if !ok || (!opts.IgnoreActions && aNode.Action() != bNode.Action()) {
+			return false
+		}
+		same, err := sameContent(aNode, bNode)
+		return err == nil && same
+	case *SymlinkNode:
+		bNode, ok := b.(*SymlinkNode)
+		return ok && aNode.Target() == bNode.Target()
+	default:
+		return true
+	}
+}
+
+// childrenByKey indexes the children of node by key.
+func childrenByKey(node SkaffoldNode) map[string]SkaffoldNode {
+	children := make(map[string]SkaffoldNode, len(node.Children()))
+	for _, child := range node.Children() {
+		children[child.Key()] = child
+	}
+	return children
+}
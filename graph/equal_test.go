@@ -0,0 +1,162 @@
+package graph
+
+import "testing"
+
+// reverseChildren reverses the order of the children of every directory
+// below node.
+func reverseChildren(node SkaffoldNode) {
+	dir, ok := node.(*DirectoryNode)
+	if !ok {
+		return
+	}
+	for i, j := 0, len(dir.children)-1; i < j; i, j = i+1, j-1 {
+		dir.children[i], dir.children[j] = dir.children[j], dir.children[i]
+	}
+	for _, child := range dir.children {
+		reverseChildren(child)
+	}
+}
+
+func TestEqualDetailed(t *testing.T) {
+	files := map[string]string{"README.md": "r", "src/a.go": "a", "src/b.go": "b"}
+
+	tests := []struct {
+		name     string
+		modify   func(t *testing.T, root *DirectoryNode)
+		want     bool
+		wantPath string
+	}{
+		{
+			name:   "identical",
+			modify: func(t *testing.T, root *DirectoryNode) {},
+			want:   true,
+		},
+		{
+			name:   "children in another order",
+			modify: func(t *testing.T, root *DirectoryNode) { reverseChildren(root) },
+			want:   true,
+		},
+		{
+			name: "different content",
+			modify: func(t *testing.T, root *DirectoryNode) {
+				node, err := FindByPath(root, "src/b.go")
+				if err != nil {
+					t.Fatal(err)
+				}
+				node.(*FileNode).SetContent([]byte("changed"))
+			},
+			wantPath: "src/b.go",
+		},
+		{
+			name: "different action",
+			modify: func(t *testing.T, root *DirectoryNode) {
+				node, err := FindByPath(root, "README.md")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := node.(*FileNode).SetAction(FILEACTION_TEMPLATE); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantPath: "README.md",
+		},
+		{
+			name: "extra node",
+			modify: func(t *testing.T, root *DirectoryNode) {
+				if err := root.AddChild(NewSymlinkNode("link", "src")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantPath: "link",
+		},
+		{
+			name: "file replaced by directory",
+			modify: func(t *testing.T, root *DirectoryNode) {
+				if err := root.ReplaceChild(NewDirectoryNode("README.md")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantPath: "README.md",
+		},
+		{
+			name:     "different root key",
+			modify:   func(t *testing.T, root *DirectoryNode) { root.name = "other" },
+			wantPath: ".",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := buildTree(t, files)
+			b := buildTree(t, files)
+			tt.modify(t, b)
+
+			equal, diffPath := EqualDetailed(a, b)
+			if equal != tt.want || diffPath != tt.wantPath {
+				t.Errorf("EqualDetailed = %v, %q; want %v, %q", equal, diffPath, tt.want, tt.wantPath)
+			}
+			if Equal(b, a) != tt.want {
+				t.Errorf("Equal is not symmetric for %s", tt.name)
+			}
+		})
+	}
+}
+
+func TestEqualSymlinks(t *testing.T) {
+	a := NewDirectoryNode("root")
+	b := NewDirectoryNode("root")
+	if err := a.AddChild(NewSymlinkNode("link", "src")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddChild(NewSymlinkNode("link", "docs")); err != nil {
+		t.Fatal(err)
+	}
+	if equal, diffPath := EqualDetailed(a, b); equal || diffPath != "link" {
+		t.Errorf("EqualDetailed = %v, %q; want symlinks with different targets to differ at link", equal, diffPath)
+	}
+}
+
+func TestEqualWithOptions(t *testing.T) {
+	// As built from disk, with the action derived from the .tmpl suffix
+	built, err := NewBuilder("root").
+		Dir("cmd", func(b *Builder) { b.File("main.go.tmpl").Content([]byte("package {{.Package}}\n")) }).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// As read back from a document recording the file as COPY
+	stored, err := NewBuilder("root").
+		Dir("cmd", func(b *Builder) { b.File("main.go.tmpl").Content([]byte("package {{.Package}}\n")).Copy() }).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err := NewBuilder("root").
+		Dir("cmd", func(b *Builder) { b.File("main.go.tmpl").Content([]byte("package main\n")).Copy() }).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		other    SkaffoldNode
+		opts     EqualOptions
+		want     bool
+		wantPath string
+	}{
+		{name: "actions compared", other: stored, want: false, wantPath: "cmd/main.go.tmpl"},
+		{name: "actions ignored", other: stored, opts: EqualOptions{IgnoreActions: true}, want: true},
+		{name: "content still compared", other: changed, opts: EqualOptions{IgnoreActions: true}, want: false, wantPath: "cmd/main.go.tmpl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			equal, diffPath := EqualDetailedWithOptions(built, tt.other, tt.opts)
+			if equal != tt.want || diffPath != tt.wantPath {
+				t.Errorf("EqualDetailedWithOptions = %v, %q; want %v, %q", equal, diffPath, tt.want, tt.wantPath)
+			}
+			if got := EqualWithOptions(built, tt.other, tt.opts); got != tt.want {
+				t.Errorf("EqualWithOptions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Filter returns a copy of the graph rooted at root keeping only the files
+// and symlinks whose slash-separated path relative to root matches at least
+// one include pattern, or any path when include is empty, and no exclude
+// pattern. Patterns use MatchGlob syntax. Directories are kept only when
+// something beneath them is kept, though the root itself always is.
+func Filter(root SkaffoldNode, include, exclude []string) (SkaffoldNode, error) {
+	rootDir, ok := root.(*DirectoryNode)
+	if !ok {
+		return nil, fmt.Errorf("cannot filter %s: root must be a directory", root.Key())
+	}
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if err := ValidateGlob(pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	f := filter{include: include, exclude: exclude}
+	return f.dir(rootDir, nil)
+}
+
+// filter holds the patterns of a Filter call.
+type filter struct {
+	include []string
+	exclude []string
+}
+
+// dir returns a copy of d holding only its kept descendants.
+func (f filter) dir(d *DirectoryNode, segments []string) (*DirectoryNode, error) {
+	result := shallowCopyDir(d)
+	for _, child := range d.children {
+		childSegments := append(segments[:len(segments):len(segments)], child.Key())
+
+		var kept SkaffoldNode
+		switch c := child.(type) {
+		case *DirectoryNode:
+			sub, err := f.dir(c, childSegments)
+			if err != nil {
+				return nil, err
+			}
+			if len(sub.children) > 0 {
+				kept = sub
+			}
+		case *FileNode, *SymlinkNode:
+			if f.keep(strings.Join(childSegments, "/")) {
+				kept = Clone(c)
+			}
+		default:
+			return nil, fmt.Errorf("cannot filter node %s of type %s", child.Key(), child.Type())
+		}
+
+		if kept != nil {
+			_ = kept.SetParent(result)
+			if err := result.AddChild(kept); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// keep reports whether the leaf at relPath passes the patterns.
+func (f filter) keep(relPath string) bool {
+	for _, pattern := range f.exclude {
+		if MatchGlob(pattern, relPath) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, pattern := range f.include {
+		if MatchGlob(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
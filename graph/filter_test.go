@@ -0,0 +1,81 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	files := map[string]string{
+		"README.md":           "",
+		"src/main.go":         "",
+		"src/main_test.go":    "",
+		"src/pkg/util.go":     "",
+		"docs/guide/intro.md": "",
+		"vendor/lib/lib.go":   "",
+	}
+
+	tests := []struct {
+		name             string
+		include, exclude []string
+		want             []string
+	}{
+		{
+			name: "no patterns",
+			want: listPaths(buildTree(t, files)),
+		},
+		{
+			name:    "include by name at any depth",
+			include: []string{"*.md"},
+			want:    []string{"README.md", "docs/", "docs/guide/", "docs/guide/intro.md"},
+		},
+		{
+			name:    "include anchored with globstar",
+			include: []string{"src/**"},
+			want:    []string{"src/", "src/main.go", "src/main_test.go", "src/pkg/", "src/pkg/util.go"},
+		},
+		{
+			name:    "exclude wins over include",
+			include: []string{"*.go"},
+			exclude: []string{"*_test.go", "vendor/**"},
+			want:    []string{"src/", "src/main.go", "src/pkg/", "src/pkg/util.go"},
+		},
+		{
+			name:    "nothing kept",
+			include: []string{"*.rs"},
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := buildTree(t, files)
+			filtered, err := Filter(root, tt.include, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filtered.Key() != root.Key() {
+				t.Errorf("root key = %q, want %q", filtered.Key(), root.Key())
+			}
+			if got := listPaths(filtered); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Filter kept %v, want %v", got, tt.want)
+			}
+			// The input graph is left untouched
+			if got, want := listPaths(root), listPaths(buildTree(t, files)); !reflect.DeepEqual(got, want) {
+				t.Errorf("input graph changed to %v", got)
+			}
+		})
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	if _, err := Filter(buildTree(t, map[string]string{"a": ""}), []string{"[unterminated"}, nil); err == nil {
+		t.Error("Filter accepted a malformed pattern")
+	}
+	file, err := NewFileNodeFull("a", nil, FILEACTION_COPY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Filter(file, nil, nil); err == nil {
+		t.Error("Filter accepted a file root")
+	}
+}
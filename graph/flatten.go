@@ -0,0 +1,36 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PathNode pairs a leaf of a graph with its slash-separated path relative to
+// the root.
+type PathNode struct {
+	Path string
+	Node SkaffoldNode
+}
+
+// Flatten returns every leaf below root, meaning its files, symlinks and
+// empty directories, sorted lexicographically by path. The root itself is
+// never included, so an empty root yields no entries.
+func Flatten(root SkaffoldNode) ([]PathNode, error) {
+	if root.Type() != NODETYPE_DIRECTORY {
+		return nil, fmt.Errorf("cannot flatten %s: root must be a directory", root.Key())
+	}
+
+	var leaves []PathNode
+	_ = Walk(root, func(node SkaffoldNode, depth int, segments []string) error {
+		if depth > 0 && len(node.Children()) == 0 {
+			leaves = append(leaves, PathNode{Path: strings.Join(segments, "/"), Node: node})
+		}
+		return nil
+	})
+
+	sort.Slice(leaves, func(i, j int) bool {
+		return leaves[i].Path < leaves[j].Path
+	})
+	return leaves, nil
+}
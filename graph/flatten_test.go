@@ -0,0 +1,44 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	root := buildTree(t, map[string]string{"src/pkg/b.go": "b", "src/a.go": "a", "README.md": "r"})
+	empty := NewDirectoryNode("empty")
+	link := NewSymlinkNode("link", "src")
+	for _, child := range []SkaffoldNode{empty, link} {
+		_ = child.SetParent(root)
+		if err := root.AddChild(child); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	leaves, err := Flatten(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := make([]string, 0, len(leaves))
+	for _, leaf := range leaves {
+		paths = append(paths, leaf.Path)
+	}
+	want := []string{"README.md", "empty", "link", "src/a.go", "src/pkg/b.go"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Flatten paths = %v, want %v", paths, want)
+	}
+	if leaves[1].Node != SkaffoldNode(empty) || leaves[2].Node != SkaffoldNode(link) {
+		t.Error("Flatten did not return the leaf nodes themselves")
+	}
+}
+
+func TestFlattenEmptyAndFileRoots(t *testing.T) {
+	leaves, err := Flatten(NewDirectoryNode("root"))
+	if err != nil || len(leaves) != 0 {
+		t.Errorf("Flatten of an empty root = %v, %v; want no leaves", leaves, err)
+	}
+	if _, err := Flatten(NewFileNode("a")); err == nil {
+		t.Error("Flatten accepted a file root")
+	}
+}
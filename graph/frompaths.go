@@ -0,0 +1,104 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FromPathsOptions controls how FromPaths resolves conflicting entries.
+type FromPathsOptions struct {
+	// AssumeDirsForIntermediates treats an entry listed as a file as a directory
+	// when another entry lies beneath it, rather than reporting a conflict.
+	AssumeDirsForIntermediates bool
+}
+
+// pathKind records which entries declared a path as a file, as a directory,
+// or implied it as a directory by listing something beneath it.
+type pathKind struct {
+	file    string
+	dir     string
+	implied string
+}
+
+// FromPaths builds a graph rooted at a directory named rootName from a list of
+// slash-separated paths relative to the root. Entries ending in "/" are
+// directories and all others are files; missing intermediate directories are
+// created. Children appear in the order their paths are first seen. A path
+// declared as both a file and a directory is an error, as is a file that another
+// entry treats as a directory unless AssumeDirsForIntermediates is set.
+func FromPaths(rootName string, paths []string, opts FromPathsOptions) (SkaffoldNode, error) {
+	kinds := make(map[string]*pathKind)
+	order := make([]string, 0)
+
+	kindOf := func(p string) *pathKind {
+		k, ok := kinds[p]
+		if !ok {
+			k = &pathKind{}
+			kinds[p] = k
+			order = append(order, p)
+		}
+		return k
+	}
+
+	for _, entry := range paths {
+		isDir := strings.HasSuffix(entry, "/")
+		p, err := CleanPath(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path entry %q: %w", entry, err)
+		}
+		if p == "." {
+			if !isDir {
+				return nil, fmt.Errorf("invalid path entry %q: the root cannot be a file", entry)
+			}
+			continue
+		}
+
+		// Ancestors are recorded first so parents always precede their children
+		segments := strings.Split(p, "/")
+		for i := 1; i < len(segments); i++ {
+			k := kindOf(strings.Join(segments[:i], "/"))
+			if k.implied == "" {
+				k.implied = entry
+			}
+		}
+
+		k := kindOf(p)
+		if isDir && k.dir == "" {
+			k.dir = entry
+		} else if !isDir && k.file == "" {
+			k.file = entry
+		}
+	}
+
+	root := NewDirectoryNode(rootName)
+	dirs := map[string]*DirectoryNode{".": root}
+	for _, p := range order {
+		k := kinds[p]
+		if k.file != "" && k.dir != "" {
+			return nil, fmt.Errorf("path %s is a file in entry %q but a directory in entry %q", p, k.file, k.dir)
+		}
+		if k.file != "" && k.implied != "" && !opts.AssumeDirsForIntermediates {
+			return nil, fmt.Errorf("path %s is a file in entry %q but a directory in entry %q", p, k.file, k.implied)
+		}
+
+		parentPath, name := ".", p
+		if idx := strings.LastIndex(p, "/"); idx >= 0 {
+			parentPath, name = p[:idx], p[idx+1:]
+		}
+		parent := dirs[parentPath]
+
+		var node SkaffoldNode
+		if k.dir != "" || k.implied != "" {
+			dirNode := NewDirectoryNode(name)
+			dirs[p] = dirNode
+			node = dirNode
+		} else {
+			node = NewFileNode(name)
+		}
+
+		if err := parent.AddChild(node); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
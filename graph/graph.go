@@ -0,0 +1,582 @@
+package graph
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const NODETYPE_DIRECTORY = "DIRECTORY" //nolint:revive // ignore ST1003
+const NODETYPE_FILE = "FILE"
+
+// Permissions reported by Mode for nodes that have not had one set.
+const (
+	DefaultDirMode  os.FileMode = 0o755
+	DefaultFileMode os.FileMode = 0o644
+)
+
+// ErrDuplicateChild is returned by AddChild when the directory already has a
+// child with the same key.
+var ErrDuplicateChild = errors.New("duplicate child")
+
+// ErrLeafNode is returned when adding a child to, or grafting beneath, a
+// node that cannot hold children, such as a file or symlink.
+var ErrLeafNode = errors.New("node cannot have children")
+
+type SkaffoldNode interface {
+	Children() []SkaffoldNode
+	AddChild(child SkaffoldNode) error
+	Parent() (SkaffoldNode, error)
+	SetParent(parent SkaffoldNode) error
+	Key() string
+	Type() string
+	// IsLeaf reports whether the node can never hold children, so that
+	// AddChild always fails with ErrLeafNode.
+	IsLeaf() bool
+}
+
+type DirectoryNode struct {
+	name     string         // Name of the file or directory
+	children []SkaffoldNode // Child nodes (nil for files, populated for directories)
+	parent   SkaffoldNode   // Optional: Pointer to the parent node, might be useful later
+	xattrs   map[string][]byte
+	metadata map[string]string // Free-form annotations, ignored by sinks that do not understand them
+	size     int64             // Aggregate size of all descendant files, set by ComputeSizes
+	owner    *ownership
+	mode     os.FileMode // Permission bits, zero if unset
+
+	default_action string          // Action applied to descendant files without an explicit action
+	on_collision   CollisionAction // Used by Union for collisions beneath the directory
+	truncated      bool            // True if the children were left out, as when a build stops at a maximum depth
+}
+
+// NewDirectoryNode creates a new DirectoryNode.
+func NewDirectoryNode(name string) *DirectoryNode {
+	return &DirectoryNode{
+		name:     name,
+		children: make([]SkaffoldNode, 0), // Initialize slice
+	}
+}
+
+func NewDirectoryNodeWithParent(name string, parent SkaffoldNode) *DirectoryNode {
+	n := NewDirectoryNode(name)
+	n.parent = parent
+	return n
+}
+
+func (d *DirectoryNode) Children() []SkaffoldNode {
+	return d.children
+}
+
+// Child returns the child with the given key, reporting whether it exists.
+// Its Type tells a directory, file or symlink apart without downcasting.
+func (d *DirectoryNode) Child(key string) (SkaffoldNode, bool) {
+	idx := d.childIndex(key)
+	if idx < 0 {
+		return nil, false
+	}
+	return d.children[idx], true
+}
+
+// AddChild appends child to the directory and makes the directory its parent,
+// so a node grafted in from another graph no longer points back into it. Keys
+// are unique within a directory, so adding a child whose key is already
+// present fails with ErrDuplicateChild; use ReplaceChild to swap an existing
+// child.
+func (d *DirectoryNode) AddChild(child SkaffoldNode) error {
+	if d.childIndex(child.Key()) >= 0 {
+		return fmt.Errorf("%w %s in directory %s", ErrDuplicateChild, child.Key(), d.name)
+	}
+	_ = child.SetParent(d)
+	d.children = append(d.children, child)
+	return nil
+}
+
+// ReplaceChild replaces the existing child with the same key as child,
+// keeping its position among its siblings, and makes the directory its parent.
+func (d *DirectoryNode) ReplaceChild(child SkaffoldNode) error {
+	idx := d.childIndex(child.Key())
+	if idx < 0 {
+		return fmt.Errorf("directory %s has no child %s to replace", d.name, child.Key())
+	}
+	_ = child.SetParent(d)
+	d.children[idx] = child
+	return nil
+}
+
+// RemoveChild removes the child with the given key from the directory and
+// clears its parent, so the removed node can be added elsewhere.
+func (d *DirectoryNode) RemoveChild(key string) error {
+	idx := d.childIndex(key)
+	if idx < 0 {
+		return fmt.Errorf("directory %s has no child %s to remove", d.name, key)
+	}
+	_ = d.children[idx].SetParent(nil)
+	d.children = append(d.children[:idx], d.children[idx+1:]...)
+	return nil
+}
+
+// childIndex returns the index of the child with the given key, or -1.
+func (d *DirectoryNode) childIndex(key string) int {
+	for i, child := range d.children {
+		if child.Key() == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (d *DirectoryNode) IsLeaf() bool {
+	return false
+}
+
+func (d *DirectoryNode) Parent() (SkaffoldNode, error) {
+	if d.parent == nil {
+		return nil, fmt.Errorf("node %s has no parent", d.name)
+	}
+	return d.parent, nil
+}
+
+func (d *DirectoryNode) SetParent(parent SkaffoldNode) error {
+	d.parent = parent
+	return nil
+}
+
+func (d *DirectoryNode) Key() string {
+	return d.name // Assuming Name is unique enough for a key within its context
+}
+
+// SetKey renames the directory. Keys are unique within a directory, so
+// renaming to the key of a sibling fails with ErrDuplicateChild.
+func (d *DirectoryNode) SetKey(key string) error {
+	if err := checkRename(d, d.parent, key); err != nil {
+		return err
+	}
+	d.name = key
+	return nil
+}
+
+func (d *DirectoryNode) Type() string {
+	return NODETYPE_DIRECTORY
+}
+
+// TotalSize returns the aggregate size in bytes of all files under the
+// directory as last computed by ComputeSizes.
+func (d *DirectoryNode) TotalSize() int64 {
+	return d.size
+}
+
+// Truncated reports whether the directory's children were left out of the
+// graph, as when a build stops at a maximum depth.
+func (d *DirectoryNode) Truncated() bool {
+	return d.truncated
+}
+
+func (d *DirectoryNode) SetTruncated(truncated bool) {
+	d.truncated = truncated
+}
+
+// DefaultAction returns the action inherited by files under this directory, or
+// an empty string if the directory does not set one.
+func (d *DirectoryNode) DefaultAction() string {
+	return d.default_action
+}
+
+// SetDefaultAction sets the action inherited by descendant files that have no
+// explicit action. An empty action clears the default.
+func (d *DirectoryNode) SetDefaultAction(action string) error {
+	if action != "" && action != FILEACTION_COPY && action != FILEACTION_TEMPLATE {
+		return fmt.Errorf("invalid default action %s for directory %s", action, d.name)
+	}
+	d.default_action = action
+	return nil
+}
+
+// Owner returns the numeric owner and group captured for the directory, if any.
+func (d *DirectoryNode) Owner() (int, int, bool) {
+	return d.owner.get()
+}
+
+func (d *DirectoryNode) SetOwner(uid, gid int) {
+	d.owner = &ownership{uid: uid, gid: gid}
+}
+
+// CollisionAction returns the action Union takes for collisions beneath the
+// directory that no nearer node overrides, or DefaultOnCollision if unset.
+func (d *DirectoryNode) CollisionAction() CollisionAction {
+	return d.on_collision
+}
+
+func (d *DirectoryNode) SetCollisionAction(action CollisionAction) {
+	d.on_collision = action
+}
+
+// Mode returns the permission bits of the directory, or DefaultDirMode if
+// none were set.
+func (d *DirectoryNode) Mode() os.FileMode {
+	if d.mode == 0 {
+		return DefaultDirMode
+	}
+	return d.mode
+}
+
+// SetMode sets the permission bits of the directory; other bits of mode are
+// ignored.
+func (d *DirectoryNode) SetMode(mode os.FileMode) {
+	d.mode = mode.Perm()
+}
+
+// Xattrs returns the extended attributes captured for the directory, if any.
+func (d *DirectoryNode) Xattrs() map[string][]byte {
+	return d.xattrs
+}
+
+func (d *DirectoryNode) SetXattr(name string, value []byte) {
+	if d.xattrs == nil {
+		d.xattrs = make(map[string][]byte)
+	}
+	d.xattrs[name] = value
+}
+
+// Metadata returns the annotations attached to the directory, if any.
+func (d *DirectoryNode) Metadata() map[string]string {
+	return d.metadata
+}
+
+func (d *DirectoryNode) SetMetadata(key, value string) {
+	if d.metadata == nil {
+		d.metadata = make(map[string]string)
+	}
+	d.metadata[key] = value
+}
+
+const FILEACTION_COPY = "COPY"
+const FILEACTION_TEMPLATE = "TEMPLATE"
+
+// METADATA_CONTENT_ENCODING is the metadata key under which sources record
+// the encoding, such as gzip, that they decompressed file content from.
+const METADATA_CONTENT_ENCODING = "content-encoding"
+
+type FileNode struct {
+	name           string
+	action         string
+	data           []byte
+	content_type   string
+	datahash       []byte
+	hash_algorithm string // Algorithm that produced datahash, one of the HASHALGORITHM_* constants
+	size           int64
+	parent         SkaffoldNode
+	xattrs         map[string][]byte
+	metadata       map[string]string // Free-form annotations, ignored by sinks that do not understand them
+	owner          *ownership
+	mode           os.FileMode     // Permission bits, zero if unset
+	on_collision   CollisionAction // Used by Union when the file collides
+	provider       ContentProvider // Lazily supplies content that is not held in data
+	template_err   error
+	action_set     bool // True once the action is set explicitly rather than derived from the name
+
+	content_skipped bool // True if the content was deliberately not read, as when a build limits file size
+}
+
+// NewFileNode creates a new FileNode whose action is derived from its name
+// by DefaultClassifier.
+func NewFileNode(name string) *FileNode {
+	return NewFileNodeWithClassifier(name, name, DefaultClassifier)
+}
+
+// NewFileNodeWithClassifier creates a new FileNode whose action is derived by
+// classifier from relPath, the slash-separated path of the file relative to
+// the graph root.
+func NewFileNodeWithClassifier(name, relPath string, classifier Classifier) *FileNode {
+	return &FileNode{
+		name:   name,
+		action: classifier.Classify(relPath),
+	}
+}
+
+func NewFileNodeWithParent(name string, parent SkaffoldNode) *FileNode {
+	n := NewFileNode(name)
+	n.parent = parent
+	return n
+}
+
+// NewFileNodeFull creates a new FileNode with the given content and action,
+// computing the content hash and type in one step.
+func NewFileNodeFull(name string, content []byte, action string) (*FileNode, error) {
+	n := NewFileNode(name)
+	if err := n.SetAction(action); err != nil {
+		return nil, err
+	}
+	n.SetContent(content)
+	return n, nil
+}
+
+func (f *FileNode) Children() []SkaffoldNode {
+	return []SkaffoldNode{}
+}
+
+func (f *FileNode) AddChild(child SkaffoldNode) error {
+	return fmt.Errorf("cannot add child %s to file node %s: %w", child.Key(), f.name, ErrLeafNode)
+}
+
+func (f *FileNode) IsLeaf() bool {
+	return true
+}
+
+func (f *FileNode) Parent() (SkaffoldNode, error) {
+	if f.parent == nil {
+		return nil, fmt.Errorf("node %s has no parent", f.name)
+	}
+	return f.parent, nil
+}
+
+func (f *FileNode) SetParent(parent SkaffoldNode) error {
+	f.parent = parent
+	return nil
+}
+
+func (f *FileNode) Key() string {
+	return f.name // Assuming Name is unique enough for a key within its context
+}
+
+// SetKey renames the file. Keys are unique within a directory, so renaming
+// to the key of a sibling fails with ErrDuplicateChild. The action is kept,
+// even when the new name gains or loses a .tmpl suffix.
+func (f *FileNode) SetKey(key string) error {
+	if err := checkRename(f, f.parent, key); err != nil {
+		return err
+	}
+	f.name = key
+	return nil
+}
+
+func (f *FileNode) Type() string {
+	return NODETYPE_FILE
+}
+
+func (f *FileNode) Action() string {
+	return f.action
+}
+
+func (f *FileNode) SetAction(action string) error {
+	if action != FILEACTION_COPY && action != FILEACTION_TEMPLATE {
+		return fmt.Errorf("invalid action %s for file %s", action, f.name)
+	}
+	f.action = action
+	f.action_set = true
+	return nil
+}
+
+func (f *FileNode) ContentType() string {
+	return f.content_type
+}
+
+// SetContent stores data as the file content and updates the content hash and
+// type to match, hashing with DefaultHashAlgorithm.
+func (f *FileNode) SetContent(data []byte) {
+	algorithm := DefaultHashAlgorithm
+	sum, err := hashBytes(algorithm, data)
+	if err != nil {
+		algorithm = HASHALGORITHM_SHA256
+		sum, _ = hashBytes(algorithm, data)
+	}
+
+	f.provider = nil
+	f.content_skipped = false
+	f.data = data
+	f.size = int64(len(data))
+	f.datahash = sum
+	f.hash_algorithm = algorithm
+	f.content_type = DefaultContentTyper.ContentType(f.name, data[:min(len(data), 512)])
+}
+
+// SetContentInfo records the hash, the algorithm that produced it, the size
+// and the content type of file content without the content itself, as when a
+// graph is read from a serialized form. Any stored content or content
+// provider is discarded, and until a provider is set reading the content
+// fails, unless the file is recorded as empty.
+func (f *FileNode) SetContentInfo(datahash []byte, algorithm string, size int64, contentType string) {
+	f.data = nil
+	f.provider = nil
+	f.content_skipped = false
+	f.datahash = datahash
+	f.hash_algorithm = algorithm
+	f.size = size
+	f.content_type = contentType
+	if !f.recordedEmpty() {
+		f.provider = errProvider{err: fmt.Errorf("content of %s is not available", f.name), size: size}
+	}
+}
+
+// recordedEmpty reports whether the recorded size and hash describe a file
+// with no content.
+func (f *FileNode) recordedEmpty() bool {
+	if f.size != 0 {
+		return false
+	}
+	if f.datahash == nil {
+		return true
+	}
+	empty, err := hashBytes(f.hash_algorithm, nil)
+	return err == nil && bytes.Equal(f.datahash, empty)
+}
+
+// Size returns the size of the file content in bytes.
+func (f *FileNode) Size() int64 {
+	return f.size
+}
+
+// DataHash returns the hash of the file content, produced by the algorithm
+// named by HashAlgorithm, or nil if the content has not been hashed.
+func (f *FileNode) DataHash() []byte {
+	return f.datahash
+}
+
+// HashAlgorithm returns the algorithm that produced DataHash.
+func (f *FileNode) HashAlgorithm() string {
+	return f.hash_algorithm
+}
+
+// Xattrs returns the extended attributes captured for the file, if any.
+func (f *FileNode) Xattrs() map[string][]byte {
+	return f.xattrs
+}
+
+func (f *FileNode) SetXattr(name string, value []byte) {
+	if f.xattrs == nil {
+		f.xattrs = make(map[string][]byte)
+	}
+	f.xattrs[name] = value
+}
+
+// Metadata returns the annotations attached to the file, such as hints for
+// tools that generate from the graph, if any.
+func (f *FileNode) Metadata() map[string]string {
+	return f.metadata
+}
+
+func (f *FileNode) SetMetadata(key, value string) {
+	if f.metadata == nil {
+		f.metadata = make(map[string]string)
+	}
+	f.metadata[key] = value
+}
+
+// Owner returns the numeric owner and group captured for the file, if any.
+func (f *FileNode) Owner() (int, int, bool) {
+	return f.owner.get()
+}
+
+func (f *FileNode) SetOwner(uid, gid int) {
+	f.owner = &ownership{uid: uid, gid: gid}
+}
+
+// CollisionAction returns the action Union takes when the file collides, or
+// DefaultOnCollision if unset.
+func (f *FileNode) CollisionAction() CollisionAction {
+	return f.on_collision
+}
+
+func (f *FileNode) SetCollisionAction(action CollisionAction) {
+	f.on_collision = action
+}
+
+// Mode returns the permission bits of the file, or DefaultFileMode if none
+// were set.
+func (f *FileNode) Mode() os.FileMode {
+	if f.mode == 0 {
+		return DefaultFileMode
+	}
+	return f.mode
+}
+
+// SetMode sets the permission bits of the file; other bits of mode are
+// ignored.
+func (f *FileNode) SetMode(mode os.FileMode) {
+	f.mode = mode.Perm()
+}
+
+// checkRename returns an error if node, whose parent is parent, cannot be
+// renamed to key.
+func checkRename(node, parent SkaffoldNode, key string) error {
+	if key == "" || key == "." || key == ".." || strings.Contains(key, "/") {
+		return fmt.Errorf("invalid key %q for node %s", key, node.Key())
+	}
+	dir, ok := parent.(*DirectoryNode)
+	if !ok {
+		return nil
+	}
+	if idx := dir.childIndex(key); idx >= 0 && dir.children[idx] != node {
+		return fmt.Errorf("%w %s in directory %s", ErrDuplicateChild, key, dir.name)
+	}
+	return nil
+}
+
+// ownership holds a numeric owner and group captured from the filesystem.
+type ownership struct {
+	uid int
+	gid int
+}
+
+func (o *ownership) get() (int, int, bool) {
+	if o == nil {
+		return 0, 0, false
+	}
+	return o.uid, o.gid, true
+}
+
+// ContentSkipped reports whether the file's content was deliberately not
+// hashed or read, as when it exceeds a build's maximum file size. Such a file
+// has a size but no hash or content type, and reading its content fails.
+func (f *FileNode) ContentSkipped() bool {
+	return f.content_skipped
+}
+
+// SkipContent records that the content of the file, which is size bytes
+// long, was deliberately not read. Content returns reason from then on.
+func (f *FileNode) SkipContent(size int64, reason error) {
+	f.SetContentInfo(nil, "", size, "")
+	f.provider = errProvider{err: reason, size: size}
+	f.content_skipped = true
+}
+
+// TemplateError returns the parse error recorded for a template file during build, if any.
+func (f *FileNode) TemplateError() error {
+	return f.template_err
+}
+
+// SetTemplateError records the error from parsing the content of a template file.
+func (f *FileNode) SetTemplateError(err error) {
+	f.template_err = err
+}
+
+// ApplyDefaultActions sets the action of every file that has no explicit action
+// to the default of its nearest ancestor directory that declares one. Files
+// with an explicitly set action keep it.
+func ApplyDefaultActions(root SkaffoldNode) {
+	// defaults[d] holds the default in effect for children of the node at depth d
+	defaults := make([]string, 0)
+	_ = Walk(root, func(node SkaffoldNode, depth int, segments []string) error {
+		defaults = defaults[:depth]
+
+		inherited := ""
+		if depth > 0 {
+			inherited = defaults[depth-1]
+		}
+
+		switch n := node.(type) {
+		case *DirectoryNode:
+			if n.DefaultAction() != "" {
+				inherited = n.DefaultAction()
+			}
+		case *FileNode:
+			if !n.action_set && inherited != "" {
+				n.action = inherited
+			}
+		}
+		defaults = append(defaults, inherited)
+		return nil
+	})
+}
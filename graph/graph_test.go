@@ -0,0 +1,248 @@
+package graph
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// childKeys returns the keys of the children of node in order.
+func childKeys(node SkaffoldNode) []string {
+	keys := make([]string, 0, len(node.Children()))
+	for _, child := range node.Children() {
+		keys = append(keys, child.Key())
+	}
+	return keys
+}
+
+func TestAddChildDuplicate(t *testing.T) {
+	dir := NewDirectoryNode("root")
+	if err := dir.AddChild(NewFileNode("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.AddChild(NewDirectoryNode("b")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		child SkaffoldNode
+	}{
+		{"file over file", NewFileNode("a")},
+		{"directory over file", NewDirectoryNode("a")},
+		{"file over directory", NewFileNode("b")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := dir.AddChild(tt.child); !errors.Is(err, ErrDuplicateChild) {
+				t.Errorf("AddChild error = %v, want ErrDuplicateChild", err)
+			}
+		})
+	}
+	if got := childKeys(dir); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("children = %v after rejected adds, want [a b]", got)
+	}
+}
+
+func TestReplaceChild(t *testing.T) {
+	dir := NewDirectoryNode("root")
+	for _, key := range []string{"a", "b", "c"} {
+		if err := dir.AddChild(NewFileNode(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replacement := NewDirectoryNode("b")
+	if err := dir.ReplaceChild(replacement); err != nil {
+		t.Fatal(err)
+	}
+	if got := childKeys(dir); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("children = %v, want the replacement kept in place", got)
+	}
+	if dir.Children()[1] != SkaffoldNode(replacement) {
+		t.Error("ReplaceChild did not swap in the new node")
+	}
+
+	if err := dir.ReplaceChild(NewFileNode("d")); err == nil {
+		t.Error("ReplaceChild succeeded without an existing child to replace")
+	}
+}
+
+func TestChildParents(t *testing.T) {
+	parentOf := func(node SkaffoldNode) SkaffoldNode {
+		t.Helper()
+		parent, err := node.Parent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parent
+	}
+
+	other := NewDirectoryNode("other")
+	file := NewFileNode("a")
+	if err := other.AddChild(file); err != nil {
+		t.Fatal(err)
+	}
+	if parentOf(file) != SkaffoldNode(other) {
+		t.Error("AddChild did not set the parent")
+	}
+
+	// Moving a node into another graph points it at its new parent
+	dir := NewDirectoryNode("root")
+	if err := dir.AddChild(file); err != nil {
+		t.Fatal(err)
+	}
+	if parentOf(file) != SkaffoldNode(dir) {
+		t.Error("AddChild kept the parent from the other graph")
+	}
+
+	// A rejected child keeps its parent
+	spare := NewDirectoryNode("spare")
+	duplicate := NewFileNode("a")
+	if err := spare.AddChild(duplicate); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.AddChild(duplicate); err == nil {
+		t.Fatal("AddChild accepted a duplicate key")
+	}
+	if parentOf(duplicate) != SkaffoldNode(spare) {
+		t.Error("a rejected AddChild changed the parent")
+	}
+
+	replacement := NewDirectoryNode("a")
+	if err := dir.ReplaceChild(replacement); err != nil {
+		t.Fatal(err)
+	}
+	if parentOf(replacement) != SkaffoldNode(dir) {
+		t.Error("ReplaceChild did not set the parent")
+	}
+}
+
+func TestSetKey(t *testing.T) {
+	type renamer interface {
+		SkaffoldNode
+		SetKey(key string) error
+	}
+
+	dir := NewDirectoryNode("root")
+	file := NewFileNode("a.txt")
+	sub := NewDirectoryNode("sub")
+	link := NewSymlinkNode("link", "a.txt")
+	for _, child := range []SkaffoldNode{file, sub, link} {
+		if err := dir.AddChild(child); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		node    renamer
+		key     string
+		want    string
+		wantErr error
+	}{
+		{name: "file", node: file, key: "b.txt", want: "b.txt"},
+		{name: "directory", node: sub, key: "pkg", want: "pkg"},
+		{name: "symlink", node: link, key: "current", want: "current"},
+		{name: "unchanged", node: file, key: "b.txt", want: "b.txt"},
+		{name: "sibling key", node: sub, key: "current", want: "pkg", wantErr: ErrDuplicateChild},
+		{name: "slash", node: file, key: "x/y", want: "b.txt"},
+		{name: "dot dot", node: link, key: "..", want: "current"},
+		{name: "root", node: dir, key: "project", want: "project"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.node.SetKey(tt.key)
+			switch {
+			case tt.want != tt.key && err == nil:
+				t.Errorf("SetKey(%q) succeeded", tt.key)
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Errorf("SetKey(%q) error = %v, want %v", tt.key, err, tt.wantErr)
+			case tt.want == tt.key && err != nil:
+				t.Errorf("SetKey(%q): %v", tt.key, err)
+			}
+			if tt.node.Key() != tt.want {
+				t.Errorf("key = %s, want %s", tt.node.Key(), tt.want)
+			}
+		})
+	}
+
+	if got := childKeys(dir); !reflect.DeepEqual(got, []string{"b.txt", "pkg", "current"}) {
+		t.Errorf("children = %v after renames", got)
+	}
+	if err := dir.AddChild(NewFileNode("b.txt")); !errors.Is(err, ErrDuplicateChild) {
+		t.Errorf("AddChild with a renamed key error = %v, want ErrDuplicateChild", err)
+	}
+}
+
+func TestSetKeyKeepsAction(t *testing.T) {
+	file := NewFileNode("main.go.tmpl")
+	if err := file.SetKey("main.go"); err != nil {
+		t.Fatal(err)
+	}
+	if file.Action() != FILEACTION_TEMPLATE {
+		t.Errorf("action = %s after dropping .tmpl, want %s", file.Action(), FILEACTION_TEMPLATE)
+	}
+}
+
+func TestRemoveChild(t *testing.T) {
+	dir := NewDirectoryNode("root")
+	for _, key := range []string{"a", "b", "c"} {
+		if err := dir.AddChild(NewFileNode(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	removed := dir.Children()[1]
+
+	if err := dir.RemoveChild("b"); err != nil {
+		t.Fatal(err)
+	}
+	if got := childKeys(dir); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("children = %v after removing b, want [a c]", got)
+	}
+	if _, err := removed.Parent(); err == nil {
+		t.Error("RemoveChild kept the parent of the removed node")
+	}
+	if err := dir.RemoveChild("b"); err == nil {
+		t.Error("RemoveChild succeeded without a child to remove")
+	}
+
+	// The removed node can be added back, here or elsewhere
+	if err := dir.AddChild(removed); err != nil {
+		t.Fatal(err)
+	}
+	if got := childKeys(dir); !reflect.DeepEqual(got, []string{"a", "c", "b"}) {
+		t.Errorf("children = %v after adding b back, want [a c b]", got)
+	}
+}
+
+func TestAddChildLeaf(t *testing.T) {
+	tests := []struct {
+		node SkaffoldNode
+		leaf bool
+	}{
+		{node: NewDirectoryNode("dir")},
+		{node: NewFileNode("file"), leaf: true},
+		{node: NewSymlinkNode("link", "file"), leaf: true},
+	}
+	for _, tt := range tests {
+		if tt.node.IsLeaf() != tt.leaf {
+			t.Errorf("%s IsLeaf = %v, want %v", tt.node.Key(), tt.node.IsLeaf(), tt.leaf)
+		}
+		err := tt.node.AddChild(NewFileNode("child"))
+		if tt.leaf != errors.Is(err, ErrLeafNode) {
+			t.Errorf("%s AddChild error = %v, want ErrLeafNode %v", tt.node.Key(), err, tt.leaf)
+		}
+		if tt.leaf && len(tt.node.Children()) != 0 {
+			t.Errorf("%s holds children after a rejected AddChild", tt.node.Key())
+		}
+	}
+
+	root := buildTree(t, map[string]string{"README.md": "r"})
+	if err := Graft(root, "README.md", NewFileNode("x")); !errors.Is(err, ErrLeafNode) {
+		t.Errorf("Graft beneath a file error = %v, want ErrLeafNode", err)
+	}
+	if err := Graft(NewFileNode("root"), ".", NewFileNode("x")); !errors.Is(err, ErrLeafNode) {
+		t.Errorf("Graft into a file root error = %v, want ErrLeafNode", err)
+	}
+}
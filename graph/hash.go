@@ -0,0 +1,140 @@
+package graph
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strconv"
+)
+
+const HASHALGORITHM_MD5 = "MD5"       // Fast but broken; only suitable for interop with existing checksums
+const HASHALGORITHM_SHA1 = "SHA1"     // Weak; only suitable for interop with existing checksums
+const HASHALGORITHM_SHA256 = "SHA256" // Collision resistant and the default
+
+// DefaultHashAlgorithm is the algorithm used by FileNode.SetContent and by
+// filesystem builds that do not set fs.BuildOptions.HashAlgorithm. It must be
+// one of the HASHALGORITHM_* constants; SetContent falls back to SHA-256
+// otherwise.
+var DefaultHashAlgorithm = HASHALGORITHM_SHA256
+
+// NewHasher returns a hash for the named algorithm, one of the
+// HASHALGORITHM_* constants.
+func NewHasher(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case HASHALGORITHM_MD5:
+		return md5.New(), nil
+	case HASHALGORITHM_SHA1:
+		return sha1.New(), nil
+	case HASHALGORITHM_SHA256:
+		return sha256.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown hash algorithm %s", algorithm)
+	}
+}
+
+// hashBytes returns the hash of data using the named algorithm.
+func hashBytes(algorithm string, data []byte) ([]byte, error) {
+	hasher, err := NewHasher(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	hasher.Write(data)
+	return hasher.Sum(nil), nil
+}
+
+// hashWith returns the hash of the file content using the named algorithm,
+// streaming the content only when the recorded hash uses a different one.
+func (f *FileNode) hashWith(algorithm string) ([]byte, error) {
+	if f.hash_algorithm == algorithm {
+		return f.datahash, nil
+	}
+	hasher, err := NewHasher(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	if _, err := io.Copy(hasher, rc); err != nil {
+		return nil, fmt.Errorf("failed to read content of %s: %w", f.name, err)
+	}
+	return hasher.Sum(nil), nil
+}
+
+// sameContent reports whether two file nodes hold the same content. Hashes
+// are only compared when both use the same algorithm; otherwise the content
+// of b is rehashed with the algorithm of a. Files whose content was skipped
+// have no hash to compare, so comparing either is an error rather than a
+// match between two unknown contents.
+func sameContent(a, b *FileNode) (bool, error) {
+	for _, f := range []*FileNode{a, b} {
+		if f.ContentSkipped() {
+			return false, fmt.Errorf("cannot compare content of %s: it was skipped when the graph was built", f.name)
+		}
+	}
+	if a.ContentType() != b.ContentType() {
+		return false, nil
+	}
+	bHash, err := b.hashWith(a.hash_algorithm)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(a.DataHash(), bHash), nil
+}
+
+// GraphHash returns a SHA-256 fingerprint of the graph rooted at root,
+// covering its structure, node keys, file actions and content, and symlink
+// targets. Children are taken in key order, so graphs holding the same nodes
+// hash alike however they were assembled, and content hashed with another
+// algorithm is rehashed with SHA-256. Modes, ownership, metadata and other
+// attributes do not contribute.
+func GraphHash(root SkaffoldNode) ([]byte, error) {
+	hasher := sha256.New()
+	if err := hashNode(hasher, root); err != nil {
+		return nil, err
+	}
+	return hasher.Sum(nil), nil
+}
+
+// hashNode writes the canonical encoding of node and its descendants to w.
+// Every field is length-prefixed so distinct graphs never encode alike.
+func hashNode(w io.Writer, node SkaffoldNode) error {
+	writeField := func(s string) {
+		fmt.Fprintf(w, "%d:%s", len(s), s)
+	}
+
+	writeField(node.Type())
+	writeField(node.Key())
+	switch n := node.(type) {
+	case *DirectoryNode:
+		children := append([]SkaffoldNode(nil), n.children...)
+		sort.SliceStable(children, func(i, j int) bool {
+			return children[i].Key() < children[j].Key()
+		})
+		writeField(strconv.Itoa(len(children)))
+		for _, child := range children {
+			if err := hashNode(w, child); err != nil {
+				return err
+			}
+		}
+	case *FileNode:
+		sum, err := n.hashWith(HASHALGORITHM_SHA256)
+		if err != nil {
+			return fmt.Errorf("failed to hash content of %s: %w", n.name, err)
+		}
+		writeField(n.Action())
+		writeField(string(sum))
+	case *SymlinkNode:
+		writeField(n.Target())
+	default:
+		return fmt.Errorf("cannot hash node %s of type %s", node.Key(), node.Type())
+	}
+	return nil
+}
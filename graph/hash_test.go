@@ -0,0 +1,130 @@
+package graph
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGraphHash(t *testing.T) {
+	files := map[string]string{"README.md": "r", "src/main.go": "m", "src/lib.go": "l"}
+	base, err := GraphHash(buildTree(t, files))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(root *DirectoryNode)
+		same   bool
+	}{
+		{name: "child order", same: true, change: func(root *DirectoryNode) { reverseChildren(root) }},
+		{name: "mode", same: true, change: func(root *DirectoryNode) {
+			file, _ := root.Child("README.md")
+			file.(*FileNode).SetMode(0o600)
+		}},
+		{name: "metadata", same: true, change: func(root *DirectoryNode) { root.SetMetadata("owner", "core") }},
+		{name: "content hashed with MD5", same: true, change: func(root *DirectoryNode) {
+			file, _ := root.Child("README.md")
+			sum := md5.Sum([]byte("r"))
+			file.(*FileNode).SetContentInfo(sum[:], HASHALGORITHM_MD5, 1, "")
+			file.(*FileNode).SetContentResolver(func() ([]byte, error) { return []byte("r"), nil })
+		}},
+		{name: "content", change: func(root *DirectoryNode) {
+			file, _ := root.Child("README.md")
+			file.(*FileNode).SetContent([]byte("changed"))
+		}},
+		{name: "key", change: func(root *DirectoryNode) {
+			file, _ := root.Child("README.md")
+			_ = file.(*FileNode).SetKey("README")
+		}},
+		{name: "action", change: func(root *DirectoryNode) {
+			file, _ := root.Child("README.md")
+			_ = file.(*FileNode).SetAction(FILEACTION_TEMPLATE)
+		}},
+		{name: "new empty directory", change: func(root *DirectoryNode) { _ = root.AddChild(NewDirectoryNode("empty")) }},
+		{name: "symlink", change: func(root *DirectoryNode) { _ = root.AddChild(NewSymlinkNode("link", "src")) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := buildTree(t, files)
+			tt.change(root)
+			got, err := GraphHash(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(got, base) != tt.same {
+				t.Errorf("GraphHash changed = %v, want %v", !bytes.Equal(got, base), !tt.same)
+			}
+		})
+	}
+}
+
+func TestGraphHashSymlinkTarget(t *testing.T) {
+	hashWithTarget := func(target string) []byte {
+		root := NewDirectoryNode("root")
+		_ = root.AddChild(NewSymlinkNode("link", target))
+		sum, err := GraphHash(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sum
+	}
+	if bytes.Equal(hashWithTarget("a"), hashWithTarget("b")) {
+		t.Error("GraphHash ignores symlink targets")
+	}
+}
+
+func TestGraphHashUnreadableContent(t *testing.T) {
+	root := NewDirectoryNode("root")
+	file := NewFileNode("a.txt")
+	file.SetContentInfo([]byte{1, 2, 3}, HASHALGORITHM_MD5, 3, "")
+	file.SetContentResolver(func() ([]byte, error) { return nil, errors.New("gone") })
+	_ = root.AddChild(file)
+	if _, err := GraphHash(root); err == nil || !strings.Contains(err.Error(), "a.txt") {
+		t.Errorf("GraphHash error = %v, want one naming a.txt", err)
+	}
+}
+
+func TestSameContentSkipped(t *testing.T) {
+	skipped := func(size int64) *FileNode {
+		f := NewFileNode("big.bin")
+		f.SkipContent(size, errors.New("too large"))
+		return f
+	}
+	read := NewFileNode("big.bin")
+	read.SetContent([]byte("content"))
+
+	tests := []struct {
+		name string
+		a, b *FileNode
+	}{
+		{"both skipped", skipped(100), skipped(200)},
+		{"control skipped", skipped(100), read},
+		{"added skipped", read, skipped(100)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			same, err := sameContent(tt.a, tt.b)
+			if err == nil || !strings.Contains(err.Error(), "skipped") {
+				t.Errorf("sameContent = %v, %v; want an error naming the skipped content", same, err)
+			}
+
+			a, b := NewDirectoryNode("root"), NewDirectoryNode("root")
+			if err := a.AddChild(tt.a); err != nil {
+				t.Fatal(err)
+			}
+			if err := b.AddChild(tt.b); err != nil {
+				t.Fatal(err)
+			}
+			if Equal(a, b) {
+				t.Error("Equal reports graphs with skipped content as equal")
+			}
+			if _, err := Union(a, MergeOptions{DefaultCollisionAction: OverwriteOnCollision}, b); err == nil {
+				t.Error("Union succeeded comparing skipped content")
+			}
+		})
+	}
+}
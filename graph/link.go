@@ -0,0 +1,41 @@
+package graph
+
+const LINKTYPE_REGULAR = "REGULAR" //nolint:revive // ignore ST1003
+const LINKTYPE_SYMLINK = "SYMLINK"
+
+// SkaffoldLink describes the edge from a directory to one of its children:
+// the name the child is reached by, whether the edge is a plain entry or a
+// symbolic link, and the child itself.
+type SkaffoldLink struct {
+	Name     string
+	LinkType string // LINKTYPE_REGULAR or LINKTYPE_SYMLINK
+	Node     SkaffoldNode
+}
+
+// ChildLink returns the link to the child with the given key, reporting
+// whether it exists.
+func (d *DirectoryNode) ChildLink(key string) (SkaffoldLink, bool) {
+	child, ok := d.Child(key)
+	if !ok {
+		return SkaffoldLink{}, false
+	}
+	return linkTo(child), true
+}
+
+// Links returns the links to the children of the directory, in child order.
+func (d *DirectoryNode) Links() []SkaffoldLink {
+	links := make([]SkaffoldLink, 0, len(d.children))
+	for _, child := range d.children {
+		links = append(links, linkTo(child))
+	}
+	return links
+}
+
+// linkTo returns the link by which a directory reaches child.
+func linkTo(child SkaffoldNode) SkaffoldLink {
+	linkType := LINKTYPE_REGULAR
+	if child.Type() == NODETYPE_SYMLINK {
+		linkType = LINKTYPE_SYMLINK
+	}
+	return SkaffoldLink{Name: child.Key(), LinkType: linkType, Node: child}
+}
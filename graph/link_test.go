@@ -0,0 +1,38 @@
+package graph
+
+import "testing"
+
+func TestDirectoryLinks(t *testing.T) {
+	root, err := NewBuilder("root").
+		File("a.txt").Content([]byte("a")).
+		Dir("dir", func(b *Builder) {}).
+		Symlink("link", "a.txt").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := root.(*DirectoryNode)
+
+	want := map[string]string{"a.txt": LINKTYPE_REGULAR, "dir": LINKTYPE_REGULAR, "link": LINKTYPE_SYMLINK}
+	links := dir.Links()
+	if len(links) != len(want) {
+		t.Fatalf("got %d links, want %d", len(links), len(want))
+	}
+	for i, link := range links {
+		if child := dir.Children()[i]; link.Node != child || link.Name != child.Key() {
+			t.Errorf("link %d is %s to %v, want the child %s", i, link.Name, link.Node, child.Key())
+		}
+		if link.LinkType != want[link.Name] {
+			t.Errorf("%s has link type %s, want %s", link.Name, link.LinkType, want[link.Name])
+		}
+
+		byKey, ok := dir.ChildLink(link.Name)
+		if !ok || byKey != link {
+			t.Errorf("ChildLink(%q) = %v, %v; want %v", link.Name, byKey, ok, link)
+		}
+	}
+
+	if _, ok := dir.ChildLink("missing"); ok {
+		t.Error("ChildLink found a missing key")
+	}
+}
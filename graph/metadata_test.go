@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCloneCopiesMetadata(t *testing.T) {
+	root := buildTree(t, map[string]string{"src/main.go": "package main"})
+	root.SetMetadata("owner", "platform")
+	file, err := FindByPath(root, "src/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.(*FileNode).SetMetadata("generator", "ska")
+
+	clone := Clone(root).(*DirectoryNode)
+	cloneFile, err := FindByPath(clone, "src/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := clone.Metadata(); !reflect.DeepEqual(got, map[string]string{"owner": "platform"}) {
+		t.Errorf("cloned directory metadata = %v", got)
+	}
+	if got := cloneFile.(*FileNode).Metadata(); !reflect.DeepEqual(got, map[string]string{"generator": "ska"}) {
+		t.Errorf("cloned file metadata = %v", got)
+	}
+
+	clone.SetMetadata("owner", "changed")
+	cloneFile.(*FileNode).SetMetadata("extra", "value")
+	if root.Metadata()["owner"] != "platform" {
+		t.Error("changing the cloned directory's metadata changed the original")
+	}
+	if _, ok := file.(*FileNode).Metadata()["extra"]; ok {
+		t.Error("changing the cloned file's metadata changed the original")
+	}
+}
+
+func TestUnionMergesMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  CollisionAction
+		want    string
+		wantErr bool
+	}{
+		{name: "error", action: ErrorOnCollision, wantErr: true},
+		{name: "overwrite", action: OverwriteOnCollision, want: "control"},
+		{name: "yield", action: YieldOnCollision, want: "added"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			control := buildTree(t, map[string]string{"a.txt": "a"})
+			control.SetMetadata("owner", "control")
+			control.SetMetadata("team", "core")
+			added := buildTree(t, map[string]string{"a.txt": "a"})
+			added.SetMetadata("owner", "added")
+			added.SetMetadata("tier", "gold")
+
+			merged, err := Union(control, MergeOptions{DefaultCollisionAction: tc.action}, added)
+			if tc.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "metadata owner") {
+					t.Fatalf("Union error = %v, want a metadata collision", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]string{"owner": tc.want, "team": "core", "tier": "gold"}
+			if got := merged.(*DirectoryNode).Metadata(); !reflect.DeepEqual(got, want) {
+				t.Errorf("merged metadata = %v, want %v", got, want)
+			}
+		})
+	}
+}
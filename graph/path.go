@@ -0,0 +1,123 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// CleanPath normalizes a slash-separated path relative to a graph root,
+// removing empty and "." segments and resolving ".." segments. It returns an
+// error for absolute paths and for any ".." that would climb above the root,
+// which guards against paths from untrusted manifests or archives escaping
+// the scaffold. The root itself is returned as ".".
+func CleanPath(p string) (string, error) {
+	if strings.HasPrefix(p, "/") {
+		return "", fmt.Errorf("path %s must be relative to the root", p)
+	}
+
+	segments := make([]string, 0)
+	for _, segment := range strings.Split(p, "/") {
+		switch segment {
+		case "", ".":
+			continue
+		case "..":
+			if len(segments) == 0 {
+				return "", fmt.Errorf("path %s escapes the root", p)
+			}
+			segments = segments[:len(segments)-1]
+		default:
+			segments = append(segments, segment)
+		}
+	}
+
+	if len(segments) == 0 {
+		return ".", nil
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// ErrNodeNotFound is returned by FindByPath when no node exists at a path.
+var ErrNodeNotFound = errors.New("node not found")
+
+// FindByPath returns the node at the slash-separated path p relative to root,
+// descending one key at a time. An empty path or "." returns root itself. The
+// error names the first segment that could not be found, wrapping
+// ErrNodeNotFound, or the non-directory node that blocked the descent.
+func FindByPath(root SkaffoldNode, p string) (SkaffoldNode, error) {
+	cleaned, err := CleanPath(p)
+	if err != nil {
+		return nil, err
+	}
+	if cleaned == "." {
+		return root, nil
+	}
+
+	node := root
+	segments := strings.Split(cleaned, "/")
+	for i, segment := range segments {
+		if node.Type() != NODETYPE_DIRECTORY {
+			return nil, fmt.Errorf("cannot find %s: %s is a %s, not a directory",
+				cleaned, path.Join(segments[:i]...), strings.ToLower(node.Type()))
+		}
+
+		var next SkaffoldNode
+		for _, child := range node.Children() {
+			if child.Key() == segment {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil, fmt.Errorf("%w: %s has no entry %s", ErrNodeNotFound, displayPath(segments[:i]), segment)
+		}
+		node = next
+	}
+	return node, nil
+}
+
+// Path returns the slash-separated path of node relative to the root of its
+// graph, found by climbing parent links, so that FindByPath on the root
+// returns node again. The root itself, the first node without a parent, is
+// ".". It returns an error when a parent does not hold the node it was
+// reached from among its children, as when a node keeps a stale parent after
+// being copied or moved, and when parent links form a cycle.
+func Path(node SkaffoldNode) (string, error) {
+	var segments []string
+	seen := map[SkaffoldNode]bool{node: true}
+	for {
+		parent, err := node.Parent()
+		if err != nil || parent == nil {
+			break
+		}
+		if seen[parent] {
+			return "", fmt.Errorf("cannot find path of %s: parent links form a cycle at %s", node.Key(), parent.Key())
+		}
+		if !hasChild(parent, node) {
+			return "", fmt.Errorf("cannot find path of %s: %s is not a child of its parent %s", node.Key(), node.Key(), parent.Key())
+		}
+		seen[parent] = true
+		segments = append([]string{node.Key()}, segments...)
+		node = parent
+	}
+	return displayPath(segments), nil
+}
+
+// hasChild reports whether child is one of the children of parent.
+func hasChild(parent, child SkaffoldNode) bool {
+	for _, c := range parent.Children() {
+		if c == child {
+			return true
+		}
+	}
+	return false
+}
+
+// displayPath joins segments for messages, naming the root "." when empty.
+func displayPath(segments []string) string {
+	if len(segments) == 0 {
+		return "."
+	}
+	return path.Join(segments...)
+}
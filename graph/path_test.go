@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFindByPath(t *testing.T) {
+	root := buildTree(t, map[string]string{"src/pkg/a.go": "a", "README.md": "r"})
+
+	tests := []struct {
+		path     string
+		wantKey  string
+		notFound bool
+		wantErr  bool
+	}{
+		{path: "", wantKey: "root"},
+		{path: ".", wantKey: "root"},
+		{path: "src", wantKey: "src"},
+		{path: "src/pkg/a.go", wantKey: "a.go"},
+		{path: "src/./pkg/", wantKey: "pkg"},
+		{path: "/src", wantErr: true},
+		{path: "src/missing", notFound: true},
+		{path: "missing/a.go", notFound: true},
+		{path: "README.md/child", wantErr: true},
+		{path: "../outside", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			node, err := FindByPath(root, tt.path)
+			switch {
+			case tt.notFound:
+				if !errors.Is(err, ErrNodeNotFound) {
+					t.Errorf("FindByPath error = %v, want ErrNodeNotFound", err)
+				}
+			case tt.wantErr:
+				if err == nil || errors.Is(err, ErrNodeNotFound) {
+					t.Errorf("FindByPath error = %v, want an error other than ErrNodeNotFound", err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case node.Key() != tt.wantKey:
+				t.Errorf("FindByPath found %s, want %s", node.Key(), tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestPath(t *testing.T) {
+	root := buildTree(t, map[string]string{"README.md": "r", "src/pkg/lib.go": "l"})
+	for _, p := range []string{".", "README.md", "src", "src/pkg", "src/pkg/lib.go"} {
+		node, err := FindByPath(root, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Path(node)
+		if err != nil {
+			t.Errorf("Path(%s): %v", p, err)
+			continue
+		}
+		if got != p {
+			t.Errorf("Path = %s, want %s", got, p)
+		}
+	}
+}
+
+func TestPathErrors(t *testing.T) {
+	// A node pointing at a parent that does not hold it
+	root := buildTree(t, map[string]string{"src/main.go": "m"})
+	stale := NewFileNode("stale.go")
+	src, _ := root.Child("src")
+	_ = stale.SetParent(src)
+	if _, err := Path(stale); err == nil || !strings.Contains(err.Error(), "stale.go is not a child of its parent src") {
+		t.Errorf("Path of a stale node error = %v", err)
+	}
+
+	// Parent links forming a cycle
+	a := NewDirectoryNode("a")
+	b := NewDirectoryNode("b")
+	_ = a.AddChild(b)
+	_ = b.AddChild(a)
+	if _, err := Path(a); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Path through a cycle error = %v", err)
+	}
+}
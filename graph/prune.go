@@ -0,0 +1,27 @@
+package graph
+
+// PruneEmpty removes the directories under root that have no children,
+// including those left empty once their own empty subdirectories are
+// removed. The root itself is kept even when it ends up empty.
+func PruneEmpty(root SkaffoldNode) {
+	if dir, ok := root.(*DirectoryNode); ok {
+		pruneDir(dir)
+	}
+}
+
+// pruneDir removes the empty subdirectories of d, deepest first.
+func pruneDir(d *DirectoryNode) {
+	kept := d.children[:0]
+	for _, child := range d.children {
+		if sub, ok := child.(*DirectoryNode); ok {
+			pruneDir(sub)
+			if len(sub.children) == 0 {
+				_ = sub.SetParent(nil)
+				continue
+			}
+		}
+		kept = append(kept, child)
+	}
+	clear(d.children[len(kept):])
+	d.children = kept
+}
@@ -0,0 +1,49 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPruneEmpty(t *testing.T) {
+	root, err := NewBuilder("root").
+		Dir("empty", nil).
+		Dir("nested", func(b *Builder) {
+			b.Dir("deeper", func(b *Builder) {
+				b.Dir("deepest", nil)
+			})
+		}).
+		Dir("src", func(b *Builder) {
+			b.File("main.go")
+			b.Dir("gen", nil)
+		}).
+		Symlink("link", "src").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested := root.Children()[1]
+
+	PruneEmpty(root)
+	want := []string{"link", "src/", "src/main.go"}
+	if got := listPaths(root); !reflect.DeepEqual(got, want) {
+		t.Errorf("paths after PruneEmpty = %v, want %v", got, want)
+	}
+	if _, err := nested.Parent(); err == nil {
+		t.Error("a pruned directory kept its parent")
+	}
+}
+
+func TestPruneEmptyKeepsRoot(t *testing.T) {
+	root, err := NewBuilder("root").Dir("empty", nil).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	PruneEmpty(root)
+	if root.Key() != "root" || len(root.Children()) != 0 {
+		t.Errorf("PruneEmpty left %v under %s", listPaths(root), root.Key())
+	}
+
+	// File roots are left alone
+	PruneEmpty(NewFileNode("a.txt"))
+}
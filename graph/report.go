@@ -0,0 +1,34 @@
+package graph
+
+import (
+	"path"
+	"sort"
+)
+
+// CONTENTTYPE_UNKNOWN groups files whose content type was not detected.
+const CONTENTTYPE_UNKNOWN = "unknown"
+
+// GroupByContentType returns the paths of all files in the graph grouped by
+// their detected content type. Paths are slash-separated relative to the root
+// and sorted within each group; files without a content type are grouped
+// under CONTENTTYPE_UNKNOWN.
+func GroupByContentType(root SkaffoldNode) map[string][]string {
+	groups := make(map[string][]string)
+	_ = Walk(root, func(node SkaffoldNode, depth int, segments []string) error {
+		fileNode, ok := node.(*FileNode)
+		if !ok {
+			return nil
+		}
+		contentType := fileNode.ContentType()
+		if contentType == "" {
+			contentType = CONTENTTYPE_UNKNOWN
+		}
+		groups[contentType] = append(groups[contentType], path.Join(segments...))
+		return nil
+	})
+
+	for _, paths := range groups {
+		sort.Strings(paths)
+	}
+	return groups
+}
@@ -0,0 +1,24 @@
+package graph
+
+// ComputeSizes sets the aggregate size of every directory in the graph to the
+// sum of the sizes of all files beneath it, in a single post-order pass.
+func ComputeSizes(root SkaffoldNode) {
+	_ = WalkPostOrder(root, func(node SkaffoldNode, depth int, path []string) error {
+		dirNode, ok := node.(*DirectoryNode)
+		if !ok {
+			return nil
+		}
+
+		// Children are visited first, so their sizes are already final
+		dirNode.size = 0
+		for _, child := range dirNode.Children() {
+			switch c := child.(type) {
+			case *FileNode:
+				dirNode.size += c.Size()
+			case *DirectoryNode:
+				dirNode.size += c.TotalSize()
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,20 @@
+package graph
+
+import "sort"
+
+// SortChildren orders the children of every directory in the graph by key,
+// so graphs assembled in code or by Union walk in the same order as one
+// built from disk. Sinks walk children in order, so sorting makes their
+// output reproducible.
+func SortChildren(root SkaffoldNode) {
+	dir, ok := root.(*DirectoryNode)
+	if !ok {
+		return
+	}
+	sort.SliceStable(dir.children, func(i, j int) bool {
+		return dir.children[i].Key() < dir.children[j].Key()
+	})
+	for _, child := range dir.children {
+		SortChildren(child)
+	}
+}
@@ -0,0 +1,37 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortChildren(t *testing.T) {
+	root, err := NewBuilder("root").
+		File("zeta.txt").
+		Dir("src", func(b *Builder) {
+			b.File("main.go")
+			b.Dir("b", nil)
+			b.File("a.go")
+		}).
+		Symlink("link", "src").
+		File("Alpha.txt").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	SortChildren(root)
+	if got := childKeys(root); !reflect.DeepEqual(got, []string{"Alpha.txt", "link", "src", "zeta.txt"}) {
+		t.Errorf("root children = %v, want them sorted by key", got)
+	}
+	src, err := FindByPath(root, "src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := childKeys(src); !reflect.DeepEqual(got, []string{"a.go", "b", "main.go"}) {
+		t.Errorf("src children = %v, want them sorted by key", got)
+	}
+
+	// File roots are left alone
+	SortChildren(NewFileNode("a.txt"))
+}
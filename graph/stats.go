@@ -0,0 +1,41 @@
+package graph
+
+// Statistics summarizes the shape of a graph.
+type Statistics struct {
+	Directories   int            // Number of directory nodes, including the root
+	Files         int            // Number of file nodes
+	Symlinks      int            // Number of symlink nodes
+	TotalBytes    int64          // Sum of file sizes in bytes
+	FilesByAction map[string]int // Number of files with each FILEACTION_* action
+	ContentTypes  map[string]int // Number of files with each content type, CONTENTTYPE_UNKNOWN if undetected
+}
+
+// Stats counts the nodes of the graph rooted at root, breaking files down by
+// action and by detected content type.
+func Stats(root SkaffoldNode) Statistics {
+	stats := Statistics{
+		FilesByAction: make(map[string]int),
+		ContentTypes:  make(map[string]int),
+	}
+	_ = Walk(root, func(node SkaffoldNode, depth int, segments []string) error {
+		switch n := node.(type) {
+		case *FileNode:
+			stats.Files++
+			stats.TotalBytes += n.Size()
+			stats.FilesByAction[n.Action()]++
+			contentType := n.ContentType()
+			if contentType == "" {
+				contentType = CONTENTTYPE_UNKNOWN
+			}
+			stats.ContentTypes[contentType]++
+		case *SymlinkNode:
+			stats.Symlinks++
+		default:
+			if node.Type() == NODETYPE_DIRECTORY {
+				stats.Directories++
+			}
+		}
+		return nil
+	})
+	return stats
+}
@@ -0,0 +1,62 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	root := buildTree(t, map[string]string{
+		"README.md":      "# demo\n",
+		"src/main.go":    "package main\n",
+		"src/pkg/lib.go": "package pkg\n",
+		"bin/tool":       "\x7fELF\x00\x01",
+	})
+	tmpl, err := NewFileNodeFull("config.yaml", []byte("name: {{ .Name }}\n"), FILEACTION_TEMPLATE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := NewSymlinkNode("latest", "bin/tool")
+	for _, child := range []SkaffoldNode{tmpl, NewFileNode("placeholder"), link} {
+		_ = child.SetParent(root)
+		if err := root.AddChild(child); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := Stats(root)
+	if stats.Directories != 4 || stats.Files != 6 || stats.Symlinks != 1 {
+		t.Errorf("Stats counted %d directories, %d files, %d symlinks; want 4, 6, 1",
+			stats.Directories, stats.Files, stats.Symlinks)
+	}
+	if want := int64(7 + 13 + 12 + 6 + 18); stats.TotalBytes != want {
+		t.Errorf("TotalBytes = %d, want %d", stats.TotalBytes, want)
+	}
+	wantActions := map[string]int{FILEACTION_COPY: 5, FILEACTION_TEMPLATE: 1}
+	if !reflect.DeepEqual(stats.FilesByAction, wantActions) {
+		t.Errorf("FilesByAction = %v, want %v", stats.FilesByAction, wantActions)
+	}
+	total := 0
+	for _, n := range stats.ContentTypes {
+		total += n
+	}
+	if total != stats.Files {
+		t.Errorf("ContentTypes %v counts %d files, want %d", stats.ContentTypes, total, stats.Files)
+	}
+	if stats.ContentTypes[CONTENTTYPE_UNKNOWN] != 1 {
+		t.Errorf("ContentTypes %v, want the placeholder under %s", stats.ContentTypes, CONTENTTYPE_UNKNOWN)
+	}
+	if stats.ContentTypes["application/yaml"] != 1 {
+		t.Errorf("ContentTypes %v, want the template counted as application/yaml", stats.ContentTypes)
+	}
+}
+
+func TestStatsEmpty(t *testing.T) {
+	stats := Stats(NewDirectoryNode("root"))
+	if stats.Directories != 1 || stats.Files != 0 || stats.Symlinks != 0 || stats.TotalBytes != 0 {
+		t.Errorf("Stats of an empty root = %+v", stats)
+	}
+	if len(stats.FilesByAction) != 0 || len(stats.ContentTypes) != 0 {
+		t.Errorf("Stats of an empty root has breakdowns %v, %v", stats.FilesByAction, stats.ContentTypes)
+	}
+}
@@ -0,0 +1,72 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Subtree returns a copy of the directory at the slash-separated path p
+// relative to root, detached from the original graph so it can be used as a
+// standalone root. It fails if nothing exists at p or if p names anything
+// other than a directory.
+func Subtree(root SkaffoldNode, p string) (SkaffoldNode, error) {
+	node, err := FindByPath(root, p)
+	if err != nil {
+		return nil, err
+	}
+	if node.Type() != NODETYPE_DIRECTORY {
+		return nil, fmt.Errorf("cannot extract %s: it is a %s, not a directory", p, strings.ToLower(node.Type()))
+	}
+	return Clone(node), nil
+}
+
+// Graft mounts a copy of sub as a child of the directory at the
+// slash-separated path p relative to root, creating missing directories along
+// the way. If the directory already has a child with the key of sub, the two
+// are merged as Union merges graphs, with collisions resolved by the
+// collision actions set on the nodes involved and their directories and
+// failing otherwise. root is modified in place.
+func Graft(root SkaffoldNode, p string, sub SkaffoldNode) error {
+	cleaned, err := CleanPath(p)
+	if err != nil {
+		return err
+	}
+	if root.IsLeaf() {
+		return fmt.Errorf("cannot graft into %s: root must be a directory: %w", root.Key(), ErrLeafNode)
+	}
+	dir, ok := root.(*DirectoryNode)
+	if !ok {
+		return fmt.Errorf("cannot graft into %s: unsupported implementation %T", root.Key(), root)
+	}
+
+	inherited := firstCollisionAction(dir, nil, DefaultOnCollision)
+	var segments []string
+	if cleaned != "." {
+		for _, segment := range strings.Split(cleaned, "/") {
+			segments = append(segments, segment)
+
+			idx := dir.childIndex(segment)
+			if idx < 0 {
+				next := NewDirectoryNode(segment)
+				if err := dir.AddChild(next); err != nil {
+					return err
+				}
+				dir = next
+				continue
+			}
+
+			if dir.children[idx].IsLeaf() {
+				return fmt.Errorf("cannot graft into %s: %s is a %s, not a directory: %w",
+					cleaned, displayPath(segments), strings.ToLower(dir.children[idx].Type()), ErrLeafNode)
+			}
+			next, ok := dir.children[idx].(*DirectoryNode)
+			if !ok {
+				return fmt.Errorf("cannot graft into %s: unsupported implementation %T", cleaned, dir.children[idx])
+			}
+			dir = next
+			inherited = firstCollisionAction(dir, nil, inherited)
+		}
+	}
+
+	return mergeChild(dir, sub, append(segments, sub.Key()), inherited, MergeOptions{})
+}
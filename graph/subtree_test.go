@@ -0,0 +1,119 @@
+package graph
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSubtree(t *testing.T) {
+	root := buildTree(t, map[string]string{
+		"README.md":         "r",
+		"src/main.go":       "m",
+		"src/pkg/lib.go":    "l",
+		"src/pkg/lib_test":  "t",
+		"docs/guide/api.md": "a",
+	})
+
+	sub, err := Subtree(root, "src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Key() != "src" {
+		t.Errorf("Subtree root key = %s, want src", sub.Key())
+	}
+	if _, err := sub.Parent(); err == nil {
+		t.Error("Subtree root still has a parent")
+	}
+	want := []string{"main.go", "pkg/", "pkg/lib.go", "pkg/lib_test"}
+	if got := listPaths(sub); !reflect.DeepEqual(got, want) {
+		t.Errorf("Subtree paths = %v, want %v", got, want)
+	}
+
+	// The subtree is a copy, so changing it leaves the original alone
+	if err := sub.AddChild(NewFileNode("extra.go")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FindByPath(root, "src/extra.go"); err == nil {
+		t.Error("adding to the subtree changed the original graph")
+	}
+}
+
+func TestSubtreeErrors(t *testing.T) {
+	root := buildTree(t, map[string]string{"src/main.go": "m"})
+	tests := []struct {
+		path    string
+		wantErr string
+	}{
+		{path: "src/main.go", wantErr: "is a file, not a directory"},
+		{path: "missing", wantErr: "missing"},
+	}
+	for _, tt := range tests {
+		_, err := Subtree(root, tt.path)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Subtree(%s) error = %v, want it to contain %q", tt.path, err, tt.wantErr)
+		}
+	}
+}
+
+func TestGraft(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{
+			name: "new directories",
+			path: "vendor/github.com",
+			want: []string{"README.md", "lib/", "lib/lib.go", "src/", "src/main.go",
+				"vendor/", "vendor/github.com/", "vendor/github.com/lib/", "vendor/github.com/lib/lib.go", "vendor/github.com/lib/util.go"},
+		},
+		{
+			name: "merged into existing",
+			path: ".",
+			want: []string{"README.md", "lib/", "lib/lib.go", "lib/util.go", "src/", "src/main.go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := buildTree(t, map[string]string{"README.md": "r", "src/main.go": "m", "lib/lib.go": "l"})
+			sub, err := Subtree(buildTree(t, map[string]string{"lib/lib.go": "l", "lib/util.go": "u"}), "lib")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := Graft(root, tt.path, sub); err != nil {
+				t.Fatal(err)
+			}
+			if got := listPaths(root); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paths after Graft = %v, want %v", got, tt.want)
+			}
+			if got := listPaths(sub); !reflect.DeepEqual(got, []string{"lib.go", "util.go"}) {
+				t.Errorf("Graft changed the grafted graph: %v", got)
+			}
+		})
+	}
+}
+
+func TestGraftErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		sub     map[string]string
+		wantErr string
+	}{
+		{name: "through a file", path: "README.md", sub: map[string]string{"lib/a.go": "a"}, wantErr: "README.md is a file, not a directory"},
+		{name: "content collision", path: ".", sub: map[string]string{"src/main.go": "changed"}, wantErr: "src/main.go"},
+		{name: "escaping path", path: "../outside", sub: map[string]string{"lib/a.go": "a"}, wantErr: ".."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := buildTree(t, map[string]string{"README.md": "r", "src/main.go": "m"})
+			sub := buildTree(t, tt.sub).Children()[0]
+			err := Graft(root, tt.path, sub)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Graft error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package graph
+
+import "fmt"
+
+const NODETYPE_SYMLINK = "SYMLINK"
+
+// SymlinkNode is a symbolic link. The link itself is the node; its target is
+// recorded verbatim and never followed.
+type SymlinkNode struct {
+	name   string
+	target string
+	parent SkaffoldNode
+	owner  *ownership
+}
+
+// NewSymlinkNode creates a new SymlinkNode pointing at target.
+func NewSymlinkNode(name, target string) *SymlinkNode {
+	return &SymlinkNode{
+		name:   name,
+		target: target,
+	}
+}
+
+func (l *SymlinkNode) Children() []SkaffoldNode {
+	return []SkaffoldNode{}
+}
+
+func (l *SymlinkNode) AddChild(child SkaffoldNode) error {
+	return fmt.Errorf("cannot add child %s to symlink node %s: %w", child.Key(), l.name, ErrLeafNode)
+}
+
+func (l *SymlinkNode) IsLeaf() bool {
+	return true
+}
+
+func (l *SymlinkNode) Parent() (SkaffoldNode, error) {
+	if l.parent == nil {
+		return nil, fmt.Errorf("node %s has no parent", l.name)
+	}
+	return l.parent, nil
+}
+
+func (l *SymlinkNode) SetParent(parent SkaffoldNode) error {
+	l.parent = parent
+	return nil
+}
+
+func (l *SymlinkNode) Key() string {
+	return l.name
+}
+
+// SetKey renames the link. Keys are unique within a directory, so renaming
+// to the key of a sibling fails with ErrDuplicateChild.
+func (l *SymlinkNode) SetKey(key string) error {
+	if err := checkRename(l, l.parent, key); err != nil {
+		return err
+	}
+	l.name = key
+	return nil
+}
+
+func (l *SymlinkNode) Type() string {
+	return NODETYPE_SYMLINK
+}
+
+// Target returns the destination of the link as stored in the link itself.
+func (l *SymlinkNode) Target() string {
+	return l.target
+}
+
+// Owner returns the numeric owner and group captured for the link, if any.
+func (l *SymlinkNode) Owner() (int, int, bool) {
+	return l.owner.get()
+}
+
+func (l *SymlinkNode) SetOwner(uid, gid int) {
+	l.owner = &ownership{uid: uid, gid: gid}
+}
@@ -0,0 +1,172 @@
+package graph
+
+import (
+	"fmt"
+)
+
+// Conflict describes a path at which merged graphs changed the same node in
+// different ways. Nodes absent from a graph, or deleted by it, are nil.
+type Conflict struct {
+	Path   string       // Slash-separated path relative to the root
+	Base   SkaffoldNode // The common ancestor, nil when there is none
+	Ours   SkaffoldNode // The node kept in the merged graph
+	Theirs SkaffoldNode
+}
+
+// ThreeWayMerge merges the changes made in ours and theirs since base, as
+// when re-applying an updated template over a project generated from an
+// earlier version. At each path a node changed on only one side, including
+// by adding or deleting it, takes that side's version, and directories
+// changed on both sides are merged entry by entry. A file or symlink changed
+// on both sides in different ways is resolved by its collision action as
+// Union resolves it, with ours as the control side. Unresolved changes keep
+// ours in the result and are reported as conflicts in graph order, rather
+// than failing the merge. Nodes are compared as by Equal. None of the input
+// graphs are modified.
+func ThreeWayMerge(base, ours, theirs SkaffoldNode, opts MergeOptions) (SkaffoldNode, []Conflict, error) {
+	for _, root := range []SkaffoldNode{base, ours, theirs} {
+		if root.Type() != NODETYPE_DIRECTORY {
+			return nil, nil, fmt.Errorf("cannot merge %s: roots must be directories", root.Key())
+		}
+	}
+
+	m := threeWay{opts: opts}
+	result, err := m.dir(base, ours, theirs, nil, firstCollisionAction(ours, theirs, DefaultOnCollision))
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, m.conflicts, nil
+}
+
+// threeWay holds the state of a ThreeWayMerge call.
+type threeWay struct {
+	opts      MergeOptions
+	conflicts []Conflict
+}
+
+// dir merges three versions of a directory at segments, where base may be nil
+// and either ours or theirs is a directory.
+func (m *threeWay) dir(base, ours, theirs SkaffoldNode, segments []string, inherited CollisionAction) (*DirectoryNode, error) {
+	shell := ours
+	if shell == nil {
+		shell = theirs
+	}
+	shellDir, ok := shell.(*DirectoryNode)
+	if !ok {
+		return nil, fmt.Errorf("cannot merge %s: unsupported implementation %T", displayPath(segments), shell)
+	}
+	result := shallowCopyDir(shellDir)
+
+	baseChildren := m.childrenByKey(base)
+	ourChildren := m.childrenByKey(ours)
+	theirChildren := m.childrenByKey(theirs)
+
+	// Entries keep our order, followed by those only theirs holds
+	var keys []string
+	seen := make(map[string]bool)
+	for _, node := range []SkaffoldNode{ours, theirs} {
+		if node == nil {
+			continue
+		}
+		for _, child := range node.Children() {
+			if key := m.key(child.Key()); !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	for _, key := range keys {
+		o, t := ourChildren[key], theirChildren[key]
+		name := t
+		if o != nil {
+			name = o
+		}
+		childPath := append(segments[:len(segments):len(segments)], name.Key())
+
+		merged, err := m.node(baseChildren[key], o, t, childPath, inherited)
+		if err != nil {
+			return nil, err
+		}
+		if merged == nil {
+			continue
+		}
+		if err := result.AddChild(merged); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// node merges three versions of the node at childPath, any of which may be
+// nil, returning nil when the merged graph holds no node there.
+func (m *threeWay) node(base, ours, theirs SkaffoldNode, childPath []string, inherited CollisionAction) (SkaffoldNode, error) {
+	if ours != nil && theirs != nil && ours.Type() == NODETYPE_DIRECTORY && theirs.Type() == NODETYPE_DIRECTORY {
+		if base != nil && base.Type() != NODETYPE_DIRECTORY {
+			base = nil
+		}
+		return m.dir(base, ours, theirs, childPath, firstCollisionAction(ours, theirs, inherited))
+	}
+
+	switch {
+	case sameNode(ours, theirs), sameNode(base, theirs):
+		return m.copy(ours, ours)
+	case sameNode(base, ours):
+		return m.copy(theirs, ours)
+	}
+
+	switch resolveCollision(firstCollisionAction(ours, theirs, inherited), m.opts) {
+	case OverwriteOnCollision:
+		return m.copy(ours, ours)
+	case YieldOnCollision:
+		return m.copy(theirs, ours)
+	default:
+		m.conflicts = append(m.conflicts, Conflict{Path: displayPath(childPath), Base: base, Ours: ours, Theirs: theirs})
+		return m.copy(ours, ours)
+	}
+}
+
+// copy returns a copy of node for the merged graph, named as ours when the
+// keys of the two only match case-insensitively, or nil if node is nil.
+func (m *threeWay) copy(node, ours SkaffoldNode) (SkaffoldNode, error) {
+	if node == nil {
+		return nil, nil
+	}
+	c := Clone(node)
+	if ours != nil && c.Key() != ours.Key() {
+		if renamer, ok := c.(interface{ SetKey(string) error }); ok {
+			if err := renamer.SetKey(ours.Key()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c, nil
+}
+
+// childrenByKey indexes the children of node by their matching key, and is
+// empty for a nil node.
+func (m *threeWay) childrenByKey(node SkaffoldNode) map[string]SkaffoldNode {
+	children := make(map[string]SkaffoldNode)
+	if node == nil {
+		return children
+	}
+	for _, child := range node.Children() {
+		children[m.key(child.Key())] = child
+	}
+	return children
+}
+
+// key returns the form of key used for matching, as Union matches keys.
+func (m *threeWay) key(key string) string {
+	return m.opts.MatchKey(key)
+}
+
+// sameNode reports whether a and b, either of which may be nil, are both
+// absent or equal as by Equal.
+func sameNode(a, b SkaffoldNode) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	equal, _ := equalNodes(a, b, nil, EqualOptions{})
+	return equal
+}
@@ -0,0 +1,131 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestThreeWayMerge(t *testing.T) {
+	base := map[string]string{
+		"keep.txt":     "k",
+		"ours.txt":     "o",
+		"theirs.txt":   "t",
+		"both.txt":     "b",
+		"same.txt":     "s",
+		"gone.txt":     "g",
+		"src/main.go":  "m",
+		"src/lib.go":   "l",
+		"docs/api.md":  "a",
+		"delete/x.txt": "x",
+	}
+	ours := map[string]string{
+		"keep.txt":     "k",
+		"ours.txt":     "o2",
+		"theirs.txt":   "t",
+		"both.txt":     "b-ours",
+		"same.txt":     "s2",
+		"src/main.go":  "m",
+		"src/lib.go":   "l-ours",
+		"src/new.go":   "n",
+		"docs/api.md":  "a",
+		"delete/x.txt": "x",
+		"added.txt":    "ours",
+	}
+	theirs := map[string]string{
+		"keep.txt":    "k",
+		"ours.txt":    "o",
+		"theirs.txt":  "t2",
+		"both.txt":    "b-theirs",
+		"same.txt":    "s2",
+		"gone.txt":    "g",
+		"src/main.go": "m-theirs",
+		"src/lib.go":  "l",
+		"docs/api.md": "a",
+		"docs/faq.md": "f",
+		"added.txt":   "theirs",
+	}
+
+	merged, conflicts, err := ThreeWayMerge(buildTree(t, base), buildTree(t, ours), buildTree(t, theirs), MergeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"keep.txt":    "k",
+		"ours.txt":    "o2",
+		"theirs.txt":  "t2",
+		"both.txt":    "b-ours",
+		"same.txt":    "s2",
+		"src/main.go": "m-theirs",
+		"src/lib.go":  "l-ours",
+		"src/new.go":  "n",
+		"docs/api.md": "a",
+		"docs/faq.md": "f",
+		"added.txt":   "ours",
+	}
+	if equal, diff := EqualDetailed(merged, buildTree(t, want)); !equal {
+		t.Errorf("merged graph differs from the expected one: %s", diff)
+	}
+
+	var paths []string
+	for _, c := range conflicts {
+		paths = append(paths, c.Path)
+	}
+	if want := []string{"added.txt", "both.txt"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("conflicts at %v, want %v", paths, want)
+	}
+	if c := conflicts[0]; c.Base != nil || c.Ours == nil || c.Theirs == nil {
+		t.Errorf("conflict at added.txt = %+v, want no base and both sides", c)
+	}
+}
+
+func TestThreeWayMergeCollisionActions(t *testing.T) {
+	base := map[string]string{"a.txt": "base"}
+	ours := map[string]string{"a.txt": "ours"}
+	theirs := map[string]string{"a.txt": "theirs"}
+
+	tests := []struct {
+		action    CollisionAction
+		want      string
+		conflicts int
+	}{
+		{action: ErrorOnCollision, want: "ours", conflicts: 1},
+		{action: OverwriteOnCollision, want: "ours"},
+		{action: YieldOnCollision, want: "theirs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action.String(), func(t *testing.T) {
+			merged, conflicts, err := ThreeWayMerge(buildTree(t, base), buildTree(t, ours), buildTree(t, theirs),
+				MergeOptions{DefaultCollisionAction: tt.action})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if equal, diff := EqualDetailed(merged, buildTree(t, map[string]string{"a.txt": tt.want})); !equal {
+				t.Errorf("merged graph differs: %s", diff)
+			}
+			if len(conflicts) != tt.conflicts {
+				t.Errorf("got %d conflicts, want %d", len(conflicts), tt.conflicts)
+			}
+		})
+	}
+}
+
+func TestThreeWayMergeLeavesInputsUnchanged(t *testing.T) {
+	base := buildTree(t, map[string]string{"a.txt": "a"})
+	ours := buildTree(t, map[string]string{"a.txt": "a", "b.txt": "b"})
+	theirs := buildTree(t, map[string]string{"c.txt": "c"})
+
+	merged, _, err := ThreeWayMerge(base, ours, theirs, MergeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := listPaths(merged); !reflect.DeepEqual(got, []string{"b.txt", "c.txt"}) {
+		t.Errorf("merged paths = %v, want [b.txt c.txt]", got)
+	}
+	if got := listPaths(ours); !reflect.DeepEqual(got, []string{"a.txt", "b.txt"}) {
+		t.Errorf("ThreeWayMerge modified ours: %v", got)
+	}
+	if _, _, err := ThreeWayMerge(base, NewFileNode("root"), theirs, MergeOptions{}); err == nil {
+		t.Error("ThreeWayMerge accepted a file root")
+	}
+}
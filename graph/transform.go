@@ -0,0 +1,58 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TransformFunc returns the new key for node, whose current key is key.
+// Returning key unchanged keeps the node's name.
+type TransformFunc func(key string, node SkaffoldNode) (string, error)
+
+// Transform returns a copy of the graph rooted at root with every node,
+// including the root, renamed to the key returned by fn. Nodes are visited
+// in pre-order and passed as they appear in the original graph, which is not
+// modified. It fails if fn returns an error, if a new key is empty, "." or
+// ".." or contains a slash, or if two siblings end up with the same key.
+func Transform(root SkaffoldNode, fn TransformFunc) (SkaffoldNode, error) {
+	return transformNode(root, nil, fn)
+}
+
+// transformNode returns a renamed copy of node, where segments is the
+// original path of node.
+func transformNode(node SkaffoldNode, segments []string, fn TransformFunc) (SkaffoldNode, error) {
+	key, err := fn(node.Key(), node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform %s: %w", displayPath(segments), err)
+	}
+	if key == "" || key == "." || key == ".." || strings.Contains(key, "/") {
+		return nil, fmt.Errorf("failed to transform %s: invalid key %q", displayPath(segments), key)
+	}
+
+	switch n := node.(type) {
+	case *DirectoryNode:
+		c := shallowCopyDir(n)
+		c.name = key
+		for _, child := range n.children {
+			childPath := append(segments[:len(segments):len(segments)], child.Key())
+			childCopy, err := transformNode(child, childPath, fn)
+			if err != nil {
+				return nil, err
+			}
+			if err := c.AddChild(childCopy); err != nil {
+				return nil, fmt.Errorf("failed to transform %s: %w", displayPath(childPath), err)
+			}
+		}
+		return c, nil
+	case *FileNode:
+		c := Clone(n).(*FileNode)
+		c.name = key
+		return c, nil
+	case *SymlinkNode:
+		c := Clone(n).(*SymlinkNode)
+		c.name = key
+		return c, nil
+	default:
+		return nil, fmt.Errorf("cannot transform node %s of type %s", node.Key(), node.Type())
+	}
+}
@@ -0,0 +1,92 @@
+package graph
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	root := buildTree(t, map[string]string{
+		"README.md":           "r",
+		"src/main.go.tmpl":    "m",
+		"src/pkg/lib.go.tmpl": "l",
+	})
+
+	var visited []string
+	result, err := Transform(root, func(key string, node SkaffoldNode) (string, error) {
+		visited = append(visited, key)
+		if node == SkaffoldNode(root) {
+			return "project", nil
+		}
+		return strings.TrimSuffix(key, ".tmpl"), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Key() != "project" {
+		t.Errorf("Transform root key = %s, want project", result.Key())
+	}
+	want := []string{"README.md", "src/", "src/main.go", "src/pkg/", "src/pkg/lib.go"}
+	if got := listPaths(result); !reflect.DeepEqual(got, want) {
+		t.Errorf("Transform paths = %v, want %v", got, want)
+	}
+	wantVisited := []string{"root", "README.md", "src", "main.go.tmpl", "pkg", "lib.go.tmpl"}
+	if !reflect.DeepEqual(visited, wantVisited) {
+		t.Errorf("Transform visited %v, want %v", visited, wantVisited)
+	}
+	if root.Key() != "root" || !reflect.DeepEqual(listPaths(root), []string{"README.md", "src/", "src/main.go.tmpl", "src/pkg/", "src/pkg/lib.go.tmpl"}) {
+		t.Error("Transform modified the original graph")
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	failure := errors.New("boom")
+	tests := []struct {
+		name    string
+		fn      TransformFunc
+		wantErr string
+	}{
+		{
+			name: "callback error",
+			fn: func(key string, node SkaffoldNode) (string, error) {
+				if key == "b.txt" {
+					return "", failure
+				}
+				return key, nil
+			},
+			wantErr: "failed to transform dir/b.txt: boom",
+		},
+		{
+			name: "invalid key",
+			fn: func(key string, node SkaffoldNode) (string, error) {
+				if key == "a.txt" {
+					return "sub/a.txt", nil
+				}
+				return key, nil
+			},
+			wantErr: `invalid key "sub/a.txt"`,
+		},
+		{
+			name: "sibling clash",
+			fn: func(key string, node SkaffoldNode) (string, error) {
+				if node.Type() == NODETYPE_FILE {
+					return "same.txt", nil
+				}
+				return key, nil
+			},
+			wantErr: "duplicate child",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := buildTree(t, map[string]string{"dir/a.txt": "a", "dir/b.txt": "b"})
+			_, err := Transform(root, tt.fn)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Transform error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
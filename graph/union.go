@@ -0,0 +1,504 @@
+package graph
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// CollisionAction determines how Union resolves two file nodes at the same
+// path whose content differs, or two symlinks whose targets differ.
+type CollisionAction int
+
+const (
+	// DefaultOnCollision defers to the enclosing directories and then to
+	// MergeOptions.DefaultCollisionAction.
+	DefaultOnCollision CollisionAction = iota
+	// ErrorOnCollision aborts the union with an error naming the path.
+	ErrorOnCollision
+	// OverwriteOnCollision keeps the control node's content.
+	OverwriteOnCollision
+	// YieldOnCollision replaces the control node's content with the added node's.
+	YieldOnCollision
+)
+
+func (a CollisionAction) String() string {
+	switch a {
+	case DefaultOnCollision:
+		return "DEFAULT"
+	case ErrorOnCollision:
+		return "ERROR"
+	case OverwriteOnCollision:
+		return "OVERWRITE"
+	case YieldOnCollision:
+		return "YIELD"
+	default:
+		return fmt.Sprintf("CollisionAction(%d)", int(a))
+	}
+}
+
+// ParseCollisionAction returns the CollisionAction named by s, as produced by
+// String.
+func ParseCollisionAction(s string) (CollisionAction, error) {
+	for _, action := range []CollisionAction{DefaultOnCollision, ErrorOnCollision, OverwriteOnCollision, YieldOnCollision} {
+		if action.String() == s {
+			return action, nil
+		}
+	}
+	return DefaultOnCollision, fmt.Errorf("unknown collision action %s", s)
+}
+
+// MetadataMergePolicy determines how Union resolves a metadata key set to
+// different values on two nodes being merged.
+type MetadataMergePolicy int
+
+const (
+	// MetadataFollowsCollision resolves metadata with the node's collision
+	// action, as file content is, and lets the node winning a content
+	// collision keep its own values.
+	MetadataFollowsCollision MetadataMergePolicy = iota
+	// ControlMetadataWins keeps the control node's values.
+	ControlMetadataWins
+	// AddedMetadataWins takes the added node's values.
+	AddedMetadataWins
+	// ErrorOnMetadataConflict aborts the union with an error naming the path
+	// and key, or records a conflict when conflicts are being collected.
+	ErrorOnMetadataConflict
+)
+
+func (p MetadataMergePolicy) String() string {
+	switch p {
+	case MetadataFollowsCollision:
+		return "FOLLOW_COLLISION"
+	case ControlMetadataWins:
+		return "CONTROL_WINS"
+	case AddedMetadataWins:
+		return "ADDED_WINS"
+	case ErrorOnMetadataConflict:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("MetadataMergePolicy(%d)", int(p))
+	}
+}
+
+// MergeOptions controls how Union combines graphs.
+type MergeOptions struct {
+	// DefaultCollisionAction resolves file content collisions. The zero value,
+	// DefaultOnCollision, behaves as ErrorOnCollision.
+	DefaultCollisionAction CollisionAction
+	// CaseInsensitive matches keys regardless of case, as on macOS and Windows
+	// filesystems where README.md and Readme.md are the same file. Matched
+	// nodes keep the control node's key.
+	CaseInsensitive bool
+	// CollectConflicts makes UnionWithConflicts record the collisions that
+	// would make Union fail, and nodes of different types at the same path,
+	// as conflicts and carry on merging, keeping the control side's node at
+	// each. Union then reports every conflict in a single error.
+	CollectConflicts bool
+	// RehashOnMismatch lets files on both sides whose hashes were produced
+	// by different algorithms be compared by rehashing the added file's
+	// content with the control file's algorithm, as when merging a graph
+	// hashed with MD5 into one hashed with SHA-256. The added file's content
+	// must still be available. Without it such files are an error.
+	RehashOnMismatch bool
+	// MetadataMergePolicy resolves metadata keys set to different values on
+	// both sides. Keys set on one side only are always kept. Other than the
+	// zero value, MetadataFollowsCollision, a policy applies regardless of
+	// collision actions and of which node wins a content collision.
+	MetadataMergePolicy MetadataMergePolicy
+
+	conflicts *[]Conflict // Receives the conflicts while collecting them
+}
+
+// Union merges the add graphs into a copy of the control graph, matching
+// nodes by key at each path. Directories are merged recursively and nodes
+// present only in an add graph are copied in. Files present on both sides
+// whose hash or content type differ are resolved with a CollisionAction: the
+// first one set on the control node, the added node, or their nearest
+// enclosing directories, falling back to MergeOptions.DefaultCollisionAction.
+// Metadata of nodes on both sides is merged, with differing values for the
+// same key resolved by MergeOptions.MetadataMergePolicy, by default the same
+// action, except that the node winning a content collision keeps its own
+// values. Neither the control nor the add graphs are modified.
+func Union(control SkaffoldNode, opts MergeOptions, add ...SkaffoldNode) (SkaffoldNode, error) {
+	result, conflicts, err := UnionWithConflicts(control, opts, add...)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 {
+		paths := make([]string, 0, len(conflicts))
+		for _, c := range conflicts {
+			paths = append(paths, c.Path)
+		}
+		return nil, fmt.Errorf("cannot union graphs: conflicts at %s", strings.Join(paths, ", "))
+	}
+	return result, nil
+}
+
+// UnionWithConflicts merges graphs as Union does. When
+// MergeOptions.CollectConflicts is set, it returns the merged graph together
+// with the conflicts found, in graph order, rather than failing at the first.
+// Each conflict names the node kept in the result as Ours and the node from
+// the add graph as Theirs.
+func UnionWithConflicts(control SkaffoldNode, opts MergeOptions, add ...SkaffoldNode) (SkaffoldNode, []Conflict, error) {
+	var conflicts []Conflict
+	opts.conflicts = nil
+	if opts.CollectConflicts {
+		opts.conflicts = &conflicts
+	}
+
+	result, err := union(control, opts, add)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, conflicts, nil
+}
+
+func union(control SkaffoldNode, opts MergeOptions, add []SkaffoldNode) (SkaffoldNode, error) {
+	if control.Type() != NODETYPE_DIRECTORY {
+		return nil, fmt.Errorf("union root %s is not a directory", control.Key())
+	}
+
+	result, ok := Clone(control).(*DirectoryNode)
+	if !ok {
+		return nil, fmt.Errorf("union root %s has unsupported implementation %T", control.Key(), control)
+	}
+
+	for _, a := range add {
+		if a.Type() != NODETYPE_DIRECTORY {
+			return nil, fmt.Errorf("union root %s is not a directory", a.Key())
+		}
+		if !opts.keysMatch(a.Key(), control.Key()) {
+			return nil, fmt.Errorf("cannot union graph rooted at %s into graph rooted at %s", a.Key(), control.Key())
+		}
+		inherited := firstCollisionAction(result, a, DefaultOnCollision)
+		if err := mergeMetadata(result, a, nil, inherited, opts); err != nil {
+			return nil, err
+		}
+		if err := mergeDir(result, a, nil, inherited, opts); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// MergeChild merges child into the directory as Union merges the children of
+// two graphs, adding a copy of it when no child has its key. Collisions are
+// resolved with the collision actions set on the nodes and the directories
+// above them, falling back to opts. The child itself is not modified.
+func (d *DirectoryNode) MergeChild(child SkaffoldNode, opts MergeOptions) error {
+	// Collect the directories from the root down to d
+	var chain []*DirectoryNode
+	for node := SkaffoldNode(d); node != nil; {
+		dir, ok := node.(*DirectoryNode)
+		if !ok {
+			break
+		}
+		chain = append([]*DirectoryNode{dir}, chain...)
+		node, _ = dir.Parent()
+	}
+
+	inherited := DefaultOnCollision
+	var segments []string
+	for i, dir := range chain {
+		inherited = firstCollisionAction(dir, nil, inherited)
+		if i > 0 {
+			segments = append(segments, dir.Key())
+		}
+	}
+	return mergeChild(d, child, append(segments, child.Key()), inherited, opts)
+}
+
+// mergeDir merges the children of src into dst, where segments is the path of
+// dst and inherited is the collision action set by dst, src or their parents.
+func mergeDir(dst *DirectoryNode, src SkaffoldNode, segments []string, inherited CollisionAction, opts MergeOptions) error {
+	for _, srcChild := range src.Children() {
+		childPath := append(segments[:len(segments):len(segments)], srcChild.Key())
+		if err := mergeChild(dst, srcChild, childPath, inherited, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeChild merges srcChild into dst as the child at childPath, copying it
+// in when dst has no child with its key.
+func mergeChild(dst *DirectoryNode, srcChild SkaffoldNode, childPath []string, inherited CollisionAction, opts MergeOptions) error {
+	idx := dst.childIndex(srcChild.Key())
+	if idx < 0 && opts.CaseInsensitive {
+		idx = dst.childIndexFold(srcChild.Key())
+	}
+	if idx < 0 {
+		childCopy := Clone(srcChild)
+		_ = childCopy.SetParent(dst)
+		return dst.AddChild(childCopy)
+	}
+
+	dstChild := dst.children[idx]
+	// Report and resolve the node under the control node's key
+	childPath[len(childPath)-1] = dstChild.Key()
+	if dstChild.Type() != srcChild.Type() {
+		return opts.conflict(childPath, dstChild, srcChild, fmt.Errorf("cannot union %s: %s in one graph and %s in another",
+			path.Join(childPath...), dstChild.Type(), srcChild.Type()))
+	}
+
+	switch d := dstChild.(type) {
+	case *DirectoryNode:
+		action := firstCollisionAction(d, srcChild, inherited)
+		if err := mergeMetadata(d, srcChild, childPath, action, opts); err != nil {
+			return err
+		}
+		return mergeDir(d, srcChild, childPath, action, opts)
+	case *FileNode:
+		s, ok := srcChild.(*FileNode)
+		if !ok {
+			return fmt.Errorf("cannot union %s: unsupported implementation %T", path.Join(childPath...), srcChild)
+		}
+		if err := checkHashAlgorithms(d, s, opts); err != nil {
+			return fmt.Errorf("cannot union %s: %w", path.Join(childPath...), err)
+		}
+		same, err := sameContent(d, s)
+		if err != nil {
+			return fmt.Errorf("cannot union %s: %w", path.Join(childPath...), err)
+		}
+		action := firstCollisionAction(d, s, inherited)
+		if same {
+			return mergeMetadata(d, s, childPath, action, opts)
+		}
+		if opts.MetadataMergePolicy != MetadataFollowsCollision {
+			// Metadata is settled before the content, whichever node wins
+			if err := mergeMetadata(d, s, childPath, action, opts); err != nil {
+				return err
+			}
+		}
+		if err := applyCollision(dst, s, childPath, action, opts); err != nil {
+			return err
+		}
+		winner, loser := dst.children[idx], SkaffoldNode(d)
+		if winner == d {
+			loser = s
+		}
+		if opts.MetadataMergePolicy != MetadataFollowsCollision {
+			if winner != d {
+				copyMetadata(winner, d)
+			}
+			return nil
+		}
+		// The node that won the collision keeps its own metadata values
+		return mergeMetadata(winner, loser, childPath, OverwriteOnCollision, opts)
+	case *SymlinkNode:
+		s, ok := srcChild.(*SymlinkNode)
+		if !ok {
+			return fmt.Errorf("cannot union %s: unsupported implementation %T", path.Join(childPath...), srcChild)
+		}
+		if d.Target() == s.Target() {
+			return nil
+		}
+		return applyCollision(dst, s, childPath, firstCollisionAction(d, s, inherited), opts)
+	}
+	return nil
+}
+
+// checkHashAlgorithms checks that the hashes of control and added, files at
+// the same path, can be compared. Hashes produced by different algorithms are
+// only compared with MergeOptions.RehashOnMismatch, which rehashes the added
+// file and so needs its content.
+func checkHashAlgorithms(control, added *FileNode, opts MergeOptions) error {
+	if control.hash_algorithm == added.hash_algorithm {
+		return nil
+	}
+	for _, f := range []*FileNode{control, added} {
+		if f.hash_algorithm == "" {
+			return fmt.Errorf("cannot compare content of %s: no hash algorithm is recorded for it in one graph", f.name)
+		}
+	}
+	if !opts.RehashOnMismatch {
+		return fmt.Errorf("cannot compare content of %s: hashed with %s in one graph and %s in another; set RehashOnMismatch to rehash it",
+			added.name, control.hash_algorithm, added.hash_algorithm)
+	}
+	if _, ok := added.ContentProvider().(errProvider); ok && !added.ContentSkipped() {
+		return fmt.Errorf("cannot rehash %s with %s: its content was not kept", added.name, control.hash_algorithm)
+	}
+	return nil
+}
+
+// applyCollision applies the effective CollisionAction to a file or symlink
+// in dst whose counterpart src differs, where childPath is the path of the
+// node and action is the action set on the nodes or their directories.
+func applyCollision(dst *DirectoryNode, src SkaffoldNode, childPath []string, action CollisionAction, opts MergeOptions) error {
+	switch resolveCollision(action, opts) {
+	case OverwriteOnCollision:
+		// The control side already holds the winning content
+	case YieldOnCollision:
+		childCopy := Clone(src)
+		// The replacement keeps the control node's key, which differs from
+		// its own only in case when matching case-insensitively
+		if renamer, ok := childCopy.(interface{ SetKey(string) error }); ok {
+			if err := renamer.SetKey(childPath[len(childPath)-1]); err != nil {
+				return err
+			}
+		}
+		if err := dst.ReplaceChild(childCopy); err != nil {
+			return err
+		}
+	default:
+		ours := dst.children[dst.childIndex(childPath[len(childPath)-1])]
+		return opts.conflict(childPath, ours, src, fmt.Errorf("collision at %s: content differs between graphs", path.Join(childPath...)))
+	}
+	return nil
+}
+
+// conflict records a conflict at childPath between ours, the node kept in
+// the result, and theirs when conflicts are being collected, and otherwise
+// returns err. Repeated conflicts at the same path, as over several metadata
+// keys, are recorded once.
+func (o MergeOptions) conflict(childPath []string, ours, theirs SkaffoldNode, err error) error {
+	if !o.CollectConflicts || o.conflicts == nil {
+		return err
+	}
+	p := displayPath(childPath)
+	if n := len(*o.conflicts); n > 0 && (*o.conflicts)[n-1].Path == p {
+		return nil
+	}
+	*o.conflicts = append(*o.conflicts, Conflict{Path: p, Ours: ours, Theirs: theirs})
+	return nil
+}
+
+// childIndexFold returns the index of the child whose key equals key under
+// case folding, or -1.
+func (d *DirectoryNode) childIndexFold(key string) int {
+	folded := foldKey(key)
+	for i, child := range d.children {
+		if foldKey(child.Key()) == folded {
+			return i
+		}
+	}
+	return -1
+}
+
+// keysMatch reports whether the keys a and b name the same node.
+func (o MergeOptions) keysMatch(a, b string) bool {
+	return o.MatchKey(a) == o.MatchKey(b)
+}
+
+// MatchKey returns the form of key that Union matches nodes by: key itself,
+// or key case folded when CaseInsensitive is set. Two keys name the same node
+// exactly when their MatchKey forms are equal, so callers grouping nodes as
+// Union would, such as render, should key them by it.
+func (o MergeOptions) MatchKey(key string) string {
+	if o.CaseInsensitive {
+		return foldKey(key)
+	}
+	return key
+}
+
+// foldKey returns key with every rune replaced by the smallest rune it is
+// equivalent to under Unicode simple case folding, so that two keys fold to
+// the same string exactly when strings.EqualFold reports them equal.
+func foldKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		folded := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < folded {
+				folded = f
+			}
+		}
+		return folded
+	}, key)
+}
+
+// resolveCollision returns the effective action for a content collision.
+func resolveCollision(action CollisionAction, opts MergeOptions) CollisionAction {
+	if action != DefaultOnCollision {
+		return action
+	}
+	if opts.DefaultCollisionAction == DefaultOnCollision {
+		return ErrorOnCollision
+	}
+	return opts.DefaultCollisionAction
+}
+
+// annotated is implemented by nodes that carry metadata.
+type annotated interface {
+	Metadata() map[string]string
+	SetMetadata(key, value string)
+}
+
+// mergeMetadata copies the metadata of src onto dst, the node at childPath in
+// the union result. Keys set on both with different values are resolved by
+// opts.MetadataMergePolicy, by default with the effective CollisionAction as
+// file content is.
+func mergeMetadata(dst, src SkaffoldNode, childPath []string, action CollisionAction, opts MergeOptions) error {
+	d, dOk := dst.(annotated)
+	s, sOk := src.(annotated)
+	if !dOk || !sOk {
+		return nil
+	}
+
+	keys := make([]string, 0, len(s.Metadata()))
+	for key := range s.Metadata() {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := s.Metadata()[key]
+		existing, ok := d.Metadata()[key]
+		if !ok || existing == value {
+			d.SetMetadata(key, value)
+			continue
+		}
+		switch opts.metadataAction(action) {
+		case OverwriteOnCollision:
+			// The control side already holds the winning value
+		case YieldOnCollision:
+			d.SetMetadata(key, value)
+		default:
+			err := fmt.Errorf("collision at %s: metadata %s differs between graphs", displayPath(childPath), key)
+			if err := opts.conflict(childPath, dst, src, err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// metadataAction returns the effective action for a metadata key whose
+// values differ, given the effective collision action of the node.
+func (o MergeOptions) metadataAction(action CollisionAction) CollisionAction {
+	switch o.MetadataMergePolicy {
+	case ControlMetadataWins:
+		return OverwriteOnCollision
+	case AddedMetadataWins:
+		return YieldOnCollision
+	case ErrorOnMetadataConflict:
+		return ErrorOnCollision
+	default:
+		return resolveCollision(action, o)
+	}
+}
+
+// copyMetadata sets every metadata key of src on dst.
+func copyMetadata(dst, src SkaffoldNode) {
+	d, dOk := dst.(annotated)
+	s, sOk := src.(annotated)
+	if !dOk || !sOk {
+		return
+	}
+	for key, value := range s.Metadata() {
+		d.SetMetadata(key, value)
+	}
+}
+
+// firstCollisionAction returns the collision action set on the control node,
+// else on the added node, else inherited.
+func firstCollisionAction(control, added SkaffoldNode, inherited CollisionAction) CollisionAction {
+	for _, node := range []SkaffoldNode{control, added} {
+		if n, ok := node.(interface{ CollisionAction() CollisionAction }); ok && n.CollisionAction() != DefaultOnCollision {
+			return n.CollisionAction()
+		}
+	}
+	return inherited
+}
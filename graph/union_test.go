@@ -0,0 +1,433 @@
+package graph
+
+import (
+	"crypto/md5"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnionCaseInsensitive(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    MergeOptions
+		added   map[string]string
+		want    []string
+		content map[string]string
+		wantErr string
+	}{
+		{
+			name:  "case sensitive keeps both",
+			added: map[string]string{"Readme.md": "r", "SRC/util.go": "u"},
+			want:  []string{"README.md", "Readme.md", "SRC/", "SRC/util.go", "src/", "src/main.go"},
+		},
+		{
+			name:  "same content merges under the control key",
+			opts:  MergeOptions{CaseInsensitive: true},
+			added: map[string]string{"Readme.md": "r", "SRC/util.go": "u"},
+			want:  []string{"README.md", "src/", "src/main.go", "src/util.go"},
+		},
+		{
+			name:    "yield keeps the control key",
+			opts:    MergeOptions{CaseInsensitive: true, DefaultCollisionAction: YieldOnCollision},
+			added:   map[string]string{"readme.MD": "changed"},
+			want:    []string{"README.md", "src/", "src/main.go"},
+			content: map[string]string{"README.md": "changed"},
+		},
+		{
+			name:    "collision reported at the control key",
+			opts:    MergeOptions{CaseInsensitive: true},
+			added:   map[string]string{"Src/Main.go": "changed"},
+			wantErr: "src/main.go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			control := buildTree(t, map[string]string{"README.md": "r", "src/main.go": "m"})
+			merged, err := Union(control, tt.opts, buildTree(t, tt.added))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Union error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := listPaths(merged); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Union paths = %v, want %v", got, tt.want)
+			}
+			for p, content := range tt.content {
+				node, err := FindByPath(merged, p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := node.(*FileNode).Size(); got != int64(len(content)) {
+					t.Errorf("%s has %d bytes, want the added content", p, got)
+				}
+			}
+		})
+	}
+}
+
+func TestUnionCaseInsensitiveRoots(t *testing.T) {
+	control := NewDirectoryNode("Project")
+	added := NewDirectoryNode("project")
+	if _, err := Union(control, MergeOptions{}, added); err == nil {
+		t.Error("Union matched roots differing in case without CaseInsensitive")
+	}
+	merged, err := Union(control, MergeOptions{CaseInsensitive: true}, added)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Key() != "Project" {
+		t.Errorf("merged root key = %s, want the control key Project", merged.Key())
+	}
+}
+
+func TestMergeOptionsMatchKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"README.md", "Readme.md"},
+		{"straße", "STRASSE"},
+		{"ſrc", "SRC"}, // long s folds to s, though it has no lower case form
+		{"Kelvin", "\u212aelvin"},
+		{"a.txt", "b.txt"},
+		{"É.txt", "é.txt"},
+	}
+	for _, tt := range tests {
+		want := strings.EqualFold(tt.a, tt.b)
+		if got := (MergeOptions{CaseInsensitive: true}).keysMatch(tt.a, tt.b); got != want {
+			t.Errorf("keys %q and %q match = %v, want %v as by strings.EqualFold", tt.a, tt.b, got, want)
+		}
+		if got := (MergeOptions{}).keysMatch(tt.a, tt.b); got != (tt.a == tt.b) {
+			t.Errorf("keys %q and %q match case-sensitively = %v", tt.a, tt.b, got)
+		}
+	}
+}
+
+func TestUnionWithConflicts(t *testing.T) {
+	control := buildTree(t, map[string]string{"a.txt": "a", "b.txt": "b", "dir/c.txt": "c", "same.txt": "s"})
+	control.SetMetadata("owner", "control")
+	added := buildTree(t, map[string]string{"a.txt": "a2", "b.txt/x": "x", "dir/c.txt": "c2", "same.txt": "s", "new.txt": "n"})
+	added.SetMetadata("owner", "added")
+
+	merged, conflicts, err := UnionWithConflicts(control, MergeOptions{CollectConflicts: true}, added)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for _, c := range conflicts {
+		paths = append(paths, c.Path)
+	}
+	if want := []string{".", "a.txt", "b.txt", "dir/c.txt"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("conflicts at %v, want %v", paths, want)
+	}
+	for _, c := range conflicts[1:] {
+		if c.Ours == nil || c.Theirs == nil || c.Ours.Type() != NODETYPE_FILE {
+			t.Errorf("conflict at %s = %+v, want the control file as Ours", c.Path, c)
+		}
+	}
+
+	// The control side is kept at each conflict, and the rest is merged
+	want := []string{"a.txt", "b.txt", "dir/", "dir/c.txt", "new.txt", "same.txt"}
+	if got := listPaths(merged); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged paths = %v, want %v", got, want)
+	}
+	if got := merged.(*DirectoryNode).Metadata()["owner"]; got != "control" {
+		t.Errorf("merged owner = %s, want the control value kept", got)
+	}
+
+	_, err = Union(control, MergeOptions{CollectConflicts: true}, added)
+	if err == nil || !strings.Contains(err.Error(), "conflicts at ., a.txt, b.txt, dir/c.txt") {
+		t.Errorf("Union error = %v, want every conflict reported", err)
+	}
+}
+
+func TestUnionWithConflictsStopsWithoutCollecting(t *testing.T) {
+	control := buildTree(t, map[string]string{"a.txt": "a", "b.txt": "b"})
+	added := buildTree(t, map[string]string{"a.txt": "a2", "b.txt": "b2"})
+	_, conflicts, err := UnionWithConflicts(control, MergeOptions{}, added)
+	if err == nil || !strings.Contains(err.Error(), "collision at a.txt") || conflicts != nil {
+		t.Errorf("UnionWithConflicts = %v, %v; want it to fail at a.txt", conflicts, err)
+	}
+}
+
+func TestUnionRehashOnMismatch(t *testing.T) {
+	// md5Tree builds a tree holding a.txt whose content is hashed with MD5
+	// and, if kept, supplied by a provider.
+	md5Tree := func(content string, kept bool) *DirectoryNode {
+		root := buildTree(t, map[string]string{"a.txt": content})
+		node, _ := root.Child("a.txt")
+		file := node.(*FileNode)
+		sum := md5.Sum([]byte(content))
+		file.SetContentInfo(sum[:], HASHALGORITHM_MD5, int64(len(content)), file.ContentType())
+		if kept {
+			file.SetContentProvider(BytesProvider(content))
+		}
+		return root
+	}
+
+	tests := []struct {
+		name    string
+		added   *DirectoryNode
+		opts    MergeOptions
+		want    string
+		wantErr string
+	}{
+		{name: "same content", added: md5Tree("a", true), opts: MergeOptions{RehashOnMismatch: true}, want: "a"},
+		{name: "different content", added: md5Tree("b", true), opts: MergeOptions{RehashOnMismatch: true, DefaultCollisionAction: YieldOnCollision}, want: "b"},
+		{name: "not enabled", added: md5Tree("a", true), wantErr: "hashed with SHA256 in one graph and MD5 in another"},
+		{name: "content not kept", added: md5Tree("a", false), opts: MergeOptions{RehashOnMismatch: true}, wantErr: "its content was not kept"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			control := buildTree(t, map[string]string{"a.txt": "a"})
+			merged, err := Union(control, tt.opts, tt.added)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Union error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			file, _ := merged.(*DirectoryNode).Child("a.txt")
+			content, err := file.(*FileNode).Content()
+			if err != nil || string(content) != tt.want {
+				t.Errorf("merged content = %q, %v; want %q", content, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnionNoHashAlgorithm(t *testing.T) {
+	control := buildTree(t, map[string]string{"a.txt": "a"})
+	added := NewDirectoryNode("root")
+	file := NewFileNode("a.txt")
+	_ = file.SetParent(added)
+	if err := added.AddChild(file); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Union(control, MergeOptions{RehashOnMismatch: true}, added)
+	if err == nil || !strings.Contains(err.Error(), "no hash algorithm is recorded") {
+		t.Errorf("Union error = %v, want a missing hash algorithm reported", err)
+	}
+}
+
+// annotatedGraph returns a graph holding a.txt with content and metadata.
+func annotatedGraph(t *testing.T, content string, metadata map[string]string) SkaffoldNode {
+	t.Helper()
+	root, err := NewBuilder("root").File("a.txt").Content([]byte(content)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := FindByPath(root, "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range metadata {
+		node.(*FileNode).SetMetadata(key, value)
+	}
+	return root
+}
+
+func TestUnionMetadataMergePolicy(t *testing.T) {
+	control := map[string]string{"owner": "platform", "team": "core"}
+	added := map[string]string{"owner": "payments", "tier": "1"}
+
+	tests := []struct {
+		name     string
+		policy   MergeOptions
+		theirs   string
+		want     map[string]string
+		wantErr  bool
+		wantData string
+	}{
+		{
+			name:   "control wins",
+			policy: MergeOptions{MetadataMergePolicy: ControlMetadataWins},
+			theirs: "same",
+			want:   map[string]string{"owner": "platform", "team": "core", "tier": "1"},
+		},
+		{
+			name:   "added wins",
+			policy: MergeOptions{MetadataMergePolicy: AddedMetadataWins},
+			theirs: "same",
+			want:   map[string]string{"owner": "payments", "team": "core", "tier": "1"},
+		},
+		{
+			name:    "error on conflict",
+			policy:  MergeOptions{MetadataMergePolicy: ErrorOnMetadataConflict},
+			theirs:  "same",
+			wantErr: true,
+		},
+		{
+			name:   "follows collision action",
+			policy: MergeOptions{DefaultCollisionAction: YieldOnCollision},
+			theirs: "same",
+			want:   map[string]string{"owner": "payments", "team": "core", "tier": "1"},
+		},
+		{
+			// The policy holds even though the added content replaces the control's
+			name:     "control wins over yielded content",
+			policy:   MergeOptions{DefaultCollisionAction: YieldOnCollision, MetadataMergePolicy: ControlMetadataWins},
+			theirs:   "other",
+			want:     map[string]string{"owner": "platform", "team": "core", "tier": "1"},
+			wantData: "other",
+		},
+		{
+			name:     "winner keeps its values by default",
+			policy:   MergeOptions{DefaultCollisionAction: YieldOnCollision},
+			theirs:   "other",
+			want:     map[string]string{"owner": "payments", "team": "core", "tier": "1"},
+			wantData: "other",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Union(annotatedGraph(t, "same", control), tt.policy, annotatedGraph(t, tt.theirs, added))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			node, err := FindByPath(result, "a.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			fileNode := node.(*FileNode)
+			if got := fileNode.Metadata(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("metadata = %v, want %v", got, tt.want)
+			}
+			if tt.wantData != "" {
+				if content, _ := fileNode.Content(); string(content) != tt.wantData {
+					t.Errorf("content = %q, want %q", content, tt.wantData)
+				}
+			}
+		})
+	}
+}
+
+// collisionGraph returns a graph holding dir/a.txt with content, with the
+// collision actions of the file and its directory set as given.
+func collisionGraph(t *testing.T, content string, fileAction, dirAction CollisionAction) SkaffoldNode {
+	t.Helper()
+	root, err := NewBuilder("root").
+		Dir("dir", func(b *Builder) { b.File("a.txt").Content([]byte(content)) }).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := FindByPath(root, "dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir.(*DirectoryNode).SetCollisionAction(dirAction)
+	file, err := FindByPath(root, "dir/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.(*FileNode).SetCollisionAction(fileAction)
+	return root
+}
+
+// fileContent returns the content of the file at p in root.
+func fileContent(t *testing.T, root SkaffoldNode, p string) string {
+	t.Helper()
+	node, err := FindByPath(root, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := node.(*FileNode).Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestUnionCollisionActions(t *testing.T) {
+	tests := []struct {
+		name                 string
+		defaultAction        CollisionAction
+		controlFile, addFile CollisionAction
+		controlDir, addDir   CollisionAction
+		want                 string
+		wantErr              bool
+	}{
+		{name: "default errors", defaultAction: DefaultOnCollision, wantErr: true},
+		{name: "error", defaultAction: ErrorOnCollision, wantErr: true},
+		{name: "overwrite keeps control", defaultAction: OverwriteOnCollision, want: "control"},
+		{name: "yield takes added", defaultAction: YieldOnCollision, want: "added"},
+		{name: "control file action wins over default", defaultAction: ErrorOnCollision, controlFile: YieldOnCollision, want: "added"},
+		{name: "control file action wins over added", controlFile: OverwriteOnCollision, addFile: YieldOnCollision, want: "control"},
+		{name: "added file action applies when control has none", addFile: YieldOnCollision, want: "added"},
+		{name: "file action wins over directory", controlFile: OverwriteOnCollision, controlDir: YieldOnCollision, want: "control"},
+		{name: "control directory action is inherited", controlDir: YieldOnCollision, want: "added"},
+		{name: "added directory action is inherited", addDir: OverwriteOnCollision, want: "control"},
+		{name: "directory error wins over default", defaultAction: YieldOnCollision, controlDir: ErrorOnCollision, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			control := collisionGraph(t, "control", tt.controlFile, tt.controlDir)
+			added := collisionGraph(t, "added", tt.addFile, tt.addDir)
+
+			result, err := Union(control, MergeOptions{DefaultCollisionAction: tt.defaultAction}, added)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), "dir/a.txt") {
+					t.Errorf("error %q does not name dir/a.txt", err)
+				}
+				return
+			}
+			if got := fileContent(t, result, "dir/a.txt"); got != tt.want {
+				t.Errorf("dir/a.txt = %q, want %q", got, tt.want)
+			}
+			// Neither input is modified
+			if got := fileContent(t, control, "dir/a.txt"); got != "control" {
+				t.Errorf("control graph now holds %q", got)
+			}
+			if got := fileContent(t, added, "dir/a.txt"); got != "added" {
+				t.Errorf("added graph now holds %q", got)
+			}
+		})
+	}
+}
+
+func TestUnionCollisionSeveralGraphs(t *testing.T) {
+	control := collisionGraph(t, "control", DefaultOnCollision, DefaultOnCollision)
+	first := collisionGraph(t, "first", DefaultOnCollision, DefaultOnCollision)
+	second := collisionGraph(t, "second", DefaultOnCollision, DefaultOnCollision)
+
+	// Each added graph is merged in turn, so the last to yield wins
+	result, err := Union(control, MergeOptions{DefaultCollisionAction: YieldOnCollision}, first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fileContent(t, result, "dir/a.txt"); got != "second" {
+		t.Errorf("dir/a.txt = %q, want %q", got, "second")
+	}
+
+	result, err = Union(control, MergeOptions{DefaultCollisionAction: OverwriteOnCollision}, first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fileContent(t, result, "dir/a.txt"); got != "control" {
+		t.Errorf("dir/a.txt = %q, want %q", got, "control")
+	}
+}
+
+func TestUnionIdenticalContent(t *testing.T) {
+	control := collisionGraph(t, "same", DefaultOnCollision, DefaultOnCollision)
+	added := collisionGraph(t, "same", DefaultOnCollision, DefaultOnCollision)
+	if _, err := Union(control, MergeOptions{DefaultCollisionAction: ErrorOnCollision}, added); err != nil {
+		t.Fatalf("identical files collided: %v", err)
+	}
+}
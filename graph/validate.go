@@ -0,0 +1,61 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validate checks a graph for problems recorded while it was built, such as
+// template files that failed to parse, and for structural problems: siblings
+// sharing a key, and nodes reachable more than once, including nodes that
+// are their own ancestor. It reports all of them at once. Since Walk and the
+// operations built on it would never finish on a cycle, graphs assembled
+// from untrusted code can be checked with Validate first.
+func Validate(root SkaffoldNode) error {
+	v := validator{
+		seen:     make(map[SkaffoldNode]bool),
+		visiting: make(map[SkaffoldNode]bool),
+	}
+	v.node(root, nil)
+	return errors.Join(v.errs...)
+}
+
+// validator accumulates the problems found by Validate. Nodes are tracked by
+// identity, so a node seen again while still visiting its descendants is a
+// cycle.
+type validator struct {
+	seen     map[SkaffoldNode]bool
+	visiting map[SkaffoldNode]bool
+	errs     []error
+}
+
+func (v *validator) node(node SkaffoldNode, segments []string) {
+	if v.visiting[node] {
+		v.errs = append(v.errs, fmt.Errorf("cycle at %s: node %s is its own ancestor", displayPath(segments), node.Key()))
+		return
+	}
+	if v.seen[node] {
+		v.errs = append(v.errs, fmt.Errorf("node at %s also appears elsewhere in the graph", displayPath(segments)))
+		return
+	}
+	v.seen[node] = true
+	v.visiting[node] = true
+	defer delete(v.visiting, node)
+
+	if fileNode, ok := node.(*FileNode); ok {
+		if err := fileNode.TemplateError(); err != nil {
+			v.errs = append(v.errs, fmt.Errorf("invalid template %s: %w", displayPath(segments), err))
+		}
+	}
+
+	keys := make(map[string]bool)
+	for _, child := range node.Children() {
+		childPath := append(segments[:len(segments):len(segments)], child.Key())
+		if keys[child.Key()] {
+			v.errs = append(v.errs, fmt.Errorf("%w %s in directory %s", ErrDuplicateChild, child.Key(), displayPath(segments)))
+			continue
+		}
+		keys[child.Key()] = true
+		v.node(child, childPath)
+	}
+}
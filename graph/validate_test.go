@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(root, src *DirectoryNode)
+		wantErr []string
+	}{
+		{name: "valid", corrupt: func(root, src *DirectoryNode) {}},
+		{
+			name: "cycle",
+			corrupt: func(root, src *DirectoryNode) {
+				// AddChild would reject the key, so link the nodes directly
+				src.children = append(src.children, root)
+			},
+			wantErr: []string{"cycle at src/root: node root is its own ancestor"},
+		},
+		{
+			name: "shared node",
+			corrupt: func(root, src *DirectoryNode) {
+				shared := NewFileNode("shared.txt")
+				root.children = append(root.children, shared)
+				src.children = append(src.children, shared)
+			},
+			wantErr: []string{"node at shared.txt also appears elsewhere in the graph"},
+		},
+		{
+			name: "duplicate keys",
+			corrupt: func(root, src *DirectoryNode) {
+				src.children = append(src.children, NewFileNode("main.go"))
+			},
+			wantErr: []string{"duplicate child main.go in directory src"},
+		},
+		{
+			name: "template error",
+			corrupt: func(root, src *DirectoryNode) {
+				src.children[0].(*FileNode).SetTemplateError(errors.New("unexpected EOF"))
+			},
+			wantErr: []string{"invalid template src/main.go: unexpected EOF"},
+		},
+		{
+			name: "all at once",
+			corrupt: func(root, src *DirectoryNode) {
+				src.children = append(src.children, NewFileNode("main.go"), root)
+			},
+			wantErr: []string{"duplicate child main.go", "cycle at src/root"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := buildTree(t, map[string]string{"README.md": "r", "src/main.go": "m"})
+			src := root.Children()[1].(*DirectoryNode)
+			tt.corrupt(root, src)
+
+			err := Validate(root)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Validate found no problems")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
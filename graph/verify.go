@@ -0,0 +1,128 @@
+package graph
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+const MISMATCH_MISSING = "MISSING" // listed in the manifest but absent from the graph
+const MISMATCH_EXTRA = "EXTRA"     // present in the graph but not listed in the manifest
+const MISMATCH_HASH = "HASH"       // present in both with differing hashes
+
+// Mismatch describes a difference between a graph and a checksum manifest.
+type Mismatch struct {
+	Path     string // Slash-separated path relative to the graph root
+	Kind     string // One of the MISMATCH_* constants
+	Expected []byte // Hash listed in the manifest, nil for MISMATCH_EXTRA
+	Actual   []byte // Hash of the graph node's content, nil for MISMATCH_MISSING
+}
+
+// VerifyAgainstManifest checks the file hashes in a graph against a checksum
+// manifest and reports missing, extra and mismatched files sorted by path.
+// The manifest uses the sha256sum format: one "<hex hash>  <path>" line per
+// file, with paths relative to the graph root. The output of md5sum and
+// sha1sum is accepted too, with the algorithm inferred from the hash length;
+// files hashed with a different algorithm are rehashed from their content.
+func VerifyAgainstManifest(root SkaffoldNode, manifest io.Reader) ([]Mismatch, error) {
+	expected, algorithm, err := readManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	actual := make(map[string][]byte)
+	err = Walk(root, func(node SkaffoldNode, depth int, segments []string) error {
+		if fileNode, ok := node.(*FileNode); ok {
+			p := path.Join(segments...)
+			hash := fileNode.DataHash()
+			if _, listed := expected[p]; listed {
+				rehashed, err := fileNode.hashWith(algorithm)
+				if err != nil {
+					return fmt.Errorf("failed to hash %s: %w", p, err)
+				}
+				hash = rehashed
+			}
+			actual[p] = hash
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mismatches := make([]Mismatch, 0)
+	for p, want := range expected {
+		got, ok := actual[p]
+		if !ok {
+			mismatches = append(mismatches, Mismatch{Path: p, Kind: MISMATCH_MISSING, Expected: want})
+		} else if !bytes.Equal(want, got) {
+			mismatches = append(mismatches, Mismatch{Path: p, Kind: MISMATCH_HASH, Expected: want, Actual: got})
+		}
+	}
+	for p, got := range actual {
+		if _, ok := expected[p]; !ok {
+			mismatches = append(mismatches, Mismatch{Path: p, Kind: MISMATCH_EXTRA, Actual: got})
+		}
+	}
+
+	sort.Slice(mismatches, func(i, j int) bool {
+		return mismatches[i].Path < mismatches[j].Path
+	})
+	return mismatches, nil
+}
+
+// manifestAlgorithms maps hash lengths in bytes to the algorithm producing them.
+var manifestAlgorithms = map[int]string{
+	16: HASHALGORITHM_MD5,
+	20: HASHALGORITHM_SHA1,
+	32: HASHALGORITHM_SHA256,
+}
+
+// readManifest parses a sha256sum-style manifest into a map of path to hash,
+// returning the algorithm shared by every entry, or "" for an empty manifest.
+func readManifest(r io.Reader) (map[string][]byte, string, error) {
+	entries := make(map[string][]byte)
+	algorithm := ""
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		hexHash, p, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, "", fmt.Errorf("malformed manifest line %d: %q", line, text)
+		}
+		hash, err := hex.DecodeString(hexHash)
+		if err != nil {
+			return nil, "", fmt.Errorf("malformed hash on manifest line %d: %w", line, err)
+		}
+		lineAlgorithm, ok := manifestAlgorithms[len(hash)]
+		if !ok {
+			return nil, "", fmt.Errorf("unrecognized hash length on manifest line %d", line)
+		}
+		if algorithm != "" && lineAlgorithm != algorithm {
+			return nil, "", fmt.Errorf("manifest line %d uses %s but earlier lines use %s", line, lineAlgorithm, algorithm)
+		}
+		algorithm = lineAlgorithm
+
+		// sha256sum separates the hash and path with a space and a mode marker
+		p, err = CleanPath(strings.TrimPrefix(strings.TrimLeft(p, " "), "*"))
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid path on manifest line %d: %w", line, err)
+		}
+		entries[p] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return entries, algorithm, nil
+}
@@ -0,0 +1,61 @@
+package graph
+
+import "errors"
+
+// SkipChildren can be returned by a WalkFunc to skip the children of the
+// node being visited. It is ignored by WalkPostOrder, which visits children
+// before their parent.
+var SkipChildren = errors.New("skip children")
+
+// WalkFunc is called for each node visited during a walk. depth is the
+// distance from the root and path holds the keys leading from the root to
+// node, excluding the root itself, so the root is visited with an empty path.
+// Returning an error other than SkipChildren stops the walk.
+type WalkFunc func(node SkaffoldNode, depth int, path []string) error
+
+// Walk traverses the graph depth-first in pre-order, visiting each node
+// before its children.
+func Walk(root SkaffoldNode, fn WalkFunc) error {
+	return walk(root, 0, nil, fn)
+}
+
+func walk(node SkaffoldNode, depth int, path []string, fn WalkFunc) error {
+	err := fn(node, depth, path)
+	if err == SkipChildren {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, child := range node.Children() {
+		// Cap the slice so siblings never share a backing array
+		childPath := append(path[:len(path):len(path)], child.Key())
+		if err := walk(child, depth+1, childPath, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WalkPostOrder traverses the graph depth-first in post-order, visiting
+// each node after all of its children. This suits operations such as
+// deletion or bottom-up aggregation.
+func WalkPostOrder(root SkaffoldNode, fn WalkFunc) error {
+	return walkPostOrder(root, 0, nil, fn)
+}
+
+func walkPostOrder(node SkaffoldNode, depth int, path []string, fn WalkFunc) error {
+	for _, child := range node.Children() {
+		childPath := append(path[:len(path):len(path)], child.Key())
+		if err := walkPostOrder(child, depth+1, childPath, fn); err != nil {
+			return err
+		}
+	}
+
+	err := fn(node, depth, path)
+	if err == SkipChildren {
+		return nil
+	}
+	return err
+}
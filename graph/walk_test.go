@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWalkOrder(t *testing.T) {
+	root := buildTree(t, map[string]string{"a/x": "", "a/y/z": "", "b": ""})
+
+	visit := func(visited *[]string, skip string) WalkFunc {
+		return func(node SkaffoldNode, depth int, path []string) error {
+			*visited = append(*visited, fmt.Sprintf("%d:%s", depth, strings.Join(path, "/")))
+			if node.Key() == skip {
+				return SkipChildren
+			}
+			return nil
+		}
+	}
+
+	tests := []struct {
+		name string
+		walk func(SkaffoldNode, WalkFunc) error
+		skip string
+		want []string
+	}{
+		{"pre-order", Walk, "", []string{"0:", "1:a", "2:a/x", "2:a/y", "3:a/y/z", "1:b"}},
+		{"pre-order skipping", Walk, "y", []string{"0:", "1:a", "2:a/x", "2:a/y", "1:b"}},
+		{"post-order", WalkPostOrder, "", []string{"2:a/x", "3:a/y/z", "2:a/y", "1:a", "1:b", "0:"}},
+		{"post-order ignores skip", WalkPostOrder, "y", []string{"2:a/x", "3:a/y/z", "2:a/y", "1:a", "1:b", "0:"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var visited []string
+			if err := tt.walk(root, visit(&visited, tt.skip)); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(visited, tt.want) {
+				t.Errorf("visited %v, want %v", visited, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkStops(t *testing.T) {
+	root := buildTree(t, map[string]string{"a/x": "", "b": ""})
+	stop := errors.New("stop")
+
+	var visited []string
+	err := Walk(root, func(node SkaffoldNode, depth int, path []string) error {
+		visited = append(visited, strings.Join(path, "/"))
+		if node.Key() == "x" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("Walk error = %v, want the error returned by the WalkFunc", err)
+	}
+	if want := []string{"", "a", "a/x"}; !reflect.DeepEqual(visited, want) {
+		t.Errorf("visited %v, want %v", visited, want)
+	}
+}
+
+func TestWalkPathsAreNotShared(t *testing.T) {
+	root := buildTree(t, map[string]string{"a/x": "", "a/y": "", "a/z": ""})
+
+	var paths [][]string
+	err := Walk(root, func(node SkaffoldNode, depth int, path []string) error {
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{nil, {"a"}, {"a", "x"}, {"a", "y"}, {"a", "z"}}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("retained paths %v, want %v", paths, want)
+	}
+}
@@ -0,0 +1,100 @@
+// Package graphtest provides helpers for tests, benchmarks and fuzzing that
+// need scaffold graphs.
+package graphtest
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// GenOptions shapes the graphs produced by Generate. Zero values select the defaults.
+type GenOptions struct {
+	MaxDepth      int     // Maximum directory nesting below the root (default 3)
+	MaxBreadth    int     // Maximum children per directory (default 5)
+	MaxFileSize   int     // Maximum file content size in bytes (default 1024)
+	BinaryRatio   float64 // Fraction of files with binary rather than text content
+	TemplateRatio float64 // Fraction of text files that are templates
+}
+
+func (o GenOptions) withDefaults() GenOptions {
+	if o.MaxDepth <= 0 {
+		o.MaxDepth = 3
+	}
+	if o.MaxBreadth <= 0 {
+		o.MaxBreadth = 5
+	}
+	if o.MaxFileSize <= 0 {
+		o.MaxFileSize = 1024
+	}
+	return o
+}
+
+// Generate deterministically produces a random graph from seed, so the same
+// seed and options always yield the same structure, names and content.
+func Generate(seed int64, opts GenOptions) graph.SkaffoldNode {
+	g := &generator{
+		rng:  rand.New(rand.NewSource(seed)),
+		opts: opts.withDefaults(),
+	}
+	root := graph.NewDirectoryNode("root")
+	g.fill(root, 0)
+	return root
+}
+
+type generator struct {
+	rng  *rand.Rand
+	opts GenOptions
+}
+
+// fill populates dir with a random mix of files and subdirectories.
+func (g *generator) fill(dir *graph.DirectoryNode, depth int) {
+	count := g.rng.Intn(g.opts.MaxBreadth + 1)
+	for i := 0; i < count; i++ {
+		var child graph.SkaffoldNode
+		// Directories become less likely deeper in the tree and stop at MaxDepth
+		if depth < g.opts.MaxDepth && g.rng.Intn(g.opts.MaxDepth+1) > depth {
+			subDir := graph.NewDirectoryNode(fmt.Sprintf("dir%d", i))
+			g.fill(subDir, depth+1)
+			child = subDir
+		} else {
+			child = g.file(i)
+		}
+		_ = child.SetParent(dir)
+		_ = dir.AddChild(child)
+	}
+}
+
+// file creates a file node with random text, template or binary content.
+func (g *generator) file(i int) *graph.FileNode {
+	size := g.rng.Intn(g.opts.MaxFileSize + 1)
+
+	var fileNode *graph.FileNode
+	switch {
+	case g.rng.Float64() < g.opts.BinaryRatio:
+		content := make([]byte, size)
+		g.rng.Read(content)
+		fileNode, _ = graph.NewFileNodeFull(fmt.Sprintf("file%d.bin", i), content, graph.FILEACTION_COPY)
+	case g.rng.Float64() < g.opts.TemplateRatio:
+		content := append([]byte("{{.Name}}\n"), g.text(size)...)
+		fileNode, _ = graph.NewFileNodeFull(fmt.Sprintf("file%d.txt.tmpl", i), content, graph.FILEACTION_TEMPLATE)
+	default:
+		fileNode, _ = graph.NewFileNodeFull(fmt.Sprintf("file%d.txt", i), g.text(size), graph.FILEACTION_COPY)
+	}
+	return fileNode
+}
+
+// text returns size bytes of printable ASCII broken into lines.
+func (g *generator) text(size int) []byte {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz      "
+	content := make([]byte, size)
+	for i := range content {
+		if i%64 == 63 {
+			content[i] = '\n'
+			continue
+		}
+		content[i] = alphabet[g.rng.Intn(len(alphabet))]
+	}
+	return content
+}
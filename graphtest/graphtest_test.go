@@ -0,0 +1,70 @@
+package graphtest
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// describe lists every node below root with its depth, action, size and hash.
+func describe(t *testing.T, node graph.SkaffoldNode, prefix string, depth int, out *[]string) {
+	t.Helper()
+	p := path.Join(prefix, node.Key())
+	switch n := node.(type) {
+	case *graph.DirectoryNode:
+		*out = append(*out, fmt.Sprintf("%d %s/ %d", depth, p, len(n.Children())))
+		for _, child := range n.Children() {
+			describe(t, child, p, depth+1, out)
+		}
+	case *graph.FileNode:
+		*out = append(*out, fmt.Sprintf("%d %s %s %d %x", depth, p, n.Action(), n.Size(), n.DataHash()))
+	default:
+		t.Fatalf("unexpected node %T at %s", node, p)
+	}
+}
+
+func TestGenerateDeterministic(t *testing.T) {
+	opts := GenOptions{BinaryRatio: 0.2, TemplateRatio: 0.3}
+
+	var first, second, other []string
+	describe(t, Generate(42, opts), "", 0, &first)
+	describe(t, Generate(42, opts), "", 0, &second)
+	describe(t, Generate(43, opts), "", 0, &other)
+
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("the same seed produced different graphs:\n%v\n%v", first, second)
+	}
+	if reflect.DeepEqual(first, other) {
+		t.Error("different seeds produced the same graph")
+	}
+}
+
+func TestGenerateBounds(t *testing.T) {
+	opts := GenOptions{MaxDepth: 2, MaxBreadth: 3, MaxFileSize: 16}
+
+	var check func(seed int64, node graph.SkaffoldNode, depth int)
+	check = func(seed int64, node graph.SkaffoldNode, depth int) {
+		switch n := node.(type) {
+		case *graph.DirectoryNode:
+			if depth > opts.MaxDepth {
+				t.Errorf("seed %d: directory %s is deeper than %d", seed, n.Key(), opts.MaxDepth)
+			}
+			if len(n.Children()) > opts.MaxBreadth {
+				t.Errorf("seed %d: directory %s has more than %d children", seed, n.Key(), opts.MaxBreadth)
+			}
+			for _, child := range n.Children() {
+				check(seed, child, depth+1)
+			}
+		case *graph.FileNode:
+			if n.Size() > int64(opts.MaxFileSize) {
+				t.Errorf("seed %d: file %s is %d bytes, more than %d", seed, n.Key(), n.Size(), opts.MaxFileSize)
+			}
+		}
+	}
+	for seed := int64(0); seed < 20; seed++ {
+		check(seed, Generate(seed, opts), 0)
+	}
+}
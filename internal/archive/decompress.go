@@ -0,0 +1,55 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// encodings maps the extensions of individually compressed entries that can
+// be decoded to the name of their encoding.
+var encodings = map[string]string{
+	".gz": "gzip",
+}
+
+// TrimEncoding returns the cleaned path p without its extension and the name
+// of the encoding the extension stands for, when the extension is one of exts
+// and its encoding can be decoded. Otherwise p is returned unchanged with an
+// empty encoding, so entries such as .br files are kept as they are.
+func TrimEncoding(p string, exts []string) (string, string) {
+	for _, ext := range exts {
+		encoding, ok := encodings[strings.ToLower(ext)]
+		if !ok || len(p) <= len(ext) || !strings.EqualFold(p[len(p)-len(ext):], ext) {
+			continue
+		}
+		trimmed := p[:len(p)-len(ext)]
+		if strings.HasSuffix(trimmed, "/") {
+			// An entry named only by the extension keeps its name
+			continue
+		}
+		return trimmed, encoding
+	}
+	return p, ""
+}
+
+// Decode returns data decoded from the named encoding, as returned by
+// TrimEncoding.
+func Decode(p string, data []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case "gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress archive entry %s: %w", p, err)
+		}
+		defer zr.Close()
+		decoded, err := io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress archive entry %s: %w", p, err)
+		}
+		return decoded, nil
+	default:
+		return nil, fmt.Errorf("cannot decompress archive entry %s: unknown encoding %s", p, encoding)
+	}
+}
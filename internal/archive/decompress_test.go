@@ -0,0 +1,51 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func TestTrimEncoding(t *testing.T) {
+	tests := []struct {
+		p            string
+		exts         []string
+		want         string
+		wantEncoding string
+	}{
+		{p: "dir/a.txt.gz", exts: []string{".gz"}, want: "dir/a.txt", wantEncoding: "gzip"},
+		{p: "A.TXT.GZ", exts: []string{".gz"}, want: "A.TXT", wantEncoding: "gzip"},
+		{p: "a.txt.gz", exts: nil, want: "a.txt.gz"},
+		{p: "a.txt.br", exts: []string{".gz", ".br"}, want: "a.txt.br"},
+		{p: "dir/.gz", exts: []string{".gz"}, want: "dir/.gz"},
+		{p: ".gz", exts: []string{".gz"}, want: ".gz"},
+	}
+	for _, tt := range tests {
+		got, encoding := TrimEncoding(tt.p, tt.exts)
+		if got != tt.want || encoding != tt.wantEncoding {
+			t.Errorf("TrimEncoding(%q, %q) = %q, %q; want %q, %q", tt.p, tt.exts, got, encoding, tt.want, tt.wantEncoding)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Decode("a.txt.gz", buf.Bytes(), "gzip")
+	if err != nil || string(data) != "hello\n" {
+		t.Errorf("Decode = %q, %v; want hello", data, err)
+	}
+	if _, err := Decode("a.txt.gz", []byte("not gzip"), "gzip"); err == nil {
+		t.Error("decoded content that is not gzip")
+	}
+	if _, err := Decode("a.txt.br", buf.Bytes(), "br"); err == nil {
+		t.Error("decoded an unknown encoding")
+	}
+}
@@ -0,0 +1,145 @@
+// Package archive assembles scaffold graphs from the flat, slash-separated
+// entry names found in archive formats such as tar and zip.
+package archive
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// RootKey is the key of the root directory of a graph read from an archive,
+// which has no name of its own.
+const RootKey = "."
+
+// DuplicatePolicy determines what happens when an archive holds a file or
+// symlink at a path already taken by an earlier entry.
+type DuplicatePolicy int
+
+const (
+	// LastWins replaces the earlier entry, as extracting the archive would.
+	LastWins DuplicatePolicy = iota
+	// FirstWins keeps the earlier entry and ignores the later one.
+	FirstWins
+	// ErrorOnDuplicate aborts the build with an error naming the path.
+	ErrorOnDuplicate
+)
+
+func (p DuplicatePolicy) String() string {
+	switch p {
+	case LastWins:
+		return "LAST_WINS"
+	case FirstWins:
+		return "FIRST_WINS"
+	case ErrorOnDuplicate:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("DuplicatePolicy(%d)", int(p))
+	}
+}
+
+// Tree builds a graph from archive entries, which may appear in any order.
+type Tree struct {
+	root   *graph.DirectoryNode
+	dirs   map[string]*graph.DirectoryNode
+	policy DuplicatePolicy
+}
+
+// NewTree creates a Tree with an empty root directory in which later entries
+// replace earlier ones at the same path.
+func NewTree() *Tree {
+	return NewTreeWithPolicy(LastWins)
+}
+
+// NewTreeWithPolicy creates a Tree with an empty root directory that resolves
+// entries at the same path according to policy.
+func NewTreeWithPolicy(policy DuplicatePolicy) *Tree {
+	root := graph.NewDirectoryNode(RootKey)
+	return &Tree{
+		root:   root,
+		dirs:   map[string]*graph.DirectoryNode{".": root},
+		policy: policy,
+	}
+}
+
+// Root returns the root of the graph built so far.
+func (t *Tree) Root() graph.SkaffoldNode {
+	return t.root
+}
+
+// Clean normalizes an entry name to a path relative to the root, rejecting
+// names that are absolute or climb above the root.
+func Clean(name string) (string, error) {
+	cleaned, err := graph.CleanPath(strings.TrimSuffix(name, "/"))
+	if err != nil {
+		return "", fmt.Errorf("invalid archive entry: %w", err)
+	}
+	return cleaned, nil
+}
+
+// Dir returns the directory at the cleaned path p, creating it and any missing
+// parents. It is an error for a file or symlink to already occupy the path.
+func (t *Tree) Dir(p string) (*graph.DirectoryNode, error) {
+	if dir, ok := t.dirs[p]; ok {
+		return dir, nil
+	}
+
+	parentPath, name := split(p)
+	parent, err := t.Dir(parentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := graph.NewDirectoryNode(name)
+	_ = dir.SetParent(parent)
+	if err := parent.AddChild(dir); err != nil {
+		return nil, fmt.Errorf("archive entry %s is both a directory and a file: %w", p, err)
+	}
+	t.dirs[p] = dir
+	return dir, nil
+}
+
+// Add places node at the cleaned path p, whose final segment must match the
+// node key, creating missing parent directories, and reports whether it did.
+// An earlier file or symlink at the same path is resolved by the duplicate
+// policy of the tree: it is replaced, kept in place of node, or an error.
+func (t *Tree) Add(p string, node graph.SkaffoldNode) (bool, error) {
+	if p == "." {
+		return false, fmt.Errorf("archive entry for the root must be a directory")
+	}
+	if _, ok := t.dirs[p]; ok {
+		return false, fmt.Errorf("archive entry %s is both a directory and a %s", p, strings.ToLower(node.Type()))
+	}
+
+	parentPath, _ := split(p)
+	parent, err := t.Dir(parentPath)
+	if err != nil {
+		return false, err
+	}
+
+	_ = node.SetParent(parent)
+	if err := parent.AddChild(node); err == nil {
+		return true, nil
+	}
+	switch t.policy {
+	case FirstWins:
+		return false, nil
+	case ErrorOnDuplicate:
+		return false, fmt.Errorf("archive entry %s appears more than once", p)
+	default:
+		if err := parent.ReplaceChild(node); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+}
+
+// split returns the parent path and final segment of the cleaned path p.
+func split(p string) (string, string) {
+	idx := strings.LastIndex(p, "/")
+	if idx < 0 {
+		return ".", p
+	}
+	return p[:idx], p[idx+1:]
+}
@@ -0,0 +1,100 @@
+package archive
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+)
+
+func fileWith(key, content string) *graph.FileNode {
+	file := graph.NewFileNode(key)
+	file.SetContent([]byte(content))
+	return file
+}
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "dir/", want: "dir"},
+		{name: "./dir/a.txt", want: "dir/a.txt"},
+		{name: "dir/../a.txt", want: "a.txt"},
+		{name: "/etc/passwd", wantErr: true},
+		{name: "../a.txt", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Clean(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Clean(%q) = %q, %v; want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestTreeCreatesParents(t *testing.T) {
+	tree := NewTree()
+	if _, err := tree.Add("a/b/c.txt", fileWith("c.txt", "c")); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := tree.Dir("a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := dir.Child("c.txt"); !ok {
+		t.Error("a/b has no c.txt")
+	}
+	if node, err := graph.FindByPath(tree.Root(), "a/b/c.txt"); err != nil || node.Key() != "c.txt" {
+		t.Errorf("a/b/c.txt = %v, %v", node, err)
+	}
+
+	if _, err := tree.Add("a", fileWith("a", "a")); err == nil || !strings.Contains(err.Error(), "both a directory and a file") {
+		t.Errorf("adding a file over a directory: %v", err)
+	}
+	if _, err := tree.Dir("a/b/c.txt"); err == nil {
+		t.Error("created a directory over a file")
+	}
+	if _, err := tree.Add(".", fileWith(".", "root")); err == nil {
+		t.Error("added a file as the root")
+	}
+}
+
+func TestTreeDuplicatePolicy(t *testing.T) {
+	tests := []struct {
+		policy    DuplicatePolicy
+		want      string
+		wantAdded bool
+		wantErr   bool
+	}{
+		{policy: LastWins, want: "second", wantAdded: true},
+		{policy: FirstWins, want: "first"},
+		{policy: ErrorOnDuplicate, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			tree := NewTreeWithPolicy(tt.policy)
+			if _, err := tree.Add("dir/a.txt", fileWith("a.txt", "first")); err != nil {
+				t.Fatal(err)
+			}
+			added, err := tree.Add("dir/a.txt", fileWith("a.txt", "second"))
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "dir/a.txt appears more than once") {
+					t.Errorf("error = %v, want the duplicate path named", err)
+				}
+				return
+			}
+			if err != nil || added != tt.wantAdded {
+				t.Fatalf("Add = %v, %v; want %v", added, err, tt.wantAdded)
+			}
+			node, err := graph.FindByPath(tree.Root(), "dir/a.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err := node.(*graph.FileNode).Content()
+			if err != nil || string(content) != tt.want {
+				t.Errorf("content = %q, %v; want %q", content, err, tt.want)
+			}
+		})
+	}
+}
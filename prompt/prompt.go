@@ -0,0 +1,127 @@
+// Package prompt collects the values of template variables, such as those
+// reported by render.RequiredVars, by asking the user for each one.
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sthussey/ska/render"
+)
+
+// PromptOptions controls how variables are collected.
+type PromptOptions struct {
+	// In is read for answers, defaulting to os.Stdin.
+	In io.Reader
+	// Out receives the prompts, defaulting to os.Stderr so they stay out of
+	// any output written to stdout.
+	Out io.Writer
+	// Values holds variables already known, nested as render.Render expects.
+	// Variables found in it are not prompted for and are kept in the result.
+	Values map[string]any
+	// Defaults offers a value for variables by dotted name, accepted by
+	// answering with an empty line.
+	Defaults map[string]string
+	// NonInteractive never prompts: variables without a value or default
+	// are reported together as an error.
+	NonInteractive bool
+}
+
+// Collect prompts on stdin for the value of each named variable.
+func Collect(names []string) (map[string]any, error) {
+	return CollectWithOptions(names, PromptOptions{})
+}
+
+// CollectWithOptions returns the variables of opts.Values together with a
+// value for each named variable missing from them, prompting for it in the
+// order given. Names are dotted paths such as "Author.Name", whose values are
+// nested under "Author" in the result. A name that only leads to other names,
+// as "Author" does here, is not prompted for. Answers are kept as strings.
+func CollectWithOptions(names []string, opts PromptOptions) (map[string]any, error) {
+	in := opts.In
+	if in == nil {
+		in = os.Stdin
+	}
+	out := opts.Out
+	if out == nil {
+		out = os.Stderr
+	}
+
+	vars := render.MergeValues(opts.Values)
+	reader := bufio.NewReader(in)
+	var missing []string
+	for _, name := range names {
+		if _, ok := render.LookupValue(vars, name); ok || isParent(name, names) {
+			continue
+		}
+
+		def, hasDefault := opts.Defaults[name]
+		var value string
+		switch {
+		case opts.NonInteractive && hasDefault:
+			value = def
+		case opts.NonInteractive:
+			missing = append(missing, name)
+			continue
+		default:
+			var err error
+			value, err = ask(reader, out, name, def, hasDefault)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if err := render.SetValue(vars, name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("no value for variables %s", strings.Join(missing, ", "))
+	}
+	return vars, nil
+}
+
+// ask prompts for the variable name until it gets a non-empty answer or the
+// default is accepted.
+func ask(reader *bufio.Reader, out io.Writer, name, def string, hasDefault bool) (string, error) {
+	for {
+		if hasDefault {
+			fmt.Fprintf(out, "%s [%s]: ", name, def)
+		} else {
+			fmt.Fprintf(out, "%s: ", name)
+		}
+
+		line, err := reader.ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", fmt.Errorf("failed to read value for %s: %w", name, err)
+		}
+
+		answer := strings.TrimSpace(line)
+		switch {
+		case answer != "":
+			return answer, nil
+		case hasDefault:
+			return def, nil
+		default:
+			fmt.Fprintf(out, "a value for %s is required\n", name)
+		}
+	}
+}
+
+// isParent reports whether name leads to any other of names.
+func isParent(name string, names []string) bool {
+	for _, other := range names {
+		if strings.HasPrefix(other, name+".") {
+			return true
+		}
+	}
+	return false
+}
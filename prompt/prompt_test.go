@@ -0,0 +1,158 @@
+package prompt
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCollectWithOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		names    []string
+		input    string
+		values   map[string]any
+		defaults map[string]string
+		want     map[string]any
+		prompts  string
+	}{
+		{
+			name:    "answers in order",
+			names:   []string{"Name", "Version"},
+			input:   "demo\n1.0\n",
+			want:    map[string]any{"Name": "demo", "Version": "1.0"},
+			prompts: "Name: Version: ",
+		},
+		{
+			name:    "nested names",
+			names:   []string{"Author", "Author.Email", "Author.Name"},
+			input:   "a@example.com\n  Ada  \n",
+			want:    map[string]any{"Author": map[string]any{"Email": "a@example.com", "Name": "Ada"}},
+			prompts: "Author.Email: Author.Name: ",
+		},
+		{
+			name:     "defaults",
+			names:    []string{"License", "Name"},
+			input:    "\nAPACHE\n",
+			defaults: map[string]string{"License": "MIT", "Name": "demo"},
+			want:     map[string]any{"License": "MIT", "Name": "APACHE"},
+			prompts:  "License [MIT]: Name [demo]: ",
+		},
+		{
+			name:    "empty answer asks again",
+			names:   []string{"Name"},
+			input:   "\n\ndemo\n",
+			want:    map[string]any{"Name": "demo"},
+			prompts: "Name: a value for Name is required\nName: a value for Name is required\nName: ",
+		},
+		{
+			name:    "known values are kept",
+			names:   []string{"Author.Email", "Author.Name", "Name"},
+			input:   "b@example.com\n",
+			values:  map[string]any{"Name": "demo", "Author": map[string]any{"Name": "Ada"}, "Extra": 1},
+			want:    map[string]any{"Name": "demo", "Extra": 1, "Author": map[string]any{"Name": "Ada", "Email": "b@example.com"}},
+			prompts: "Author.Email: ",
+		},
+		{
+			name:    "last answer without newline",
+			names:   []string{"Name"},
+			input:   "demo",
+			want:    map[string]any{"Name": "demo"},
+			prompts: "Name: ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			got, err := CollectWithOptions(tt.names, PromptOptions{
+				In:       strings.NewReader(tt.input),
+				Out:      &out,
+				Values:   tt.values,
+				Defaults: tt.defaults,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CollectWithOptions = %v, want %v", got, tt.want)
+			}
+			if out.String() != tt.prompts {
+				t.Errorf("prompts = %q, want %q", out.String(), tt.prompts)
+			}
+		})
+	}
+}
+
+func TestCollectWithOptionsLeavesValuesUnchanged(t *testing.T) {
+	values := map[string]any{"Author": map[string]any{"Name": "Ada"}}
+	_, err := CollectWithOptions([]string{"Author.Email"}, PromptOptions{
+		In:     strings.NewReader("a@example.com\n"),
+		Out:    io.Discard,
+		Values: values,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]any{"Author": map[string]any{"Name": "Ada"}}; !reflect.DeepEqual(values, want) {
+		t.Errorf("Values = %v after collecting, want them unchanged", values)
+	}
+}
+
+func TestCollectWithOptionsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		opts    PromptOptions
+		wantErr string
+	}{
+		{
+			name:    "input ends early",
+			names:   []string{"Name", "Version"},
+			opts:    PromptOptions{In: strings.NewReader("demo\n")},
+			wantErr: "failed to read value for Version",
+		},
+		{
+			name:    "non-interactive without values",
+			names:   []string{"Name", "License", "Version"},
+			opts:    PromptOptions{NonInteractive: true, Defaults: map[string]string{"License": "MIT"}},
+			wantErr: "no value for variables Name, Version",
+		},
+		{
+			name:    "value is not a map",
+			names:   []string{"Author.Name"},
+			opts:    PromptOptions{In: strings.NewReader("Ada\n"), Values: map[string]any{"Author": "Ada"}},
+			wantErr: "cannot set Author.Name: Author is not a map",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Out = io.Discard
+			_, err := CollectWithOptions(tt.names, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CollectWithOptions error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	_, err := CollectWithOptions([]string{"Name"}, PromptOptions{In: strings.NewReader(""), Out: io.Discard})
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("CollectWithOptions on empty input error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestCollectWithOptionsNonInteractiveDefaults(t *testing.T) {
+	got, err := CollectWithOptions([]string{"License"}, PromptOptions{
+		In:             strings.NewReader("ignored\n"),
+		Out:            io.Discard,
+		Defaults:       map[string]string{"License": "MIT"},
+		NonInteractive: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]any{"License": "MIT"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CollectWithOptions = %v, want %v", got, want)
+	}
+}
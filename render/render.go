@@ -0,0 +1,321 @@
+// Package render expands a scaffold graph into its final form by executing
+// TEMPLATE files and templated node names with Go text/template.
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// TemplateSuffix is removed from the names of rendered TEMPLATE files.
+const TemplateSuffix = ".tmpl"
+
+// METADATA_ORIGIN is the metadata key under which Render records how each
+// output file was produced, as one of the ORIGIN_* constants, so tools can
+// regenerate generated files while leaving copied files, which a user may
+// have edited, alone.
+const METADATA_ORIGIN = "render-origin"
+
+const ORIGIN_GENERATED = "GENERATED" // Rendered from a TEMPLATE file
+const ORIGIN_COPIED = "COPIED"       // Copied verbatim from a COPY file
+
+// Default limits on a single template execution, generous enough for any
+// scaffold file while stopping runaway templates.
+const (
+	DefaultMaxOutputBytes int64 = 64 << 20
+	DefaultTimeout              = 30 * time.Second
+)
+
+// ErrOutputTooLarge is returned when a template produces more output than
+// RenderOptions.MaxOutputBytes allows.
+var ErrOutputTooLarge = errors.New("template output too large")
+
+// attributed is implemented by nodes carrying filesystem attributes.
+type attributed interface {
+	Xattrs() map[string][]byte
+	SetXattr(name string, value []byte)
+	Owner() (int, int, bool)
+	SetOwner(uid, gid int)
+	Mode() os.FileMode
+	SetMode(mode os.FileMode)
+	CollisionAction() graph.CollisionAction
+	SetCollisionAction(action graph.CollisionAction)
+	Metadata() map[string]string
+	SetMetadata(key, value string)
+}
+
+// RenderOptions controls how RenderWithOptions expands a graph.
+type RenderOptions struct {
+	// Merge combines siblings whose names render alike, as "{{.Module}}" and
+	// "api" do when Module is "api": directories are merged and files whose
+	// content differs are resolved by collision action, as by graph.Union.
+	Merge graph.MergeOptions
+	// MaxOutputBytes bounds the output of each template, including templated
+	// names and symlink targets. Zero uses DefaultMaxOutputBytes and a
+	// negative value removes the limit.
+	MaxOutputBytes int64
+	// Timeout bounds the execution of each template. Zero uses
+	// DefaultTimeout and a negative value removes the limit. text/template
+	// cannot be interrupted, so a template that times out without writing,
+	// such as one looping forever, leaves its goroutine running until the
+	// program exits.
+	Timeout time.Duration
+}
+
+// maxOutputBytes returns the output limit in effect, or zero for none.
+func (o RenderOptions) maxOutputBytes() int64 {
+	switch {
+	case o.MaxOutputBytes == 0:
+		return DefaultMaxOutputBytes
+	case o.MaxOutputBytes < 0:
+		return 0
+	default:
+		return o.MaxOutputBytes
+	}
+}
+
+// timeout returns the execution time limit in effect, or zero for none.
+func (o RenderOptions) timeout() time.Duration {
+	switch {
+	case o.Timeout == 0:
+		return DefaultTimeout
+	case o.Timeout < 0:
+		return 0
+	default:
+		return o.Timeout
+	}
+}
+
+// Render returns a copy of the graph rooted at root with every TEMPLATE file
+// executed against vars, producing a COPY file holding the rendered content
+// and named without its .tmpl suffix. Directory and file names containing
+// template actions, such as "{{.ProjectName}}.go", are expanded as well, as
+// are the targets of symlinks. Every output file records under
+// METADATA_ORIGIN whether it was generated or copied.
+// Referencing a variable missing from vars is an error. The input graph is
+// not modified. Each template is limited to DefaultMaxOutputBytes of output
+// and DefaultTimeout of execution.
+func Render(root graph.SkaffoldNode, vars map[string]any) (graph.SkaffoldNode, error) {
+	return RenderWithOptions(context.Background(), root, vars, RenderOptions{})
+}
+
+// RenderWithOptions renders the graph as Render does using the provided
+// options, stopping when ctx is done. Siblings whose names render alike are
+// merged according to opts.Merge, so by default they combine unless two
+// files differ in content.
+func RenderWithOptions(ctx context.Context, root graph.SkaffoldNode, vars map[string]any, opts RenderOptions) (graph.SkaffoldNode, error) {
+	r := renderer{ctx: ctx, vars: vars, opts: opts}
+	return r.renderNode(root, nil, root.Key())
+}
+
+// renderer holds the state shared by a single render.
+type renderer struct {
+	ctx  context.Context
+	vars map[string]any
+	opts RenderOptions
+}
+
+// renderNode renders n and its descendants, where keyPath locates n in the
+// source graph for error messages and parent is the rendered directory that
+// will hold it, so merges see the collision actions of its ancestors.
+func (r renderer) renderNode(n, parent graph.SkaffoldNode, keyPath string) (graph.SkaffoldNode, error) {
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	key, err := r.renderKey(n.Key(), keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node := n.(type) {
+	case *graph.DirectoryNode:
+		dirNode := graph.NewDirectoryNode(key)
+		_ = dirNode.SetParent(parent)
+		if err := dirNode.SetDefaultAction(node.DefaultAction()); err != nil {
+			return nil, err
+		}
+		copyAttributes(dirNode, node)
+
+		seen := make(map[string]string, len(node.Children()))
+		for _, child := range node.Children() {
+			childPath := path.Join(keyPath, child.Key())
+			rendered, err := r.renderNode(child, dirNode, childPath)
+			if err != nil {
+				return nil, err
+			}
+			seenKey := r.opts.Merge.MatchKey(rendered.Key())
+			if other, ok := seen[seenKey]; ok {
+				if err := dirNode.MergeChild(rendered, r.opts.Merge); err != nil {
+					return nil, fmt.Errorf("%s and %s both render to %s: %w", other, childPath, rendered.Key(), err)
+				}
+				continue
+			}
+			seen[seenKey] = childPath
+
+			if err := dirNode.AddChild(rendered); err != nil {
+				return nil, err
+			}
+		}
+		return dirNode, nil
+	case *graph.FileNode:
+		if node.Action() == graph.FILEACTION_TEMPLATE {
+			return r.renderFile(node, key, keyPath)
+		}
+		if key == node.Key() {
+			fileNode := graph.Clone(node).(*graph.FileNode)
+			fileNode.SetMetadata(METADATA_ORIGIN, ORIGIN_COPIED)
+			return fileNode, nil
+		}
+
+		content, err := node.Content()
+		if err != nil {
+			return nil, err
+		}
+		fileNode, err := graph.NewFileNodeFull(key, content, node.Action())
+		if err != nil {
+			return nil, err
+		}
+		copyAttributes(fileNode, node)
+		fileNode.SetMetadata(METADATA_ORIGIN, ORIGIN_COPIED)
+		return fileNode, nil
+	case *graph.SymlinkNode:
+		target := node.Target()
+		if strings.Contains(target, "{{") {
+			rendered, err := r.execute(keyPath, target)
+			if err != nil {
+				return nil, err
+			}
+			if target = string(rendered); target == "" {
+				return nil, fmt.Errorf("target of %s renders to an empty path", keyPath)
+			}
+		}
+		linkNode := graph.NewSymlinkNode(key, target)
+		if uid, gid, ok := node.Owner(); ok {
+			linkNode.SetOwner(uid, gid)
+		}
+		return linkNode, nil
+	default:
+		return nil, fmt.Errorf("node %s has unsupported implementation %T", keyPath, n)
+	}
+}
+
+// renderFile executes the content of a TEMPLATE file and returns the rendered
+// COPY file named key without its template suffix.
+func (r renderer) renderFile(node *graph.FileNode, key string, keyPath string) (graph.SkaffoldNode, error) {
+	content, err := node.Content()
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := r.execute(keyPath, string(content))
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(key, TemplateSuffix)
+	if name == "" {
+		return nil, fmt.Errorf("template %s has no name once rendered", keyPath)
+	}
+	fileNode, err := graph.NewFileNodeFull(name, rendered, graph.FILEACTION_COPY)
+	if err != nil {
+		return nil, err
+	}
+	copyAttributes(fileNode, node)
+	fileNode.SetMetadata(METADATA_ORIGIN, ORIGIN_GENERATED)
+	return fileNode, nil
+}
+
+// renderKey expands template actions in a node name and checks the result is
+// still a single path segment.
+func (r renderer) renderKey(key string, keyPath string) (string, error) {
+	if !strings.Contains(key, "{{") {
+		return key, nil
+	}
+	rendered, err := r.execute(keyPath, key)
+	if err != nil {
+		return "", err
+	}
+	name := string(rendered)
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		return "", fmt.Errorf("name of %s renders to invalid name %q", keyPath, name)
+	}
+	return name, nil
+}
+
+// execute parses text as a template called name and executes it against
+// vars, failing on any variable missing from vars or once the output or
+// execution time limits are exceeded. Errors from text/template already
+// identify the template by name.
+func (r renderer) execute(name, text string) ([]byte, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	ctx := r.ctx
+	if timeout := r.opts.timeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// text/template cannot be interrupted, so execution runs apart and is
+	// abandoned when ctx is done. The writer stops it at its next write.
+	out := &limitedBuffer{ctx: ctx, limit: r.opts.maxOutputBytes()}
+	done := make(chan error, 1)
+	go func() { done <- tmpl.Execute(out, r.vars) }()
+	select {
+	case err := <-done:
+		if errors.Is(err, ErrOutputTooLarge) {
+			return nil, fmt.Errorf("failed to render template %s: %w: limit is %d bytes", name, err, out.limit)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to render template: %w", err)
+		}
+		return out.buf.Bytes(), nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("failed to render template %s: %w", name, ctx.Err())
+	}
+}
+
+// limitedBuffer collects template output, failing writes beyond limit bytes,
+// when limit is positive, or once ctx is done.
+type limitedBuffer struct {
+	ctx   context.Context
+	limit int64
+	buf   bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if err := b.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if b.limit > 0 && int64(b.buf.Len()+len(p)) > b.limit {
+		return 0, ErrOutputTooLarge
+	}
+	return b.buf.Write(p)
+}
+
+// copyAttributes copies the extended attributes, ownership, mode, collision
+// action and metadata of src to dst.
+func copyAttributes(dst, src attributed) {
+	for name, value := range src.Xattrs() {
+		dst.SetXattr(name, value)
+	}
+	if uid, gid, ok := src.Owner(); ok {
+		dst.SetOwner(uid, gid)
+	}
+	dst.SetMode(src.Mode())
+	dst.SetCollisionAction(src.CollisionAction())
+	for key, value := range src.Metadata() {
+		dst.SetMetadata(key, value)
+	}
+}
@@ -0,0 +1,357 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// addFile adds a file holding content with the given action to dir.
+func addFile(t *testing.T, dir *graph.DirectoryNode, name, content, action string) {
+	t.Helper()
+	file, err := graph.NewFileNodeFull(name, []byte(content), action)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = file.SetParent(dir)
+	if err := dir.AddChild(file); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// addDir adds an empty directory to dir and returns it.
+func addDir(t *testing.T, dir *graph.DirectoryNode, name string) *graph.DirectoryNode {
+	t.Helper()
+	sub := graph.NewDirectoryNode(name)
+	_ = sub.SetParent(dir)
+	if err := dir.AddChild(sub); err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+// childNamed returns the child of node with the given key, failing the test if
+// there is none.
+func childNamed(t *testing.T, node graph.SkaffoldNode, key string) graph.SkaffoldNode {
+	t.Helper()
+	for _, child := range node.Children() {
+		if child.Key() == key {
+			return child
+		}
+	}
+	t.Fatalf("%s has no child %s", node.Key(), key)
+	return nil
+}
+
+// fileText returns the content and action of the file node.
+func fileText(t *testing.T, node graph.SkaffoldNode) (string, string) {
+	t.Helper()
+	file, ok := node.(*graph.FileNode)
+	if !ok {
+		t.Fatalf("%s is a %T, not a file", node.Key(), node)
+	}
+	content, err := file.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content), file.Action()
+}
+
+func TestRender(t *testing.T) {
+	root := graph.NewDirectoryNode("app")
+	addFile(t, root, "README.md.tmpl", "# {{.Name}}\n", graph.FILEACTION_TEMPLATE)
+	addFile(t, root, "{{.Name}}.go", "package {{.Name}}\n", graph.FILEACTION_COPY)
+	addFile(t, addDir(t, root, "{{.Name}}"), "static.txt", "{{.Name}}", graph.FILEACTION_COPY)
+
+	out, err := Render(root, map[string]any{"Name": "demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path, content, action string
+	}{
+		// Templates are executed and lose their suffix
+		{"README.md", "# demo\n", graph.FILEACTION_COPY},
+		// Templated names are expanded but COPY content is left alone
+		{"demo.go", "package {{.Name}}\n", graph.FILEACTION_COPY},
+		{"demo/static.txt", "{{.Name}}", graph.FILEACTION_COPY},
+	}
+	for _, tt := range tests {
+		node := out
+		for _, key := range strings.Split(tt.path, "/") {
+			node = childNamed(t, node, key)
+		}
+		content, action := fileText(t, node)
+		if content != tt.content || action != tt.action {
+			t.Errorf("%s = %q (%s), want %q (%s)", tt.path, content, action, tt.content, tt.action)
+		}
+	}
+
+	// The input graph is left untouched
+	content, action := fileText(t, childNamed(t, root, "README.md.tmpl"))
+	if content != "# {{.Name}}\n" || action != graph.FILEACTION_TEMPLATE {
+		t.Errorf("input template changed to %q (%s)", content, action)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{"missing variable", "README.md.tmpl", "{{.Missing}}", "Missing"},
+		{"parse error", "README.md.tmpl", "{{.Name", "failed to parse template"},
+		{"name with a separator", "{{.Path}}", "", "invalid name"},
+		{"name rendering empty", "{{.Empty}}", "", "invalid name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := graph.NewDirectoryNode("app")
+			addFile(t, root, tt.file, tt.content, graph.FILEACTION_TEMPLATE)
+
+			_, err := Render(root, map[string]any{"Name": "demo", "Path": "a/b", "Empty": ""})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Render error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderNameCollision(t *testing.T) {
+	root := graph.NewDirectoryNode("app")
+	addFile(t, root, "{{.Name}}.txt", "a", graph.FILEACTION_COPY)
+	addFile(t, root, "demo.txt", "b", graph.FILEACTION_COPY)
+
+	if _, err := Render(root, map[string]any{"Name": "demo"}); err == nil || !strings.Contains(err.Error(), "both render to demo.txt") {
+		t.Errorf("Render error = %v, want siblings rendering to the same name with different content rejected", err)
+	}
+
+	opts := RenderOptions{Merge: graph.MergeOptions{DefaultCollisionAction: graph.YieldOnCollision}}
+	out, err := RenderWithOptions(context.Background(), root, map[string]any{"Name": "demo"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Children()) != 1 {
+		t.Fatalf("rendered %d children, want the siblings merged", len(out.Children()))
+	}
+	if content, _ := fileText(t, childNamed(t, out, "demo.txt")); content != "b" {
+		t.Errorf("demo.txt = %q, want the later sibling to win with YieldOnCollision", content)
+	}
+}
+
+func TestRenderMergesDirectories(t *testing.T) {
+	root := graph.NewDirectoryNode("app")
+	module := addDir(t, root, "{{.Module}}")
+	addFile(t, module, "handler.go", "package {{.Module}}\n", graph.FILEACTION_TEMPLATE)
+	addFile(t, module, "shared.go", "package shared\n", graph.FILEACTION_COPY)
+	api := addDir(t, root, "api")
+	addFile(t, api, "routes.go", "package api\n", graph.FILEACTION_COPY)
+	addFile(t, api, "shared.go", "package shared\n", graph.FILEACTION_COPY)
+
+	out, err := Render(root, map[string]any{"Module": "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Children()) != 1 {
+		t.Fatalf("rendered %d children, want {{.Module}} and api merged", len(out.Children()))
+	}
+	merged := childNamed(t, out, "api")
+	for _, name := range []string{"handler.go", "shared.go", "routes.go"} {
+		childNamed(t, merged, name)
+	}
+	if content, _ := fileText(t, childNamed(t, merged, "handler.go")); content != "package api\n" {
+		t.Errorf("handler.go = %q, want it rendered", content)
+	}
+	if parent, err := merged.Parent(); err != nil || parent != out {
+		t.Errorf("merged directory parent = %v, %v; want the rendered root", parent, err)
+	}
+}
+
+func TestRenderMergesCaseInsensitive(t *testing.T) {
+	root := graph.NewDirectoryNode("app")
+	addFile(t, addDir(t, root, "{{.Name}}"), "a.txt", "a", graph.FILEACTION_COPY)
+	addFile(t, addDir(t, root, "Docs"), "b.txt", "b", graph.FILEACTION_COPY)
+
+	out, err := Render(root, map[string]any{"Name": "docs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Children()) != 2 {
+		t.Errorf("rendered %d children, want docs and Docs kept apart by default", len(out.Children()))
+	}
+
+	opts := RenderOptions{Merge: graph.MergeOptions{CaseInsensitive: true}}
+	out, err = RenderWithOptions(context.Background(), root, map[string]any{"Name": "docs"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Children()) != 1 {
+		t.Fatalf("rendered %d children, want docs and Docs merged", len(out.Children()))
+	}
+	merged := childNamed(t, out, "docs")
+	childNamed(t, merged, "a.txt")
+	childNamed(t, merged, "b.txt")
+
+	// Keys are matched as Union matches them, folding runes that have no
+	// lower case form of their own
+	out, err = RenderWithOptions(context.Background(), root, map[string]any{"Name": "doc\u017f"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Children()) != 1 {
+		t.Errorf("rendered %d children, want doc\u017f and Docs merged", len(out.Children()))
+	}
+}
+
+func TestRenderRecordsOrigin(t *testing.T) {
+	root := graph.NewDirectoryNode("app")
+	addFile(t, root, "main.go.tmpl", "package {{.Name}}\n", graph.FILEACTION_TEMPLATE)
+	addFile(t, root, "LICENSE", "MIT\n", graph.FILEACTION_COPY)
+	addFile(t, root, "{{.Name}}.txt", "static\n", graph.FILEACTION_COPY)
+	link := graph.NewSymlinkNode("link", "LICENSE")
+	_ = link.SetParent(root)
+	if err := root.AddChild(link); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := Render(root, map[string]any{"Name": "demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"main.go", ORIGIN_GENERATED},
+		{"LICENSE", ORIGIN_COPIED},
+		{"demo.txt", ORIGIN_COPIED},
+	}
+	for _, tt := range tests {
+		file := childNamed(t, out, tt.key).(*graph.FileNode)
+		if got := file.Metadata()[METADATA_ORIGIN]; got != tt.want {
+			t.Errorf("%s origin = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+
+	// The input graph is left untagged
+	for _, child := range root.Children() {
+		if file, ok := child.(*graph.FileNode); ok {
+			if _, tagged := file.Metadata()[METADATA_ORIGIN]; tagged {
+				t.Errorf("input file %s was tagged", file.Key())
+			}
+		}
+	}
+}
+
+func TestRenderSymlinks(t *testing.T) {
+	root, err := graph.NewBuilder("root").
+		Dir("{{.Name}}", func(b *graph.Builder) { b.File("main.go.tmpl").Content([]byte("package {{.Name}}\n")) }).
+		Symlink("current", "{{.Name}}").
+		Symlink("{{.Name}}-link", "static/target").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]any{"Name": "api"}
+
+	rendered, err := Render(root, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for p, target := range map[string]string{"current": "api", "api-link": "static/target"} {
+		node, err := graph.FindByPath(rendered, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		link, ok := node.(*graph.SymlinkNode)
+		if !ok {
+			t.Fatalf("%s is a %s, want a symlink", p, node.Type())
+		}
+		if link.Target() != target {
+			t.Errorf("%s points at %q, want %q", p, link.Target(), target)
+		}
+	}
+	if _, err := graph.FindByPath(rendered, "api/main.go"); err != nil {
+		t.Error(err)
+	}
+
+	required, err := RequiredVars(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(required) != 1 || required[0] != "Name" {
+		t.Errorf("RequiredVars = %v, want [Name]", required)
+	}
+}
+
+func TestRenderSymlinkMissingVariable(t *testing.T) {
+	root, err := graph.NewBuilder("root").Symlink("link", "{{.Missing}}").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Render(root, map[string]any{}); err == nil {
+		t.Fatal("rendering a symlink target with a missing variable succeeded")
+	}
+}
+
+// sleeper stalls template execution without producing output.
+type sleeper time.Duration
+
+func (s sleeper) Wait() string {
+	time.Sleep(time.Duration(s))
+	return ""
+}
+
+func TestRenderLimits(t *testing.T) {
+	root, err := graph.NewBuilder("root").
+		File("big.txt.tmpl").Content([]byte("{{range .Items}}0123456789{{end}}")).
+		File("slow.txt.tmpl").Content([]byte("{{.Slow.Wait}}")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]any{"Items": make([]int, 100), "Slow": sleeper(200 * time.Millisecond)}
+
+	tests := []struct {
+		name     string
+		opts     RenderOptions
+		wantErr  error
+		wantFile string
+	}{
+		{name: "defaults", opts: RenderOptions{}},
+		{name: "output limit", opts: RenderOptions{MaxOutputBytes: 500}, wantErr: ErrOutputTooLarge, wantFile: "big.txt.tmpl"},
+		{name: "timeout", opts: RenderOptions{Timeout: 10 * time.Millisecond}, wantErr: context.DeadlineExceeded, wantFile: "slow.txt.tmpl"},
+		{name: "no limits", opts: RenderOptions{MaxOutputBytes: -1, Timeout: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RenderWithOptions(context.Background(), root, vars, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.wantFile) {
+				t.Errorf("error %q does not name %s", err, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestRenderCancelled(t *testing.T) {
+	root, err := graph.NewBuilder("root").File("a.txt.tmpl").Content([]byte("{{.Name}}")).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := RenderWithOptions(ctx, root, map[string]any{"Name": "api"}, RenderOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,182 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix marks the environment variables read by ValuesFromEnv.
+const EnvPrefix = "SKA_VAR_"
+
+// LoadValuesFile reads template variables from a YAML or JSON document whose
+// top level is a mapping. Values keep the type they are written with, so
+// "count: 3" is an int and "debug: true" a bool, and nested mappings become
+// nested maps as Render expects. An empty document holds no variables.
+func LoadValuesFile(path string) (map[string]any, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open values file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var doc any
+	if err := yaml.NewDecoder(file).Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse values file %s: %w", path, err)
+	}
+	if doc == nil {
+		return map[string]any{}, nil
+	}
+	vars, ok := normalizeValue(doc).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("values file %s must hold a mapping", path)
+	}
+	return vars, nil
+}
+
+// normalizeValue converts mappings with non-string keys, which YAML allows,
+// into maps keyed by string throughout value.
+func normalizeValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeValue(item)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalizeValue(item)
+		}
+		return m
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeValue(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// ValuesFromEnv returns the template variables set by the entries of environ,
+// in the "KEY=value" form of os.Environ, whose key starts with prefix. The
+// rest of the key names the variable: words separated by "_" are joined in
+// CamelCase and "__" separates nested fields, so with EnvPrefix
+// SKA_VAR_PROJECT_NAME sets ProjectName and SKA_VAR_AUTHOR__NAME sets
+// Author.Name. Values are kept as strings.
+func ValuesFromEnv(prefix string, environ []string) map[string]any {
+	vars := make(map[string]any)
+	for _, entry := range environ {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(key, prefix) || key == prefix {
+			continue
+		}
+
+		var fields []string
+		for _, field := range strings.Split(strings.TrimPrefix(key, prefix), "__") {
+			fields = append(fields, camelCase(field))
+		}
+		// Entries nesting under a variable already set as a string are dropped
+		_ = SetValue(vars, strings.Join(fields, "."), value)
+	}
+	return vars
+}
+
+// camelCase joins the "_"-separated words of s with each word capitalized.
+func camelCase(s string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(s, "_") {
+		for i, r := range strings.ToLower(word) {
+			if i == 0 {
+				r = unicode.ToUpper(r)
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ParseValue parses a "Name=value" assignment, as given on the command line,
+// into a variable with a dotted name and its string value.
+func ParseValue(assignment string) (string, string, error) {
+	name, value, ok := strings.Cut(assignment, "=")
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("invalid variable %q: expected Name=value", assignment)
+	}
+	for _, field := range strings.Split(name, ".") {
+		if field == "" {
+			return "", "", fmt.Errorf("invalid variable name %q", name)
+		}
+	}
+	return name, value, nil
+}
+
+// MergeValues returns a new map holding the variables of every layer, with
+// later layers taking precedence. Nested maps are merged key by key, so a
+// layer setting Author.Name keeps Author.Email from an earlier one. The
+// layers themselves are not modified.
+func MergeValues(layers ...map[string]any) map[string]any {
+	vars := make(map[string]any)
+	for _, layer := range layers {
+		mergeInto(vars, layer)
+	}
+	return vars
+}
+
+func mergeInto(dst, src map[string]any) {
+	for key, value := range src {
+		nested, ok := value.(map[string]any)
+		if !ok {
+			dst[key] = value
+			continue
+		}
+		existing, ok := dst[key].(map[string]any)
+		if !ok {
+			existing = make(map[string]any, len(nested))
+			dst[key] = existing
+		}
+		mergeInto(existing, nested)
+	}
+}
+
+// LookupValue returns the value of the variable with the dotted name in vars.
+func LookupValue(vars map[string]any, name string) (any, bool) {
+	var value any = vars
+	for _, field := range strings.Split(name, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[field]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// SetValue stores value as the variable with the dotted name in vars,
+// creating the maps leading to it.
+func SetValue(vars map[string]any, name string, value any) error {
+	fields := strings.Split(name, ".")
+	m := vars
+	for i, field := range fields[:len(fields)-1] {
+		next, ok := m[field]
+		if !ok {
+			next = make(map[string]any)
+			m[field] = next
+		}
+		nested, ok := next.(map[string]any)
+		if !ok {
+			return fmt.Errorf("cannot set %s: %s is not a map", name, strings.Join(fields[:i+1], "."))
+		}
+		m = nested
+	}
+	m[fields[len(fields)-1]] = value
+	return nil
+}
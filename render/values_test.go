@@ -0,0 +1,118 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadValuesFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]any
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			data: "Name: demo\nCount: 3\nDebug: true\nAuthor:\n  Name: Ada\nTags: [a, b]\n",
+			want: map[string]any{"Name": "demo", "Count": 3, "Debug": true, "Author": map[string]any{"Name": "Ada"}, "Tags": []any{"a", "b"}},
+		},
+		{
+			name: "json",
+			data: `{"Name": "demo", "Author": {"Email": "a@example.com"}}`,
+			want: map[string]any{"Name": "demo", "Author": map[string]any{"Email": "a@example.com"}},
+		},
+		{
+			name: "non-string keys",
+			data: "Ports:\n  80: http\n  443: https\n",
+			want: map[string]any{"Ports": map[string]any{"80": "http", "443": "https"}},
+		},
+		{name: "empty", data: "", want: map[string]any{}},
+		{name: "not a mapping", data: "- a\n- b\n", wantErr: "must hold a mapping"},
+		{name: "malformed", data: "Name: [demo\n", wantErr: "failed to parse values file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "values.yaml")
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadValuesFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadValuesFile error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadValuesFile = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValuesFromEnv(t *testing.T) {
+	environ := []string{
+		"SKA_VAR_PROJECT_NAME=demo",
+		"SKA_VAR_AUTHOR__NAME=Ada",
+		"SKA_VAR_AUTHOR__EMAIL_ADDRESS=a@example.com",
+		"SKA_VAR_=ignored",
+		"HOME=/root",
+		"SKA_VAR_VERSION",
+	}
+	want := map[string]any{
+		"ProjectName": "demo",
+		"Author":      map[string]any{"Name": "Ada", "EmailAddress": "a@example.com"},
+	}
+	if got := ValuesFromEnv(EnvPrefix, environ); !reflect.DeepEqual(got, want) {
+		t.Errorf("ValuesFromEnv = %v, want %v", got, want)
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		assignment string
+		name       string
+		value      string
+		wantErr    bool
+	}{
+		{assignment: "Name=demo", name: "Name", value: "demo"},
+		{assignment: "Author.Name=Ada Lovelace", name: "Author.Name", value: "Ada Lovelace"},
+		{assignment: "Expr=a=b", name: "Expr", value: "a=b"},
+		{assignment: "Empty=", name: "Empty", value: ""},
+		{assignment: "Name", wantErr: true},
+		{assignment: "=demo", wantErr: true},
+		{assignment: "Author..Name=Ada", wantErr: true},
+	}
+	for _, tt := range tests {
+		name, value, err := ParseValue(tt.assignment)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseValue(%q) error = %v, want error %v", tt.assignment, err, tt.wantErr)
+			continue
+		}
+		if name != tt.name || value != tt.value {
+			t.Errorf("ParseValue(%q) = %q, %q; want %q, %q", tt.assignment, name, value, tt.name, tt.value)
+		}
+	}
+}
+
+func TestMergeValues(t *testing.T) {
+	file := map[string]any{"Name": "file", "License": "MIT", "Author": map[string]any{"Name": "file", "Email": "file@example.com"}}
+	env := map[string]any{"Name": "env", "Author": map[string]any{"Name": "env"}}
+	flags := map[string]any{"Author": map[string]any{"Name": "flag"}}
+
+	got := MergeValues(file, env, flags)
+	want := map[string]any{"Name": "env", "License": "MIT", "Author": map[string]any{"Name": "flag", "Email": "file@example.com"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeValues = %v, want %v", got, want)
+	}
+	if file["Author"].(map[string]any)["Name"] != "file" {
+		t.Error("MergeValues modified a layer")
+	}
+}
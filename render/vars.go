@@ -0,0 +1,159 @@
+package render
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// RequiredVars returns the variables referenced by the TEMPLATE files,
+// templated node names and templated symlink targets of the graph rooted at
+// root, sorted and without duplicates. Nested fields are reported by their
+// dotted path, so
+// "{{.Author.Name}}" and "{{with .Author}}{{.Name}}{{end}}" both require
+// "Author.Name". Fields inside a range refer to the elements being ranged
+// over rather than to vars, so only the ranged variable itself is reported.
+func RequiredVars(root graph.SkaffoldNode) ([]string, error) {
+	c := varCollector{vars: make(map[string]bool)}
+	err := graph.Walk(root, func(node graph.SkaffoldNode, depth int, segments []string) error {
+		keyPath := path.Join(append([]string{root.Key()}, segments...)...)
+		if strings.Contains(node.Key(), "{{") {
+			if err := c.parse(keyPath, node.Key()); err != nil {
+				return err
+			}
+		}
+
+		if linkNode, ok := node.(*graph.SymlinkNode); ok && strings.Contains(linkNode.Target(), "{{") {
+			return c.parse(keyPath, linkNode.Target())
+		}
+
+		fileNode, ok := node.(*graph.FileNode)
+		if !ok || fileNode.Action() != graph.FILEACTION_TEMPLATE {
+			return nil
+		}
+		content, err := fileNode.Content()
+		if err != nil {
+			return err
+		}
+		return c.parse(keyPath, string(content))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make([]string, 0, len(c.vars))
+	for v := range c.vars {
+		vars = append(vars, v)
+	}
+	sort.Strings(vars)
+	return vars, nil
+}
+
+// varCollector gathers the variables referenced by parsed templates. Each
+// walk carries the path of dot relative to vars, or nil once dot no longer
+// derives from vars by field access.
+type varCollector struct {
+	vars map[string]bool
+}
+
+// parse parses text as a template called name and collects the variables
+// referenced by it and any templates it defines.
+func (c varCollector) parse(name, text string) error {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			c.list(t.Tree.Root, []string{})
+		}
+	}
+	return nil
+}
+
+func (c varCollector) add(fields []string) {
+	if len(fields) > 0 {
+		c.vars[strings.Join(fields, ".")] = true
+	}
+}
+
+func (c varCollector) list(list *parse.ListNode, dot []string) {
+	if list == nil {
+		return
+	}
+	for _, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.ActionNode:
+			c.pipe(n.Pipe, dot)
+		case *parse.TemplateNode:
+			c.pipe(n.Pipe, dot)
+		case *parse.IfNode:
+			c.pipe(n.Pipe, dot)
+			c.list(n.List, dot)
+			c.list(n.ElseList, dot)
+		case *parse.WithNode:
+			c.pipe(n.Pipe, dot)
+			c.list(n.List, c.fieldOf(n.Pipe, dot))
+			c.list(n.ElseList, dot)
+		case *parse.RangeNode:
+			c.pipe(n.Pipe, dot)
+			c.list(n.List, nil)
+			c.list(n.ElseList, dot)
+		}
+	}
+}
+
+func (c varCollector) pipe(pipe *parse.PipeNode, dot []string) {
+	if pipe == nil {
+		return
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			c.arg(arg, dot)
+		}
+	}
+}
+
+func (c varCollector) arg(arg parse.Node, dot []string) {
+	switch n := arg.(type) {
+	case *parse.FieldNode:
+		if dot != nil {
+			c.add(append(dot[:len(dot):len(dot)], n.Ident...))
+		}
+	case *parse.VariableNode:
+		// $ always holds vars, while other variables hold arbitrary values
+		if n.Ident[0] == "$" {
+			c.add(n.Ident[1:])
+		}
+	case *parse.DotNode:
+		c.add(dot)
+	case *parse.ChainNode:
+		c.arg(n.Node, dot)
+	case *parse.PipeNode:
+		c.pipe(n, dot)
+	}
+}
+
+// fieldOf returns the path relative to vars of the value of pipe when it is
+// a plain field access, as in "with .Author", or nil otherwise.
+func (c varCollector) fieldOf(pipe *parse.PipeNode, dot []string) []string {
+	if dot == nil || len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return nil
+	}
+	switch n := pipe.Cmds[0].Args[0].(type) {
+	case *parse.FieldNode:
+		return append(dot[:len(dot):len(dot)], n.Ident...)
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" {
+			return n.Ident[1:]
+		}
+	case *parse.DotNode:
+		return dot
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+)
+
+func TestRequiredVars(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{name: "field", content: "{{.Name}}", want: []string{"Name"}},
+		{name: "nested field", content: "{{.Author.Name}}", want: []string{"Author.Name"}},
+		{name: "with", content: "{{with .Author}}{{.Name}} <{{.Email}}>{{end}}", want: []string{"Author", "Author.Email", "Author.Name"}},
+		{name: "range", content: "{{range .Items}}{{.Title}}{{end}}", want: []string{"Items"}},
+		{name: "range else", content: "{{range .Items}}{{.Title}}{{else}}{{.Empty}}{{end}}", want: []string{"Empty", "Items"}},
+		{name: "root variable", content: "{{range .Items}}{{$.Name}}{{end}}", want: []string{"Items", "Name"}},
+		{name: "local variable", content: "{{$x := .Name}}{{$x.Length}}", want: []string{"Name"}},
+		{name: "if and pipes", content: "{{if .Debug}}{{.Level | printf \"%d\"}}{{end}}", want: []string{"Debug", "Level"}},
+		{name: "defined template", content: `{{define "t"}}{{.Inner}}{{end}}{{template "t" .}}`, want: []string{"Inner"}},
+		{name: "no actions", content: "plain text", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := graph.NewDirectoryNode("app")
+			addFile(t, root, "file.tmpl", tt.content, graph.FILEACTION_TEMPLATE)
+			got, err := RequiredVars(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RequiredVars(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequiredVarsAcrossGraph(t *testing.T) {
+	root := graph.NewDirectoryNode("app")
+	addFile(t, root, "README.md.tmpl", "# {{.Name}}\n{{.Description}}\n", graph.FILEACTION_TEMPLATE)
+	// COPY files are written verbatim, so their actions are not variables
+	addFile(t, root, "static.txt", "{{.Ignored}}", graph.FILEACTION_COPY)
+	addFile(t, addDir(t, root, "{{.Package}}"), "{{.Name}}.go", "package main\n", graph.FILEACTION_COPY)
+
+	got, err := RequiredVars(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Description", "Name", "Package"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RequiredVars = %v, want %v", got, want)
+	}
+}
+
+func TestRequiredVarsParseError(t *testing.T) {
+	root := graph.NewDirectoryNode("app")
+	addFile(t, addDir(t, root, "src"), "bad.tmpl", "{{.Name", graph.FILEACTION_TEMPLATE)
+	_, err := RequiredVars(root)
+	if err == nil || !strings.Contains(err.Error(), "app/src/bad.tmpl") {
+		t.Errorf("RequiredVars error = %v, want it to name app/src/bad.tmpl", err)
+	}
+}
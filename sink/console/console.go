@@ -0,0 +1,141 @@
+// Package console prints scaffold graphs in human-readable and line-oriented
+// text formats.
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// PrintGraph prints a graph node and its descendants, indenting each by its depth
+func PrintGraph(node graph.SkaffoldNode, level int) {
+	_ = graph.Walk(node, func(n graph.SkaffoldNode, depth int, segments []string) error {
+		// Create indentation based on level
+		indent := strings.Repeat("  ", level+depth)
+
+		fmt.Printf("%s%s %s\n", indent, nodeLabel(n), n.Key())
+		return nil
+	})
+}
+
+// PrintGraphWithSizes prints the graph like PrintGraph, appending the size in bytes
+// of each file and the aggregate size of each directory as set by ComputeSizes.
+func PrintGraphWithSizes(node graph.SkaffoldNode, level int) {
+	_ = PrintGraphWithOptions(node, os.Stdout, level, PrintOptions{Sizes: true})
+}
+
+// PrintOptions selects the details PrintGraphWithOptions appends to each line.
+type PrintOptions struct {
+	// Sizes appends the size in bytes of each file and the aggregate size of
+	// each directory as set by ComputeSizes.
+	Sizes bool
+	// ContentTypes appends the detected content type of each file.
+	ContentTypes bool
+	// Hashes appends the content hash of each file in hex, prefixed by the
+	// name of its algorithm.
+	Hashes bool
+}
+
+// PrintGraphWithOptions writes the graph to w like PrintGraph, appending the
+// details selected by opts in parentheses. Files without a content type or
+// hash, as when their content was skipped, show "unknown" and "no hash".
+// With no details selected the output matches PrintGraph.
+func PrintGraphWithOptions(node graph.SkaffoldNode, w io.Writer, level int, opts PrintOptions) error {
+	return graph.Walk(node, func(n graph.SkaffoldNode, depth int, segments []string) error {
+		indent := strings.Repeat("  ", level+depth)
+
+		details := nodeDetails(n, opts)
+		suffix := ""
+		if len(details) > 0 {
+			suffix = " (" + strings.Join(details, ", ") + ")"
+		}
+
+		_, err := fmt.Fprintf(w, "%s%s %s%s\n", indent, nodeLabel(n), n.Key(), suffix)
+		return err
+	})
+}
+
+// nodeDetails returns the details of node selected by opts.
+func nodeDetails(node graph.SkaffoldNode, opts PrintOptions) []string {
+	var details []string
+	if opts.Sizes {
+		var size int64
+		if sized, ok := node.(interface{ Size() int64 }); ok {
+			size = sized.Size()
+		} else if sized, ok := node.(interface{ TotalSize() int64 }); ok {
+			size = sized.TotalSize()
+		}
+		details = append(details, fmt.Sprintf("%d bytes", size))
+	}
+
+	fileNode, ok := node.(*graph.FileNode)
+	if !ok {
+		return details
+	}
+	if opts.ContentTypes {
+		contentType := fileNode.ContentType()
+		if contentType == "" {
+			contentType = graph.CONTENTTYPE_UNKNOWN
+		}
+		details = append(details, contentType)
+	}
+	if opts.Hashes {
+		if hash := fileNode.DataHash(); hash != nil {
+			details = append(details, fmt.Sprintf("%s:%x", fileNode.HashAlgorithm(), hash))
+		} else {
+			details = append(details, "no hash")
+		}
+	}
+	return details
+}
+
+// nodeLabel returns the bracketed type label printed before a node's key.
+func nodeLabel(node graph.SkaffoldNode) string {
+	nodeType := ""
+	if node.Type() == graph.NODETYPE_DIRECTORY {
+		nodeType = "[DIR]"
+	} else if node.Type() == graph.NODETYPE_FILE {
+		// Try to cast to FileNode to get action
+		if fileNode, ok := node.(interface{ Action() string }); ok {
+			nodeType = fmt.Sprintf("[FILE:%s]", fileNode.Action())
+		} else {
+			nodeType = "[FILE]"
+		}
+	} else if node.Type() == graph.NODETYPE_SYMLINK {
+		if linkNode, ok := node.(interface{ Target() string }); ok {
+			nodeType = fmt.Sprintf("[LINK -> %s]", linkNode.Target())
+		} else {
+			nodeType = "[LINK]"
+		}
+	}
+	return nodeType
+}
+
+// PrintPaths writes one entry per node below the root in the form "<marker> <path>",
+// where marker is "d" for directories, "l" for symlinks and "f" for files and path is slash-separated
+// relative to the root. Each entry is terminated by sep, typically '\n', or 0 so
+// paths containing spaces or newlines can be parsed safely.
+func PrintPaths(node graph.SkaffoldNode, w io.Writer, sep byte) error {
+	return graph.Walk(node, func(n graph.SkaffoldNode, depth int, segments []string) error {
+		// The root itself has no path relative to the root
+		if depth == 0 {
+			return nil
+		}
+
+		marker := "f"
+		switch n.Type() {
+		case graph.NODETYPE_DIRECTORY:
+			marker = "d"
+		case graph.NODETYPE_SYMLINK:
+			marker = "l"
+		}
+
+		_, err := fmt.Fprintf(w, "%s %s%c", marker, path.Join(segments...), sep)
+		return err
+	})
+}
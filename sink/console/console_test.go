@@ -0,0 +1,79 @@
+package console
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+)
+
+func TestPrintGraphWithOptions(t *testing.T) {
+	root, err := graph.NewBuilder("app").
+		Dir("src", func(b *graph.Builder) {
+			b.File("main.go").Content([]byte("package main\n"))
+		}).
+		File("skipped.bin").
+		Symlink("current", "src").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph.ComputeSizes(root)
+	mainHash := fmt.Sprintf("SHA256:%x", sha256.Sum256([]byte("package main\n")))
+
+	tests := []struct {
+		name string
+		opts PrintOptions
+		want string
+	}{
+		{
+			name: "no details",
+			want: "[DIR] app\n" +
+				"  [DIR] src\n" +
+				"    [FILE:COPY] main.go\n" +
+				"  [FILE:COPY] skipped.bin\n" +
+				"  [LINK -> src] current\n",
+		},
+		{
+			name: "sizes",
+			opts: PrintOptions{Sizes: true},
+			want: "[DIR] app (13 bytes)\n" +
+				"  [DIR] src (13 bytes)\n" +
+				"    [FILE:COPY] main.go (13 bytes)\n" +
+				"  [FILE:COPY] skipped.bin (0 bytes)\n" +
+				"  [LINK -> src] current (0 bytes)\n",
+		},
+		{
+			name: "all details",
+			opts: PrintOptions{Sizes: true, ContentTypes: true, Hashes: true},
+			want: "[DIR] app (13 bytes)\n" +
+				"  [DIR] src (13 bytes)\n" +
+				"    [FILE:COPY] main.go (13 bytes, text/x-go; charset=utf-8, " + mainHash + ")\n" +
+				"  [FILE:COPY] skipped.bin (0 bytes, unknown, no hash)\n" +
+				"  [LINK -> src] current (0 bytes)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := PrintGraphWithOptions(root, &buf, 0, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("PrintGraphWithOptions wrote\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintGraphWithOptionsIndent(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PrintGraphWithOptions(graph.NewDirectoryNode("app"), &buf, 2, PrintOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "    [DIR] app\n"; buf.String() != want {
+		t.Errorf("PrintGraphWithOptions wrote %q, want %q", buf.String(), want)
+	}
+}
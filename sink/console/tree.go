@@ -0,0 +1,103 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// ANSI escape sequences used to colorize tree output.
+const (
+	colorReset     = "\x1b[0m"
+	colorDirectory = "\x1b[1;34m"
+	colorCopy      = "\x1b[32m"
+	colorTemplate  = "\x1b[33m"
+	colorSymlink   = "\x1b[36m"
+)
+
+// TreeOptions controls how PrintGraphTreeWithOptions renders a graph.
+type TreeOptions struct {
+	// Color colorizes directories, COPY files, TEMPLATE files and symlinks
+	// using ANSI escape codes.
+	Color bool
+}
+
+// PrintGraphTree writes the graph to w in the style of the tree command, using
+// box-drawing connectors. Directory names end in "/" and symlinks show their
+// target. Output is colorized when w is a terminal, unless the NO_COLOR
+// environment variable is set.
+func PrintGraphTree(node graph.SkaffoldNode, w io.Writer) error {
+	return PrintGraphTreeWithOptions(node, w, TreeOptions{Color: colorEnabled(w)})
+}
+
+// PrintGraphTreeWithOptions writes the graph to w like PrintGraphTree using
+// the provided options.
+func PrintGraphTreeWithOptions(node graph.SkaffoldNode, w io.Writer, opts TreeOptions) error {
+	if _, err := fmt.Fprintln(w, treeLabel(node, opts)); err != nil {
+		return err
+	}
+	return printTreeChildren(node, w, "", opts)
+}
+
+// printTreeChildren writes the children of node, each line starting with prefix.
+func printTreeChildren(node graph.SkaffoldNode, w io.Writer, prefix string, opts TreeOptions) error {
+	children := node.Children()
+	for i, child := range children {
+		connector, childPrefix := "├── ", prefix+"│   "
+		if i == len(children)-1 {
+			connector, childPrefix = "└── ", prefix+"    "
+		}
+
+		if _, err := fmt.Fprintf(w, "%s%s%s\n", prefix, connector, treeLabel(child, opts)); err != nil {
+			return err
+		}
+		if err := printTreeChildren(child, w, childPrefix, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// treeLabel returns the name of a node as shown in tree output.
+func treeLabel(node graph.SkaffoldNode, opts TreeOptions) string {
+	label, color := node.Key(), ""
+	switch node.Type() {
+	case graph.NODETYPE_DIRECTORY:
+		label, color = label+"/", colorDirectory
+	case graph.NODETYPE_SYMLINK:
+		color = colorSymlink
+		if linkNode, ok := node.(interface{ Target() string }); ok {
+			// Only the link name is colored, not its target
+			if opts.Color {
+				return color + label + colorReset + " -> " + linkNode.Target()
+			}
+			return label + " -> " + linkNode.Target()
+		}
+	case graph.NODETYPE_FILE:
+		color = colorCopy
+		if fileNode, ok := node.(interface{ Action() string }); ok && fileNode.Action() == graph.FILEACTION_TEMPLATE {
+			color = colorTemplate
+		}
+	}
+
+	if !opts.Color || color == "" {
+		return label
+	}
+	return color + label + colorReset
+}
+
+// colorEnabled reports whether output to w should be colorized: w must be a
+// terminal and NO_COLOR must be unset or empty.
+func colorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,73 @@
+package console
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// treeGraph returns a small graph with nested directories, a COPY file, a
+// TEMPLATE file and a symlink.
+func treeGraph(t *testing.T) graph.SkaffoldNode {
+	t.Helper()
+	root := graph.NewDirectoryNode("app")
+	src := graph.NewDirectoryNode("src")
+	pkg := graph.NewDirectoryNode("pkg")
+	file, err := graph.NewFileNodeFull("main.go", []byte("package main\n"), graph.FILEACTION_COPY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readme, err := graph.NewFileNodeFull("README.md.tmpl", []byte("# {{.Name}}\n"), graph.FILEACTION_TEMPLATE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, add := range []struct{ parent, child graph.SkaffoldNode }{
+		{root, src},
+		{src, pkg},
+		{src, file},
+		{root, readme},
+		{root, graph.NewSymlinkNode("current", "src")},
+	} {
+		_ = add.child.SetParent(add.parent)
+		if err := add.parent.AddChild(add.child); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestPrintGraphTree(t *testing.T) {
+	want := "app/\n" +
+		"├── src/\n" +
+		"│   ├── pkg/\n" +
+		"│   └── main.go\n" +
+		"├── README.md.tmpl\n" +
+		"└── current -> src\n"
+
+	var buf bytes.Buffer
+	// A bytes.Buffer is not a terminal, so the output is never colored
+	if err := PrintGraphTree(treeGraph(t), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("PrintGraphTree wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintGraphTreeColor(t *testing.T) {
+	want := colorDirectory + "app/" + colorReset + "\n" +
+		"├── " + colorDirectory + "src/" + colorReset + "\n" +
+		"│   ├── " + colorDirectory + "pkg/" + colorReset + "\n" +
+		"│   └── " + colorCopy + "main.go" + colorReset + "\n" +
+		"├── " + colorTemplate + "README.md.tmpl" + colorReset + "\n" +
+		"└── " + colorSymlink + "current" + colorReset + " -> src\n"
+
+	var buf bytes.Buffer
+	if err := PrintGraphTreeWithOptions(treeGraph(t), &buf, TreeOptions{Color: true}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("PrintGraphTreeWithOptions wrote\n%q\nwant\n%q", buf.String(), want)
+	}
+}
@@ -0,0 +1,81 @@
+// Package dot writes a scaffold graph in the Graphviz DOT language so that it
+// can be rendered as a diagram, for example with "dot -Tsvg". Directories,
+// files and symlinks are drawn as differently shaped nodes, and each edge is
+// labelled with the name under which the child is linked into its parent.
+package dot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// WriteGraph writes the graph rooted at root to w as a DOT digraph.
+func WriteGraph(root graph.SkaffoldNode, w io.Writer) error {
+	d := dotWriter{w: bufio.NewWriter(w)}
+	d.printf("digraph ska {\n")
+	d.printf("  node [fontname=\"monospace\"];\n")
+	if _, err := d.node(root); err != nil {
+		return err
+	}
+	d.printf("}\n")
+	if d.err == nil {
+		d.err = d.w.Flush()
+	}
+	if d.err != nil {
+		return fmt.Errorf("failed to write DOT graph: %w", d.err)
+	}
+	return nil
+}
+
+// dotWriter holds the state of a WriteGraph call. The first write error is
+// kept and later writes are skipped.
+type dotWriter struct {
+	w    *bufio.Writer
+	next int
+	err  error
+}
+
+func (d *dotWriter) printf(format string, args ...any) {
+	if d.err == nil {
+		_, d.err = fmt.Fprintf(d.w, format, args...)
+	}
+}
+
+// node declares n and its descendants, with edges to its children, and
+// returns the identifier of n.
+func (d *dotWriter) node(n graph.SkaffoldNode) (string, error) {
+	id := fmt.Sprintf("n%d", d.next)
+	d.next++
+
+	switch node := n.(type) {
+	case *graph.DirectoryNode:
+		d.printf("  %s [label=%s, shape=folder, style=filled, fillcolor=lightgoldenrod1];\n", id, quote(node.Key()+"/"))
+		for _, child := range node.Children() {
+			childID, err := d.node(child)
+			if err != nil {
+				return "", err
+			}
+			d.printf("  %s -> %s [label=%s];\n", id, childID, quote(child.Key()))
+		}
+	case *graph.FileNode:
+		d.printf("  %s [label=%s, shape=note];\n", id, quote(node.Key()+"\n"+node.Action()))
+	case *graph.SymlinkNode:
+		d.printf("  %s [label=%s, shape=cds];\n", id, quote(node.Key()+" -> "+node.Target()))
+	default:
+		return "", fmt.Errorf("cannot write node %s of type %s", n.Key(), n.Type())
+	}
+	return id, nil
+}
+
+// escaper escapes the characters that would end a DOT string or be read as
+// escapes within it.
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r", "", "\n", `\n`)
+
+// quote returns s as a double-quoted DOT string.
+func quote(s string) string {
+	return `"` + escaper.Replace(s) + `"`
+}
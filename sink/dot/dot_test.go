@@ -0,0 +1,73 @@
+package dot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+)
+
+func TestWriteGraph(t *testing.T) {
+	root, err := graph.NewBuilder("app").
+		Dir("src", func(b *graph.Builder) {
+			b.File("main.go")
+		}).
+		File(`say "hi".tmpl`).
+		Symlink("current", "src").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `digraph ska {
+  node [fontname="monospace"];
+  n0 [label="app/", shape=folder, style=filled, fillcolor=lightgoldenrod1];
+  n1 [label="src/", shape=folder, style=filled, fillcolor=lightgoldenrod1];
+  n2 [label="main.go\nCOPY", shape=note];
+  n1 -> n2 [label="main.go"];
+  n0 -> n1 [label="src"];
+  n3 [label="say \"hi\".tmpl\nTEMPLATE", shape=note];
+  n0 -> n3 [label="say \"hi\".tmpl"];
+  n4 [label="current -> src", shape=cds];
+  n0 -> n4 [label="current"];
+}
+`
+	var buf strings.Builder
+	if err := WriteGraph(root, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("WriteGraph wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "plain", want: `"plain"`},
+		{in: `back\slash`, want: `"back\\slash"`},
+		{in: "two\r\nlines", want: `"two\nlines"`},
+	}
+	for _, tt := range tests {
+		if got := quote(tt.in); got != tt.want {
+			t.Errorf("quote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestWriteGraphWriteError(t *testing.T) {
+	err := WriteGraph(graph.NewDirectoryNode("app"), failingWriter{})
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("WriteGraph error = %v, want the write error", err)
+	}
+}
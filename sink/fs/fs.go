@@ -0,0 +1,275 @@
+// Package fs materializes a scaffold graph as files and directories on disk.
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// WriteOptions controls how WriteGraphWithOptions writes a graph.
+type WriteOptions struct {
+	// Overwrite allows existing files at target paths to be replaced.
+	Overwrite bool
+	// SkipIfExists leaves existing files and symlinks untouched instead of
+	// replacing them or failing, taking precedence over Overwrite.
+	SkipIfExists bool
+	// SkipIfContentEqual leaves an existing file untouched when its content
+	// hashes to the node's, and an existing symlink when its target matches,
+	// so re-applying a graph only writes what changed. Files that differ are
+	// replaced or conflict according to Overwrite.
+	SkipIfContentEqual bool
+	// OnWarning, if set, receives non-fatal problems such as lacking the
+	// privilege to restore file ownership.
+	OnWarning func(err error)
+	// Progress, if set, is called after each file is written with its path
+	// and the number of bytes written, in the order files appear in the graph.
+	Progress func(path string, bytesWritten int64)
+	// Transform, if set, rewrites the content of each file before it is
+	// written, given the target path, for last-mile edits such as adding a
+	// license header. Returning an error stops the write before that file is
+	// touched. SkipIfContentEqual compares existing files with the
+	// transformed content.
+	Transform func(path string, content []byte) ([]byte, error)
+}
+
+// WriteGraph writes the graph under destRoot, which stands in for the graph
+// root, failing if any target file already exists.
+func WriteGraph(root graph.SkaffoldNode, destRoot string) error {
+	return WriteGraphWithOptions(context.Background(), root, destRoot, WriteOptions{})
+}
+
+// WriteGraphWithOptions writes the graph under destRoot using the provided
+// options. It plans the write with PlanGraphWithOptions and, if nothing
+// conflicts, carries the plan out with ApplyPlan.
+func WriteGraphWithOptions(ctx context.Context, root graph.SkaffoldNode, destRoot string, opts WriteOptions) error {
+	plan, err := PlanGraphWithOptions(root, destRoot, opts)
+	if err != nil {
+		return err
+	}
+	return ApplyPlan(ctx, plan, opts)
+}
+
+// ApplyPlan performs the operations of plan, failing without writing
+// anything if any of them conflict. Directories are created as needed, file
+// nodes are written with their stored content and symlinks are recreated with
+// their recorded target; TEMPLATE files are written verbatim. Node modes are
+// applied to the directories the plan creates and to every file. Captured
+// extended attributes and ownership are restored where present, while
+// directories that already exist are left untouched. Writing stops between
+// operations once ctx is done, returning the context's error and leaving
+// whatever was already written in place.
+func ApplyPlan(ctx context.Context, plan Plan, opts WriteOptions) error {
+	if conflicts := plan.Conflicts(); len(conflicts) > 0 {
+		return fmt.Errorf("refusing to write %s: %s", conflicts[0].Path, conflicts[0].Reason)
+	}
+
+	var dirs []dirMode
+	for _, op := range plan {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if op.Kind == OPERATION_SKIP {
+			continue
+		}
+
+		switch n := op.Node.(type) {
+		case *graph.DirectoryNode:
+			if err := writeDir(op.Path); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirMode{path: op.Path, mode: n.Mode()})
+			if err := restoreAttributes(op.Path, n, opts); err != nil {
+				return err
+			}
+		case *graph.FileNode:
+			if err := writeFile(op.Path, n, opts); err != nil {
+				return err
+			}
+			if err := restoreAttributes(op.Path, n, opts); err != nil {
+				return err
+			}
+		case *graph.SymlinkNode:
+			if err := writeSymlink(op.Path, n, opts); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("cannot write node %s of type %s", op.Node.Key(), op.Node.Type())
+		}
+	}
+
+	// Directory modes are applied last, deepest first, so a read-only
+	// directory does not prevent its children from being written
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].path, dirs[i].mode); err != nil {
+			return fmt.Errorf("failed to set mode of directory %s: %w", dirs[i].path, err)
+		}
+	}
+	return nil
+}
+
+// dirMode is a written directory awaiting its mode.
+type dirMode struct {
+	path string
+	mode os.FileMode
+}
+
+// targetPath joins the node path onto destRoot, rejecting any key that would
+// not resolve to a single entry inside destRoot.
+func targetPath(destRoot string, segments []string) (string, error) {
+	for _, segment := range segments {
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsAny(segment, `/\`) {
+			return "", fmt.Errorf("refusing to write node with unsafe key %q", segment)
+		}
+	}
+
+	target := filepath.Join(append([]string{destRoot}, segments...)...)
+	rel, err := filepath.Rel(destRoot, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing to write %s outside of %s", target, destRoot)
+	}
+	return target, nil
+}
+
+// writeDir creates the directory at target, accepting one that already exists.
+func writeDir(target string) error {
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", target, err)
+	}
+	return nil
+}
+
+// writeFile streams the content of fileNode to target, removing the partly
+// written file if the content cannot be read in full, and reports progress.
+// An existing symlink at target is replaced rather than followed, so
+// overwriting never writes through a link to a file outside destRoot.
+func writeFile(target string, fileNode *graph.FileNode, opts WriteOptions) error {
+	content, err := openContent(target, fileNode, opts)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if opts.Overwrite {
+		info, err := os.Lstat(target)
+		if err == nil && !info.Mode().IsRegular() {
+			if info.Mode()&os.ModeSymlink == 0 {
+				return fmt.Errorf("refusing to overwrite %s: it is not a regular file", target)
+			}
+			if err := os.Remove(target); err != nil {
+				return fmt.Errorf("failed to replace %s: %w", target, err)
+			}
+			// Fail rather than follow a link recreated in the meantime
+			flags |= os.O_EXCL
+		}
+	} else {
+		flags |= os.O_EXCL
+	}
+
+	file, err := os.OpenFile(target, flags, fileNode.Mode())
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("refusing to overwrite existing file %s", target)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", target, err)
+	}
+
+	written, err := io.Copy(file, content)
+	if err != nil {
+		file.Close()
+		os.Remove(target)
+		return fmt.Errorf("failed to write file %s: %w", target, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", target, err)
+	}
+	// The mode given to OpenFile is filtered by the umask and ignored for
+	// existing files
+	if err := os.Chmod(target, fileNode.Mode()); err != nil {
+		return fmt.Errorf("failed to set mode of file %s: %w", target, err)
+	}
+
+	if opts.Progress != nil {
+		opts.Progress(target, written)
+	}
+	return nil
+}
+
+// openContent returns a reader over the content of fileNode as it is written
+// to target, passed through opts.Transform when one is set.
+func openContent(target string, fileNode *graph.FileNode, opts WriteOptions) (io.ReadCloser, error) {
+	if opts.Transform == nil {
+		return fileNode.Open()
+	}
+	content, err := fileNode.Content()
+	if err != nil {
+		return nil, err
+	}
+	transformed, err := opts.Transform(target, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform %s: %w", target, err)
+	}
+	return graph.BytesProvider(transformed).Open()
+}
+
+// writeSymlink creates a symbolic link at target pointing at the node's target.
+// The target is not validated, so links may point outside of destRoot.
+func writeSymlink(target string, linkNode *graph.SymlinkNode, opts WriteOptions) error {
+	if opts.Overwrite {
+		// Only an existing link or file is replaced, never a directory
+		info, err := os.Lstat(target)
+		if err == nil && !info.IsDir() {
+			if err := os.Remove(target); err != nil {
+				return fmt.Errorf("failed to replace %s: %w", target, err)
+			}
+		}
+	}
+
+	err := os.Symlink(linkNode.Target(), target)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("refusing to overwrite existing file %s", target)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create symlink %s: %w", target, err)
+	}
+
+	return restoreOwner(target, linkNode, opts)
+}
+
+// attributed is implemented by nodes that carry captured filesystem attributes.
+type attributed interface {
+	Xattrs() map[string][]byte
+	Owner() (int, int, bool)
+}
+
+// restoreAttributes applies captured extended attributes and ownership to target.
+func restoreAttributes(target string, node attributed, opts WriteOptions) error {
+	if err := RestoreXattrs(target, node.Xattrs()); err != nil {
+		return err
+	}
+	return restoreOwner(target, node, opts)
+}
+
+// restoreOwner applies captured ownership to target, reporting a lack of
+// privilege as a warning rather than an error.
+func restoreOwner(target string, node interface{ Owner() (int, int, bool) }, opts WriteOptions) error {
+	uid, gid, ok := node.Owner()
+	if !ok {
+		return nil
+	}
+	err := RestoreOwner(target, uid, gid)
+	if errors.Is(err, ErrOwnershipNotPermitted) {
+		if opts.OnWarning != nil {
+			opts.OnWarning(err)
+		}
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,450 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+	srcfs "github.com/sthussey/ska/source/fs"
+)
+
+func TestWriteGraphModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not supported on Windows")
+	}
+
+	root := graph.NewDirectoryNode("root")
+	bin := graph.NewDirectoryNode("bin")
+	bin.SetMode(0o700)
+	script, err := graph.NewFileNodeFull("run.sh", []byte("#!/bin/sh\n"), graph.FILEACTION_COPY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script.SetMode(0o755)
+	readme, err := graph.NewFileNodeFull("README.md", []byte("# app\n"), graph.FILEACTION_COPY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, add := range []struct{ parent, child graph.SkaffoldNode }{{root, bin}, {bin, script}, {root, readme}} {
+		_ = add.child.SetParent(add.parent)
+		if err := add.parent.AddChild(add.child); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dest := t.TempDir()
+	if err := WriteGraph(root, dest); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want os.FileMode
+	}{
+		{"bin", 0o700},
+		{"bin/run.sh", 0o755},
+		{"README.md", graph.DefaultFileMode},
+	}
+	for _, tt := range tests {
+		info, err := os.Stat(filepath.Join(dest, filepath.FromSlash(tt.path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != tt.want {
+			t.Errorf("%s written with mode %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	// Building the written tree reads the same modes back
+	built, err := srcfs.BuildGraph(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := graph.FindByPath(built, "bin/run.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := node.(*graph.FileNode).Mode(); got != 0o755 {
+		t.Errorf("built bin/run.sh with mode %v, want 0755", got)
+	}
+}
+
+func TestWriteGraphProgress(t *testing.T) {
+	root, err := graph.NewBuilder("root").
+		File("b.txt").Content([]byte("bb")).
+		Dir("a", func(b *graph.Builder) {
+			b.File("z.txt").Content([]byte("zzz"))
+			b.File("y.txt")
+		}).
+		File("c.txt").Content([]byte("c")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type report struct {
+		path  string
+		bytes int64
+	}
+	var got []report
+	dest := t.TempDir()
+	opts := WriteOptions{Progress: func(path string, bytesWritten int64) {
+		got = append(got, report{path, bytesWritten})
+	}}
+	if err := WriteGraphWithOptions(context.Background(), root, dest, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []report{
+		{filepath.Join(dest, "b.txt"), 2},
+		{filepath.Join(dest, "a", "z.txt"), 3},
+		{filepath.Join(dest, "a", "y.txt"), 0},
+		{filepath.Join(dest, "c.txt"), 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("progress reports = %v, want %v", got, want)
+	}
+}
+
+// testGraph returns a graph holding a file, a nested file and a symlink.
+func testGraph(t *testing.T) graph.SkaffoldNode {
+	t.Helper()
+	root, err := graph.NewBuilder("root").
+		File("a.txt").Content([]byte("new a")).
+		Dir("dir", func(b *graph.Builder) { b.File("b.txt").Content([]byte("new b")) }).
+		Symlink("link", "a.txt").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func writeFileAt(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteGraph(t *testing.T) {
+	dest := t.TempDir()
+	if err := WriteGraph(testGraph(t), dest); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readFile(t, filepath.Join(dest, "a.txt")); got != "new a" {
+		t.Errorf("a.txt = %q, want %q", got, "new a")
+	}
+	if got := readFile(t, filepath.Join(dest, "dir", "b.txt")); got != "new b" {
+		t.Errorf("dir/b.txt = %q, want %q", got, "new b")
+	}
+	if target, err := os.Readlink(filepath.Join(dest, "link")); err != nil || target != "a.txt" {
+		t.Errorf("link = %q, %v; want a.txt", target, err)
+	}
+}
+
+func TestWriteGraphOverwrite(t *testing.T) {
+	tests := []struct {
+		name      string
+		overwrite bool
+		wantErr   bool
+		wantA     string
+	}{
+		{name: "refuses existing files", overwrite: false, wantErr: true, wantA: "old a"},
+		{name: "overwrites existing files", overwrite: true, wantA: "new a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			writeFileAt(t, filepath.Join(dest, "a.txt"), "old a")
+
+			err := WriteGraphWithOptions(context.Background(), testGraph(t), dest, WriteOptions{Overwrite: tt.overwrite})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := readFile(t, filepath.Join(dest, "a.txt")); got != tt.wantA {
+				t.Errorf("a.txt = %q, want %q", got, tt.wantA)
+			}
+			// A refused write plans the conflict first and writes nothing
+			if tt.wantErr {
+				if _, err := os.Lstat(filepath.Join(dest, "dir")); !os.IsNotExist(err) {
+					t.Errorf("dir was written despite the conflict: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteGraphOverwriteReplacesSymlink(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "outside.txt")
+	writeFileAt(t, outside, "outside")
+
+	dest := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dest, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := PlanGraphWithOptions(testGraph(t), dest, WriteOptions{Overwrite: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan[1].Kind != OPERATION_OVERWRITE {
+		t.Errorf("a.txt planned as %s, want %s", plan[1].Kind, OPERATION_OVERWRITE)
+	}
+	if err := ApplyPlan(context.Background(), plan, WriteOptions{Overwrite: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readFile(t, outside); got != "outside" {
+		t.Errorf("file outside the destination was written: %q", got)
+	}
+	info, err := os.Lstat(filepath.Join(dest, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Mode().IsRegular() {
+		t.Errorf("a.txt has mode %v, want a regular file", info.Mode())
+	}
+	if got := readFile(t, filepath.Join(dest, "a.txt")); got != "new a" {
+		t.Errorf("a.txt = %q, want %q", got, "new a")
+	}
+}
+
+func TestWriteGraphTransform(t *testing.T) {
+	dest := t.TempDir()
+	var paths []string
+	opts := WriteOptions{
+		Transform: func(path string, content []byte) ([]byte, error) {
+			paths = append(paths, path)
+			return append([]byte("// header\n"), content...), nil
+		},
+	}
+	if err := WriteGraphWithOptions(context.Background(), testGraph(t), dest, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readFile(t, filepath.Join(dest, "a.txt")); got != "// header\nnew a" {
+		t.Errorf("a.txt = %q, want the transformed content", got)
+	}
+	want := []string{filepath.Join(dest, "a.txt"), filepath.Join(dest, "dir", "b.txt")}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("Transform called with %v, want %v", paths, want)
+	}
+
+	// The transformed content is what SkipIfContentEqual compares against
+	plan, err := PlanGraphWithOptions(testGraph(t), dest, WriteOptions{SkipIfContentEqual: true, Transform: opts.Transform})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range plan {
+		if op.Kind != OPERATION_SKIP {
+			t.Errorf("%s planned as %s, want %s", op.Path, op.Kind, OPERATION_SKIP)
+		}
+	}
+}
+
+func TestWriteGraphTransformError(t *testing.T) {
+	dest := t.TempDir()
+	writeFileAt(t, filepath.Join(dest, "a.txt"), "old a")
+
+	errRejected := errors.New("rejected")
+	opts := WriteOptions{
+		Overwrite: true,
+		Transform: func(path string, content []byte) ([]byte, error) {
+			return nil, errRejected
+		},
+	}
+	err := WriteGraphWithOptions(context.Background(), testGraph(t), dest, opts)
+	if !errors.Is(err, errRejected) {
+		t.Fatalf("err = %v, want %v", err, errRejected)
+	}
+	if got := readFile(t, filepath.Join(dest, "a.txt")); got != "old a" {
+		t.Errorf("a.txt = %q, want it left untouched", got)
+	}
+}
+
+func TestWriteGraphRefusesEscape(t *testing.T) {
+	for _, key := range []string{"..", ".", "a/b", `a\b`} {
+		t.Run(key, func(t *testing.T) {
+			root := graph.NewDirectoryNode("root")
+			file := graph.NewFileNode(key)
+			file.SetContent([]byte("escape"))
+			if err := root.AddChild(file); err != nil {
+				t.Fatal(err)
+			}
+
+			parent := t.TempDir()
+			dest := filepath.Join(parent, "dest")
+			err := WriteGraph(root, dest)
+			if err == nil || !strings.Contains(err.Error(), "unsafe key") {
+				t.Fatalf("err = %v, want an unsafe key error", err)
+			}
+			entries, err := os.ReadDir(parent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("entries were written: %v", entries)
+			}
+		})
+	}
+}
+
+func TestWriteGraphRefusesDirectoryThroughSymlink(t *testing.T) {
+	outside := t.TempDir()
+	dest := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dest, "dir")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteGraphWithOptions(context.Background(), testGraph(t), dest, WriteOptions{Overwrite: true}); err == nil {
+		t.Fatal("writing a directory through a symlink succeeded")
+	}
+	if _, err := os.Lstat(filepath.Join(outside, "b.txt")); !os.IsNotExist(err) {
+		t.Errorf("b.txt was written outside the destination: %v", err)
+	}
+}
+
+func TestPlanGraph(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, dest string)
+		opts  WriteOptions
+		want  map[string]string // Operation kind by path relative to dest
+	}{
+		{
+			name: "empty destination",
+			want: map[string]string{".": OPERATION_SKIP, "a.txt": OPERATION_CREATE, "dir": OPERATION_CREATE, "dir/b.txt": OPERATION_CREATE, "link": OPERATION_CREATE},
+		},
+		{
+			name:  "existing file conflicts",
+			setup: func(t *testing.T, dest string) { writeFileAt(t, filepath.Join(dest, "a.txt"), "old a") },
+			want:  map[string]string{"a.txt": OPERATION_CONFLICT},
+		},
+		{
+			name:  "existing file is overwritten",
+			setup: func(t *testing.T, dest string) { writeFileAt(t, filepath.Join(dest, "a.txt"), "old a") },
+			opts:  WriteOptions{Overwrite: true},
+			want:  map[string]string{"a.txt": OPERATION_OVERWRITE},
+		},
+		{
+			name:  "existing file is skipped",
+			setup: func(t *testing.T, dest string) { writeFileAt(t, filepath.Join(dest, "a.txt"), "old a") },
+			opts:  WriteOptions{SkipIfExists: true, Overwrite: true},
+			want:  map[string]string{"a.txt": OPERATION_SKIP},
+		},
+		{
+			name: "unchanged file is skipped and changed file conflicts",
+			setup: func(t *testing.T, dest string) {
+				writeFileAt(t, filepath.Join(dest, "a.txt"), "new a")
+				writeFileAt(t, filepath.Join(dest, "dir", "b.txt"), "old b")
+			},
+			opts: WriteOptions{SkipIfContentEqual: true},
+			want: map[string]string{"a.txt": OPERATION_SKIP, "dir": OPERATION_SKIP, "dir/b.txt": OPERATION_CONFLICT},
+		},
+		{
+			name:  "file where a directory is needed conflicts",
+			setup: func(t *testing.T, dest string) { writeFileAt(t, filepath.Join(dest, "dir"), "file") },
+			opts:  WriteOptions{Overwrite: true},
+			want:  map[string]string{"dir": OPERATION_CONFLICT},
+		},
+		{
+			name: "directory where a file is needed conflicts",
+			setup: func(t *testing.T, dest string) {
+				if err := os.Mkdir(filepath.Join(dest, "a.txt"), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			},
+			opts: WriteOptions{Overwrite: true},
+			want: map[string]string{"a.txt": OPERATION_CONFLICT},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			if tt.setup != nil {
+				tt.setup(t, dest)
+			}
+
+			plan, err := PlanGraphWithOptions(testGraph(t), dest, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for _, op := range plan {
+				rel, err := filepath.Rel(dest, op.Path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[filepath.ToSlash(rel)] = op.Kind
+			}
+			for p, kind := range tt.want {
+				if got[p] != kind {
+					t.Errorf("%s planned as %q, want %q", p, got[p], kind)
+				}
+			}
+			// The children of a conflicting directory are left out
+			if got["dir"] == OPERATION_CONFLICT {
+				if _, ok := got["dir/b.txt"]; ok {
+					t.Error("dir/b.txt is planned beneath a conflicting directory")
+				}
+			}
+		})
+	}
+}
+
+func TestWriteGraphCancelled(t *testing.T) {
+	dest := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := WriteGraphWithOptions(ctx, testGraph(t), dest, WriteOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dest, "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("a.txt was written after cancellation: %v", err)
+	}
+}
+
+func TestApplyPlanCancelledMidway(t *testing.T) {
+	dest := t.TempDir()
+	plan, err := PlanGraph(testGraph(t), dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cancel once the first file is written, leaving the rest unwritten
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := WriteOptions{Progress: func(string, int64) { cancel() }}
+	err = ApplyPlan(ctx, plan, opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dest, "a.txt")); err != nil {
+		t.Errorf("a.txt written before cancellation is missing: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dest, "dir", "b.txt")); !os.IsNotExist(err) {
+		t.Errorf("dir/b.txt was written after cancellation: %v", err)
+	}
+}
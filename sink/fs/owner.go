@@ -0,0 +1,24 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrOwnershipNotPermitted is returned by RestoreOwner when the process lacks
+// the privilege to change ownership. Callers should treat it as a warning.
+var ErrOwnershipNotPermitted = errors.New("not permitted to change ownership")
+
+// RestoreOwner sets the numeric owner and group of the file at path without
+// following symlinks.
+func RestoreOwner(path string, uid, gid int) error {
+	err := os.Lchown(path, uid, gid)
+	if errors.Is(err, os.ErrPermission) {
+		return fmt.Errorf("%w of %s to %d:%d", ErrOwnershipNotPermitted, path, uid, gid)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to change ownership of %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package fs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sthussey/ska/graph"
+)
+
+const OPERATION_CREATE = "CREATE"       // Nothing exists at the path yet
+const OPERATION_OVERWRITE = "OVERWRITE" // An existing file or symlink is replaced
+const OPERATION_SKIP = "SKIP"           // The directory, file or symlink already exists and is left as is
+const OPERATION_CONFLICT = "CONFLICT"   // Something at the path prevents writing the node
+
+// Operation describes what writing a graph does for one node.
+type Operation struct {
+	Kind   string // One of the OPERATION_* constants
+	Path   string // Absolute path of the target
+	Node   graph.SkaffoldNode
+	Reason string // Why the operation conflicts or an existing file is skipped, empty otherwise
+}
+
+// Plan lists the operations writing a graph performs, in graph order.
+type Plan []Operation
+
+// Conflicts returns the operations that prevent the plan from being applied.
+func (p Plan) Conflicts() []Operation {
+	var conflicts []Operation
+	for _, op := range p {
+		if op.Kind == OPERATION_CONFLICT {
+			conflicts = append(conflicts, op)
+		}
+	}
+	return conflicts
+}
+
+// Summary counts the files and symlinks a plan writes and leaves untouched.
+// Directories are not counted.
+type Summary struct {
+	Written int // Created or overwritten
+	Skipped int // Already present and left as is
+}
+
+// Summary returns the counts of files and symlinks the plan writes and skips.
+func (p Plan) Summary() Summary {
+	var s Summary
+	for _, op := range p {
+		if op.Node.Type() == graph.NODETYPE_DIRECTORY {
+			continue
+		}
+		switch op.Kind {
+		case OPERATION_CREATE, OPERATION_OVERWRITE:
+			s.Written++
+		case OPERATION_SKIP:
+			s.Skipped++
+		}
+	}
+	return s
+}
+
+// PlanGraph returns the operations WriteGraph would perform to write the graph
+// under destRoot, without touching disk.
+func PlanGraph(root graph.SkaffoldNode, destRoot string) (Plan, error) {
+	return PlanGraphWithOptions(root, destRoot, WriteOptions{})
+}
+
+// PlanGraphWithOptions returns the operations WriteGraphWithOptions would
+// perform with the provided options. Existing files and symlinks are skipped
+// when opts.SkipIfExists is set, or when opts.SkipIfContentEqual is set and
+// they already match the node, and otherwise conflict unless opts.Overwrite
+// is set. Overwriting replaces an existing symlink itself and never writes
+// through it. A directory conflicts with anything but a directory in either
+// direction, including a symlink to a directory, and special files such as
+// FIFOs and devices always conflict. The descendants of a conflicting
+// directory are left out of the plan.
+func PlanGraphWithOptions(root graph.SkaffoldNode, destRoot string, opts WriteOptions) (Plan, error) {
+	absDestRoot, err := filepath.Abs(destRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %s: %w", destRoot, err)
+	}
+
+	var plan Plan
+	err = graph.Walk(root, func(node graph.SkaffoldNode, depth int, segments []string) error {
+		target, err := targetPath(absDestRoot, segments)
+		if err != nil {
+			return err
+		}
+
+		op, err := planNode(node, target, opts)
+		if err != nil {
+			return err
+		}
+		plan = append(plan, op)
+
+		if op.Kind == OPERATION_CONFLICT {
+			return graph.SkipChildren
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// planNode returns the operation for writing node at target.
+func planNode(node graph.SkaffoldNode, target string, opts WriteOptions) (Operation, error) {
+	op := Operation{Kind: OPERATION_CREATE, Path: target, Node: node}
+
+	info, err := os.Lstat(target)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return op, fmt.Errorf("failed to stat %s: %w", target, err)
+	case node.Type() == graph.NODETYPE_DIRECTORY && info.IsDir():
+		op.Kind = OPERATION_SKIP
+	case node.Type() == graph.NODETYPE_DIRECTORY:
+		op.Kind = OPERATION_CONFLICT
+		op.Reason = "a file exists where a directory is needed"
+	case info.IsDir():
+		op.Kind = OPERATION_CONFLICT
+		op.Reason = "a directory exists where a file is needed"
+	case !info.Mode().IsRegular() && info.Mode()&os.ModeSymlink == 0:
+		op.Kind = OPERATION_CONFLICT
+		op.Reason = "a special file exists where a file is needed"
+	case opts.SkipIfExists:
+		op.Kind = OPERATION_SKIP
+		op.Reason = "the file already exists"
+	case opts.SkipIfContentEqual:
+		equal, err := existingEqual(node, target, info, opts)
+		if err != nil {
+			return op, err
+		}
+		switch {
+		case equal:
+			op.Kind = OPERATION_SKIP
+			op.Reason = "the file is unchanged"
+		case opts.Overwrite:
+			op.Kind = OPERATION_OVERWRITE
+		default:
+			op.Kind = OPERATION_CONFLICT
+			op.Reason = "the file already exists with different content"
+		}
+	case opts.Overwrite:
+		op.Kind = OPERATION_OVERWRITE
+	default:
+		op.Kind = OPERATION_CONFLICT
+		op.Reason = "the file already exists"
+	}
+	return op, nil
+}
+
+// existingEqual reports whether the file or symlink at target, described by
+// info, already matches node: a regular file whose content hashes to the
+// node's, or a symlink with the node's target. File content is compared as
+// transformed by opts.Transform when one is set.
+func existingEqual(node graph.SkaffoldNode, target string, info os.FileInfo, opts WriteOptions) (bool, error) {
+	switch n := node.(type) {
+	case *graph.FileNode:
+		if !info.Mode().IsRegular() || n.ContentSkipped() {
+			return false, nil
+		}
+		algorithm := n.HashAlgorithm()
+		want := n.DataHash()
+		if want == nil || opts.Transform != nil {
+			// Nodes given content by a provider may carry no hash, and
+			// transformed content has none until it is rehashed
+			algorithm = graph.DefaultHashAlgorithm
+			rc, err := openContent(target, n, opts)
+			if err != nil {
+				return false, err
+			}
+			defer rc.Close()
+			if want, err = hashReader(algorithm, rc); err != nil {
+				return false, fmt.Errorf("failed to hash content of %s: %w", n.Key(), err)
+			}
+		}
+
+		file, err := os.Open(target)
+		if err != nil {
+			return false, fmt.Errorf("failed to open %s: %w", target, err)
+		}
+		defer file.Close()
+		got, err := hashReader(algorithm, file)
+		if err != nil {
+			return false, fmt.Errorf("failed to hash %s: %w", target, err)
+		}
+		return bytes.Equal(got, want), nil
+	case *graph.SymlinkNode:
+		if info.Mode()&os.ModeSymlink == 0 {
+			return false, nil
+		}
+		existing, err := os.Readlink(target)
+		if err != nil {
+			return false, fmt.Errorf("failed to read symlink %s: %w", target, err)
+		}
+		return existing == n.Target(), nil
+	default:
+		return false, nil
+	}
+}
+
+// hashReader returns the hash of everything read from r using the named algorithm.
+func hashReader(algorithm string, r io.Reader) ([]byte, error) {
+	hasher, err := graph.NewHasher(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(hasher, r); err != nil {
+		return nil, err
+	}
+	return hasher.Sum(nil), nil
+}
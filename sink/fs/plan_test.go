@@ -0,0 +1,125 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+)
+
+func TestPlanGraphSkipPolicies(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+	root, err := graph.NewBuilder("root").
+		File("same.txt").Content([]byte("same\n")).
+		File("changed.txt").Content([]byte("new\n")).
+		File("absent.txt").Content([]byte("absent\n")).
+		Symlink("link", "same.txt").
+		Symlink("moved", "same.txt").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest := t.TempDir()
+	for name, content := range map[string]string{"same.txt": "same\n", "changed.txt": "old\n"} {
+		if err := os.WriteFile(filepath.Join(dest, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, target := range map[string]string{"link": "same.txt", "moved": "changed.txt"} {
+		if err := os.Symlink(target, filepath.Join(dest, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		opts    WriteOptions
+		want    map[string]string
+		summary Summary
+	}{
+		{
+			name: "skip if exists",
+			opts: WriteOptions{SkipIfExists: true, Overwrite: true},
+			want: map[string]string{
+				"same.txt": OPERATION_SKIP, "changed.txt": OPERATION_SKIP, "absent.txt": OPERATION_CREATE,
+				"link": OPERATION_SKIP, "moved": OPERATION_SKIP,
+			},
+			summary: Summary{Written: 1, Skipped: 4},
+		},
+		{
+			name: "skip if content equal",
+			opts: WriteOptions{SkipIfContentEqual: true},
+			want: map[string]string{
+				"same.txt": OPERATION_SKIP, "changed.txt": OPERATION_CONFLICT, "absent.txt": OPERATION_CREATE,
+				"link": OPERATION_SKIP, "moved": OPERATION_CONFLICT,
+			},
+			summary: Summary{Written: 1, Skipped: 2},
+		},
+		{
+			name: "skip if content equal with overwrite",
+			opts: WriteOptions{SkipIfContentEqual: true, Overwrite: true},
+			want: map[string]string{
+				"same.txt": OPERATION_SKIP, "changed.txt": OPERATION_OVERWRITE, "absent.txt": OPERATION_CREATE,
+				"link": OPERATION_SKIP, "moved": OPERATION_OVERWRITE,
+			},
+			summary: Summary{Written: 3, Skipped: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := PlanGraphWithOptions(root, dest, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, op := range plan[1:] {
+				name := filepath.Base(op.Path)
+				if op.Kind != tt.want[name] {
+					t.Errorf("%s planned as %s (%s), want %s", name, op.Kind, op.Reason, tt.want[name])
+				}
+			}
+			if got := plan.Summary(); got != tt.summary {
+				t.Errorf("Summary = %+v, want %+v", got, tt.summary)
+			}
+		})
+	}
+}
+
+func TestWriteGraphSkipUnchanged(t *testing.T) {
+	root, err := graph.NewBuilder("root").
+		File("same.txt").Content([]byte("same\n")).
+		File("changed.txt").Content([]byte("new\n")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := t.TempDir()
+	if err := WriteGraph(root, dest); err != nil {
+		t.Fatal(err)
+	}
+	changed := filepath.Join(dest, "changed.txt")
+	if err := os.WriteFile(changed, []byte("edited\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var written []string
+	opts := WriteOptions{
+		SkipIfContentEqual: true,
+		Overwrite:          true,
+		Progress:           func(path string, bytesWritten int64) { written = append(written, filepath.Base(path)) },
+	}
+	if err := WriteGraphWithOptions(context.Background(), root, dest, opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 1 || written[0] != "changed.txt" {
+		t.Errorf("rewrote %v, want only changed.txt", written)
+	}
+	if data, err := os.ReadFile(changed); err != nil || string(data) != "new\n" {
+		t.Errorf("changed.txt holds %q, %v; want the node's content", data, err)
+	}
+}
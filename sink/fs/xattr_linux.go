@@ -0,0 +1,22 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// RestoreXattrs sets the given extended attributes on the file at path.
+// It is a no-op when the underlying filesystem does not support xattrs.
+func RestoreXattrs(path string, attrs map[string][]byte) error {
+	for name, value := range attrs {
+		err := syscall.Setxattr(path, name, value, 0)
+		if errors.Is(err, syscall.ENOTSUP) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to set xattr %s on %s: %w", name, path, err)
+		}
+	}
+	return nil
+}
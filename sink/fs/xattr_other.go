@@ -0,0 +1,8 @@
+//go:build !linux
+
+package fs
+
+// RestoreXattrs is a no-op on platforms without xattr support.
+func RestoreXattrs(path string, attrs map[string][]byte) error {
+	return nil
+}
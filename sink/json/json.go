@@ -0,0 +1,180 @@
+// Package json serializes a scaffold graph as structured JSON and reads it
+// back. Files carry their action, content type, size and hex-encoded content
+// hash with the algorithm that produced it, but not their content.
+package json
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// node is the JSON representation of a graph node. Directories nest their
+// children, files describe their content and symlinks give their target.
+type node struct {
+	Key         string  `json:"key"`
+	Type        string  `json:"type"`
+	Action      string  `json:"action,omitempty"`
+	ContentType string  `json:"content_type,omitempty"`
+	DataHash    string  `json:"datahash,omitempty"`
+	Algorithm   string  `json:"hash_algorithm,omitempty"`
+	Size        int64   `json:"size,omitempty"`
+	Target      string  `json:"target,omitempty"`
+	Children    []*node `json:"children,omitempty"`
+}
+
+// WriteGraph writes the graph rooted at root to w as an indented JSON document.
+func WriteGraph(root graph.SkaffoldNode, w io.Writer) error {
+	doc, err := toJSON(root)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode graph as JSON: %w", err)
+	}
+	return nil
+}
+
+// toJSON converts a graph node and its descendants to their JSON representation.
+func toJSON(n graph.SkaffoldNode) (*node, error) {
+	out := &node{Key: n.Key(), Type: n.Type()}
+
+	switch n.Type() {
+	case graph.NODETYPE_DIRECTORY:
+		// Always emit a children array so empty directories round-trip unambiguously
+		out.Children = []*node{}
+		for _, child := range n.Children() {
+			c, err := toJSON(child)
+			if err != nil {
+				return nil, err
+			}
+			out.Children = append(out.Children, c)
+		}
+	case graph.NODETYPE_FILE:
+		fileNode, ok := n.(*graph.FileNode)
+		if !ok {
+			return nil, fmt.Errorf("file node %s has unsupported implementation %T", n.Key(), n)
+		}
+		out.Action = fileNode.Action()
+		out.ContentType = fileNode.ContentType()
+		out.DataHash = hex.EncodeToString(fileNode.DataHash())
+		out.Algorithm = fileNode.HashAlgorithm()
+		out.Size = fileNode.Size()
+	case graph.NODETYPE_SYMLINK:
+		linkNode, ok := n.(*graph.SymlinkNode)
+		if !ok {
+			return nil, fmt.Errorf("symlink node %s has unsupported implementation %T", n.Key(), n)
+		}
+		out.Target = linkNode.Target()
+	default:
+		return nil, fmt.Errorf("node %s has unsupported type %s", n.Key(), n.Type())
+	}
+	return out, nil
+}
+
+// ReadGraph parses a JSON document written by WriteGraph and rebuilds the
+// graph it describes. File nodes carry the recorded hash, size and content
+// type but no content.
+func ReadGraph(r io.Reader) (graph.SkaffoldNode, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var doc node
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON graph: %w", err)
+	}
+
+	if doc.Key == "" {
+		return nil, fmt.Errorf("graph root must have a key")
+	}
+	if doc.Type != graph.NODETYPE_DIRECTORY {
+		return nil, fmt.Errorf("graph root %s must be a directory", doc.Key)
+	}
+
+	return buildNode(&doc, doc.Key)
+}
+
+// buildNode converts a JSON node to a graph node, where keyPath locates it in
+// the document for error messages.
+func buildNode(n *node, keyPath string) (graph.SkaffoldNode, error) {
+	switch n.Type {
+	case graph.NODETYPE_DIRECTORY:
+		if n.Action != "" || n.ContentType != "" || n.DataHash != "" || n.Algorithm != "" || n.Size != 0 || n.Target != "" {
+			return nil, fmt.Errorf("directory %s cannot have file or symlink attributes", keyPath)
+		}
+
+		dirNode := graph.NewDirectoryNode(n.Key)
+		seen := make(map[string]bool, len(n.Children))
+		for _, c := range n.Children {
+			childPath := path.Join(keyPath, c.Key)
+			if err := validateKey(c.Key, childPath); err != nil {
+				return nil, err
+			}
+			if seen[c.Key] {
+				return nil, fmt.Errorf("duplicate entry %s", childPath)
+			}
+			seen[c.Key] = true
+
+			child, err := buildNode(c, childPath)
+			if err != nil {
+				return nil, err
+			}
+			if err := dirNode.AddChild(child); err != nil {
+				return nil, err
+			}
+		}
+		return dirNode, nil
+	case graph.NODETYPE_FILE:
+		if n.Children != nil || n.Target != "" {
+			return nil, fmt.Errorf("file %s cannot have children or a target", keyPath)
+		}
+
+		fileNode := graph.NewFileNode(n.Key)
+		if n.Action != "" {
+			if err := fileNode.SetAction(n.Action); err != nil {
+				return nil, fmt.Errorf("invalid action for %s: %w", keyPath, err)
+			}
+		}
+		hash, err := hex.DecodeString(n.DataHash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid datahash for %s: %w", keyPath, err)
+		}
+		algorithm := n.Algorithm
+		if len(hash) == 0 {
+			hash = nil
+		} else if algorithm == "" {
+			return nil, fmt.Errorf("file %s has a datahash but no hash_algorithm", keyPath)
+		}
+		fileNode.SetContentInfo(hash, algorithm, n.Size, n.ContentType)
+		return fileNode, nil
+	case graph.NODETYPE_SYMLINK:
+		if n.Children != nil || n.Action != "" || n.ContentType != "" || n.DataHash != "" || n.Algorithm != "" || n.Size != 0 {
+			return nil, fmt.Errorf("symlink %s cannot have children or file attributes", keyPath)
+		}
+		if n.Target == "" {
+			return nil, fmt.Errorf("symlink %s has no target", keyPath)
+		}
+		return graph.NewSymlinkNode(n.Key, n.Target), nil
+	default:
+		return nil, fmt.Errorf("node %s has unknown type %s", keyPath, n.Type)
+	}
+}
+
+// validateKey rejects keys that are empty or would not form a single path segment.
+func validateKey(key, keyPath string) error {
+	if key == "" {
+		return fmt.Errorf("entry under %s has no key", path.Dir(keyPath))
+	}
+	if key == "." || key == ".." || strings.Contains(key, "/") {
+		return fmt.Errorf("invalid key %q at %s", key, keyPath)
+	}
+	return nil
+}
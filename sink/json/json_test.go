@@ -0,0 +1,79 @@
+package json
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+	"github.com/sthussey/ska/source/fs"
+)
+
+func TestRoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "project")
+	files := map[string]string{
+		"cmd/main.go.tmpl": "package {{.Package}}\n",
+		"README.md":        "# Project\n",
+		"empty.txt":        "",
+	}
+	for p, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("cmd", filepath.Join(dir, "current")); err != nil {
+		t.Fatal(err)
+	}
+
+	built, err := fs.BuildGraphWithOptions(context.Background(), dir, fs.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var first bytes.Buffer
+	if err := WriteGraph(built, &first); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadGraph(bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if equal, diff := graph.EqualDetailed(built, read); !equal {
+		t.Errorf("graph differs after a round trip at %s:\n%s", diff, first.String())
+	}
+
+	for p := range files {
+		want, err := graph.FindByPath(built, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := graph.FindByPath(read, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, g := want.(*graph.FileNode), got.(*graph.FileNode)
+		if g.Action() != w.Action() || g.ContentType() != w.ContentType() || g.Size() != w.Size() || !bytes.Equal(g.DataHash(), w.DataHash()) {
+			t.Errorf("%s read back as %s %s %d %x, want %s %s %d %x", p,
+				g.Action(), g.ContentType(), g.Size(), g.DataHash(),
+				w.Action(), w.ContentType(), w.Size(), w.DataHash())
+		}
+	}
+
+	// Writing the graph read back reproduces the document
+	var second bytes.Buffer
+	if err := WriteGraph(read, &second); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Errorf("document changed after a round trip:\n%s\nwant:\n%s", second.String(), first.String())
+	}
+}
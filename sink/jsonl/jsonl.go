@@ -0,0 +1,59 @@
+// Package jsonl streams a scaffold graph as JSON Lines, one object per node,
+// for piping into tools such as jq without holding a whole document in
+// memory. Files carry their action, content type, size and hex-encoded
+// content hash with the algorithm that produced it, but not their content.
+package jsonl
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// line is the JSON representation of one node.
+type line struct {
+	Path        string `json:"path"`
+	Type        string `json:"type"`
+	Action      string `json:"action,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	DataHash    string `json:"datahash,omitempty"`
+	Algorithm   string `json:"hash_algorithm,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	Target      string `json:"target,omitempty"`
+}
+
+// WriteGraph writes one line to w for every node of the graph rooted at root,
+// in pre-order. Each node is named by its slash-separated path relative to
+// the root, which itself is ".".
+func WriteGraph(root graph.SkaffoldNode, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	return graph.Walk(root, func(n graph.SkaffoldNode, depth int, segments []string) error {
+		out := line{Path: ".", Type: n.Type()}
+		if len(segments) > 0 {
+			out.Path = path.Join(segments...)
+		}
+
+		switch node := n.(type) {
+		case *graph.DirectoryNode:
+		case *graph.FileNode:
+			out.Action = node.Action()
+			out.ContentType = node.ContentType()
+			out.DataHash = hex.EncodeToString(node.DataHash())
+			out.Algorithm = node.HashAlgorithm()
+			out.Size = node.Size()
+		case *graph.SymlinkNode:
+			out.Target = node.Target()
+		default:
+			return fmt.Errorf("cannot write node %s of type %s", n.Key(), n.Type())
+		}
+
+		if err := enc.Encode(out); err != nil {
+			return fmt.Errorf("failed to encode %s as JSON: %w", out.Path, err)
+		}
+		return nil
+	})
+}
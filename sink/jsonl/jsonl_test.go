@@ -0,0 +1,77 @@
+package jsonl
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+)
+
+func TestWriteGraph(t *testing.T) {
+	root, err := graph.NewBuilder("app").
+		Dir("src", func(b *graph.Builder) {
+			b.File("main.go").Content([]byte("package main\n"))
+		}).
+		File("README.md.tmpl").Content([]byte("# {{.Name}}\n")).
+		Symlink("current", "src").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf strings.Builder
+	if err := WriteGraph(root, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []map[string]any
+	scanner := bufio.NewScanner(strings.NewReader(buf.String()))
+	for scanner.Scan() {
+		var obj map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &obj); err != nil {
+			t.Fatalf("line %q is not a JSON object: %v", scanner.Text(), err)
+		}
+		got = append(got, obj)
+	}
+
+	mainSum := sha256.Sum256([]byte("package main\n"))
+	readmeSum := sha256.Sum256([]byte("# {{.Name}}\n"))
+	want := []map[string]any{
+		{"path": ".", "type": "DIRECTORY"},
+		{"path": "src", "type": "DIRECTORY"},
+		{
+			"path": "src/main.go", "type": "FILE", "action": "COPY",
+			"content_type": "text/x-go; charset=utf-8", "size": float64(13),
+			"datahash": hex.EncodeToString(mainSum[:]), "hash_algorithm": "SHA256",
+		},
+		{
+			"path": "README.md.tmpl", "type": "FILE", "action": "TEMPLATE",
+			"content_type": "text/markdown; charset=utf-8", "size": float64(12),
+			"datahash": hex.EncodeToString(readmeSum[:]), "hash_algorithm": "SHA256",
+		},
+		{"path": "current", "type": "SYMLINK", "target": "src"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteGraph wrote\n%s\nwant objects %v", buf.String(), want)
+	}
+}
+
+func TestWriteGraphOmitsUnknownFields(t *testing.T) {
+	root, err := graph.NewBuilder("app").File("empty").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf strings.Builder
+	if err := WriteGraph(root, &buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if want := `{"path":"empty","type":"FILE","action":"COPY"}`; len(lines) != 2 || lines[1] != want {
+		t.Errorf("WriteGraph wrote %q, want the file as %s", buf.String(), want)
+	}
+}
@@ -0,0 +1,170 @@
+// Package tar writes a scaffold graph as a tar archive.
+package tar
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// SymlinkMode is the permission mode given to symlink entries. Directory and
+// file entries take the mode of their node.
+const SymlinkMode = 0o777
+
+// modTime is the fixed modification time of every entry, so the same graph
+// always produces the same archive.
+var modTime = time.Unix(0, 0)
+
+// WriteOptions controls how WriteGraphWithOptions writes an archive.
+type WriteOptions struct {
+	// SortChildren writes the entries of each directory ordered by key, so
+	// graphs holding the same nodes in any order produce the same archive.
+	// The graph itself is left unchanged.
+	SortChildren bool
+	// Gzip compresses the archive as WriteGraphGz does.
+	Gzip bool
+}
+
+// WriteGraph writes the graph rooted at root to w as a tar stream. The root
+// itself stands for the archive, so entries are named by their slash-separated
+// path relative to it. Files are written with their stored content, and
+// captured ownership and extended attributes are recorded in the headers.
+func WriteGraph(root graph.SkaffoldNode, w io.Writer) error {
+	return WriteGraphWithOptions(root, w, WriteOptions{})
+}
+
+// WriteGraphGz writes the graph rooted at root to w as a gzip-compressed tar
+// stream, as WriteGraph does. The gzip stream is finished before returning,
+// so w holds a complete archive.
+func WriteGraphGz(root graph.SkaffoldNode, w io.Writer) error {
+	return WriteGraphWithOptions(root, w, WriteOptions{Gzip: true})
+}
+
+// WriteGraphWithOptions writes the graph rooted at root to w as a tar stream
+// using the provided options.
+func WriteGraphWithOptions(root graph.SkaffoldNode, w io.Writer, opts WriteOptions) error {
+	if opts.SortChildren {
+		root = graph.Clone(root)
+		graph.SortChildren(root)
+	}
+	if !opts.Gzip {
+		return writeTar(root, w)
+	}
+
+	zw := gzip.NewWriter(w)
+	if err := writeTar(root, zw); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish gzip stream: %w", err)
+	}
+	return nil
+}
+
+// writeTar writes the tar stream of the graph rooted at root to w.
+func writeTar(root graph.SkaffoldNode, w io.Writer) error {
+	tw := tar.NewWriter(w)
+
+	err := graph.Walk(root, func(node graph.SkaffoldNode, depth int, segments []string) error {
+		if depth == 0 {
+			return nil
+		}
+
+		name, err := entryName(segments)
+		if err != nil {
+			return err
+		}
+
+		hdr := &tar.Header{Name: name, ModTime: modTime, Format: tar.FormatPAX}
+		var content graph.ContentProvider
+
+		switch n := node.(type) {
+		case *graph.DirectoryNode:
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+			hdr.Mode = int64(n.Mode())
+			setAttributes(hdr, n)
+		case *graph.FileNode:
+			content = n.ContentProvider()
+			hdr.Typeflag = tar.TypeReg
+			hdr.Mode = int64(n.Mode())
+			hdr.Size = content.Size()
+			setAttributes(hdr, n)
+		case *graph.SymlinkNode:
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Mode = SymlinkMode
+			hdr.Linkname = n.Target()
+			if uid, gid, ok := n.Owner(); ok {
+				hdr.Uid, hdr.Gid = uid, gid
+			}
+		default:
+			return fmt.Errorf("cannot write node %s of type %s", node.Key(), node.Type())
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write tar header for %s: %w", name, err)
+		}
+		if content == nil {
+			return nil
+		}
+		return writeContent(tw, content, name)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finish tar archive: %w", err)
+	}
+	return nil
+}
+
+// writeContent streams the content of a file entry named name to tw.
+func writeContent(tw *tar.Writer, content graph.ContentProvider, name string) error {
+	rc, err := content.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if _, err := io.Copy(tw, rc); err != nil {
+		return fmt.Errorf("failed to write tar entry %s: %w", name, err)
+	}
+	return nil
+}
+
+// entryName joins the node path into an archive entry name, rejecting any key
+// that would not form a single path segment.
+func entryName(segments []string) (string, error) {
+	for _, segment := range segments {
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsAny(segment, `/\`) {
+			return "", fmt.Errorf("refusing to write node with unsafe key %q", segment)
+		}
+	}
+	return path.Join(segments...), nil
+}
+
+// attributed is implemented by nodes that carry captured filesystem attributes.
+type attributed interface {
+	Xattrs() map[string][]byte
+	Owner() (int, int, bool)
+}
+
+// setAttributes records captured ownership and extended attributes on hdr,
+// using the SCHILY.xattr PAX records understood by GNU tar and bsdtar.
+func setAttributes(hdr *tar.Header, node attributed) {
+	if uid, gid, ok := node.Owner(); ok {
+		hdr.Uid, hdr.Gid = uid, gid
+	}
+	for name, value := range node.Xattrs() {
+		if hdr.PAXRecords == nil {
+			hdr.PAXRecords = make(map[string]string)
+		}
+		hdr.PAXRecords["SCHILY.xattr."+name] = string(value)
+	}
+}
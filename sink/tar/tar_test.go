@@ -0,0 +1,183 @@
+package tar
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+	srctar "github.com/sthussey/ska/source/tar"
+)
+
+// entry is the part of a tar entry checked by the tests.
+type entry struct {
+	typeflag byte
+	mode     int64
+	content  string
+	linkname string
+}
+
+// readEntries reads every entry of the tar stream in data, keyed by name.
+func readEntries(t *testing.T, data []byte) (map[string]entry, []string) {
+	t.Helper()
+	entries := make(map[string]entry)
+	var names []string
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries, names
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hdr.ModTime.Equal(modTime) {
+			t.Errorf("%s has modification time %v, want %v", hdr.Name, hdr.ModTime, modTime)
+		}
+		entries[hdr.Name] = entry{hdr.Typeflag, hdr.Mode, string(content), hdr.Linkname}
+		names = append(names, hdr.Name)
+	}
+}
+
+// sinkGraph returns a graph holding a directory, a file and a symlink.
+func sinkGraph(t *testing.T) graph.SkaffoldNode {
+	t.Helper()
+	root := graph.NewDirectoryNode("root")
+	src := graph.NewDirectoryNode("src")
+	file, err := graph.NewFileNodeFull("main.go", []byte("package main\n"), graph.FILEACTION_COPY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.SetMode(0o755)
+	for _, add := range []struct{ parent, child graph.SkaffoldNode }{
+		{root, src},
+		{src, file},
+		{root, graph.NewSymlinkNode("current", "src")},
+	} {
+		_ = add.child.SetParent(add.parent)
+		if err := add.parent.AddChild(add.child); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestWriteGraph(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGraph(sinkGraph(t), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, names := readEntries(t, buf.Bytes())
+	want := map[string]entry{
+		"src/":        {tar.TypeDir, int64(graph.DefaultDirMode), "", ""},
+		"src/main.go": {tar.TypeReg, 0o755, "package main\n", ""},
+		"current":     {tar.TypeSymlink, SymlinkMode, "", "src"},
+	}
+	if len(names) != len(want) {
+		t.Errorf("archive holds %v, want %d entries", names, len(want))
+	}
+	for name, w := range want {
+		if got, ok := entries[name]; !ok || got != w {
+			t.Errorf("entry %s = %+v, want %+v", name, got, w)
+		}
+	}
+}
+
+func TestWriteGraphDeterministic(t *testing.T) {
+	var first, second bytes.Buffer
+	if err := WriteGraph(sinkGraph(t), &first); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteGraph(sinkGraph(t), &second); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("writing the same graph twice produced different archives")
+	}
+}
+
+func TestWriteGraphUnsafeKey(t *testing.T) {
+	root := graph.NewDirectoryNode("root")
+	if err := root.AddChild(graph.NewDirectoryNode("..")); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteGraph(root, io.Discard); err == nil {
+		t.Error("WriteGraph accepted a node keyed ..")
+	}
+}
+
+// leaves describes each leaf below root by its path, mode and content or target.
+func leaves(t *testing.T, root graph.SkaffoldNode) []string {
+	t.Helper()
+	flat, err := graph.Flatten(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, leaf := range flat {
+		switch n := leaf.Node.(type) {
+		case *graph.FileNode:
+			content, err := n.Content()
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, fmt.Sprintf("%s %v %q", leaf.Path, n.Mode(), content))
+		case *graph.SymlinkNode:
+			out = append(out, fmt.Sprintf("%s -> %s", leaf.Path, n.Target()))
+		default:
+			out = append(out, leaf.Path+"/")
+		}
+	}
+	return out
+}
+
+func TestWriteGraphGzRoundTrip(t *testing.T) {
+	root := sinkGraph(t)
+
+	var buf bytes.Buffer
+	if err := WriteGraphGz(root, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srctar.BuildGraph(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("the gzip stream was read as a plain tar stream")
+	}
+
+	got, err := srctar.BuildGraphGz(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := leaves(t, root), leaves(t, got); !reflect.DeepEqual(have, want) {
+		t.Errorf("read back %v, want %v", have, want)
+	}
+}
+
+func TestWriteGraphSortChildren(t *testing.T) {
+	root := sinkGraph(t)
+	var unsorted, sorted bytes.Buffer
+	if err := WriteGraph(root, &unsorted); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteGraphWithOptions(root, &sorted, WriteOptions{SortChildren: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, names := readEntries(t, unsorted.Bytes())
+	if want := []string{"src/", "src/main.go", "current"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("unsorted entries = %v, want %v", names, want)
+	}
+	_, names = readEntries(t, sorted.Bytes())
+	if want := []string{"current", "src/", "src/main.go"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("sorted entries = %v, want %v", names, want)
+	}
+	if root.Children()[0].Key() != "src" {
+		t.Error("WriteGraphWithOptions sorted the graph itself")
+	}
+}
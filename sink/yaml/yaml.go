@@ -0,0 +1,86 @@
+// Package yaml writes a scaffold graph as a nested YAML document.
+package yaml
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/sthussey/ska/graph"
+	"gopkg.in/yaml.v3"
+)
+
+// node is the YAML representation of a graph node. Directories list their
+// children, files carry their action and content and symlinks their target.
+type node struct {
+	Name     string  `yaml:"name"`
+	Type     string  `yaml:"type"`
+	Action   string  `yaml:"action,omitempty"`
+	Content  content `yaml:"content,omitempty"`
+	Target   string  `yaml:"target,omitempty"`
+	Children []*node `yaml:"children,omitempty"`
+}
+
+// content is emitted as a literal block scalar when it is valid UTF-8 text and
+// as a base64 !!binary scalar otherwise.
+type content []byte
+
+func (c content) MarshalYAML() (interface{}, error) {
+	if utf8.Valid(c) {
+		return &yaml.Node{Kind: yaml.ScalarNode, Style: yaml.LiteralStyle, Value: string(c)}, nil
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!binary", Value: base64.StdEncoding.EncodeToString(c)}, nil
+}
+
+// WriteGraph writes the graph rooted at root to w as a nested YAML document,
+// including the content of every file.
+func WriteGraph(root graph.SkaffoldNode, w io.Writer) error {
+	doc, err := toYAML(root)
+	if err != nil {
+		return err
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode graph as YAML: %w", err)
+	}
+	return enc.Close()
+}
+
+// toYAML converts a graph node and its descendants to their YAML representation.
+func toYAML(n graph.SkaffoldNode) (*node, error) {
+	out := &node{Name: n.Key(), Type: n.Type()}
+
+	switch n.Type() {
+	case graph.NODETYPE_DIRECTORY:
+		for _, child := range n.Children() {
+			c, err := toYAML(child)
+			if err != nil {
+				return nil, err
+			}
+			out.Children = append(out.Children, c)
+		}
+	case graph.NODETYPE_FILE:
+		fileNode, ok := n.(*graph.FileNode)
+		if !ok {
+			return nil, fmt.Errorf("file node %s has unsupported implementation %T", n.Key(), n)
+		}
+		data, err := fileNode.Content()
+		if err != nil {
+			return nil, err
+		}
+		out.Action = fileNode.Action()
+		out.Content = data
+	case graph.NODETYPE_SYMLINK:
+		linkNode, ok := n.(*graph.SymlinkNode)
+		if !ok {
+			return nil, fmt.Errorf("symlink node %s has unsupported implementation %T", n.Key(), n)
+		}
+		out.Target = linkNode.Target()
+	default:
+		return nil, fmt.Errorf("node %s has unsupported type %s", n.Key(), n.Type())
+	}
+	return out, nil
+}
@@ -0,0 +1,64 @@
+package yaml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+	"gopkg.in/yaml.v3"
+)
+
+func TestWriteGraph(t *testing.T) {
+	root := graph.NewDirectoryNode("app")
+	src := graph.NewDirectoryNode("src")
+	readme, err := graph.NewFileNodeFull("README.md", []byte("# app\n"), graph.FILEACTION_TEMPLATE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logo, err := graph.NewFileNodeFull("logo.bin", []byte{0xff, 0x00, 0xfe}, graph.FILEACTION_COPY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, add := range []struct{ parent, child graph.SkaffoldNode }{{root, readme}, {root, src}, {src, logo}} {
+		if err := add.parent.AddChild(add.child); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := WriteGraph(root, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "!!binary") {
+		t.Errorf("binary content is not tagged !!binary:\n%s", out.String())
+	}
+
+	var doc struct {
+		Name     string `yaml:"name"`
+		Type     string `yaml:"type"`
+		Children []struct {
+			Name     string `yaml:"name"`
+			Type     string `yaml:"type"`
+			Action   string `yaml:"action"`
+			Content  string `yaml:"content"`
+			Children []struct {
+				Name    string `yaml:"name"`
+				Content string `yaml:"content"`
+			} `yaml:"children"`
+		} `yaml:"children"`
+	}
+	if err := yaml.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Name != "app" || doc.Type != graph.NODETYPE_DIRECTORY || len(doc.Children) != 2 {
+		t.Fatalf("unexpected document:\n%s", out.String())
+	}
+	file, dir := doc.Children[0], doc.Children[1]
+	if file.Name != "README.md" || file.Action != graph.FILEACTION_TEMPLATE || file.Content != "# app\n" {
+		t.Errorf("README.md = %+v", file)
+	}
+	if dir.Name != "src" || len(dir.Children) != 1 || dir.Children[0].Content != "\xff\x00\xfe" {
+		t.Errorf("src = %+v", dir)
+	}
+}
@@ -0,0 +1,120 @@
+// Package zip writes a scaffold graph as a zip archive.
+package zip
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// SymlinkMode is the permission mode given to symlink entries. Directory and
+// file entries take the mode of their node.
+const SymlinkMode = 0o777
+
+// modTime is the fixed modification time of every entry, so the same graph
+// always produces the same archive. It is the earliest time zip can represent.
+var modTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// WriteOptions controls how WriteGraphWithOptions writes an archive.
+type WriteOptions struct {
+	// SortChildren writes the entries of each directory ordered by key, so
+	// graphs holding the same nodes in any order produce the same archive.
+	// The graph itself is left unchanged.
+	SortChildren bool
+}
+
+// WriteGraph writes the graph rooted at root to w as a zip archive. The root
+// itself stands for the archive, so entries are named by their slash-separated
+// path relative to it, with directory names ending in "/". Files are written
+// compressed with their stored content, and symlinks are stored as entries
+// whose content is the link target, as done by Info-ZIP.
+func WriteGraph(root graph.SkaffoldNode, w io.Writer) error {
+	return WriteGraphWithOptions(root, w, WriteOptions{})
+}
+
+// WriteGraphWithOptions writes the graph rooted at root to w as a zip archive
+// using the provided options.
+func WriteGraphWithOptions(root graph.SkaffoldNode, w io.Writer, opts WriteOptions) error {
+	if opts.SortChildren {
+		root = graph.Clone(root)
+		graph.SortChildren(root)
+	}
+
+	zw := zip.NewWriter(w)
+
+	err := graph.Walk(root, func(node graph.SkaffoldNode, depth int, segments []string) error {
+		if depth == 0 {
+			return nil
+		}
+
+		name, err := entryName(segments)
+		if err != nil {
+			return err
+		}
+
+		hdr := &zip.FileHeader{Name: name, Modified: modTime}
+		var content graph.ContentProvider
+
+		switch n := node.(type) {
+		case *graph.DirectoryNode:
+			hdr.Name += "/"
+			hdr.SetMode(fs.ModeDir | n.Mode())
+		case *graph.FileNode:
+			content = n.ContentProvider()
+			hdr.Method = zip.Deflate
+			hdr.SetMode(n.Mode())
+		case *graph.SymlinkNode:
+			content = graph.BytesProvider(n.Target())
+			hdr.SetMode(fs.ModeSymlink | SymlinkMode)
+		default:
+			return fmt.Errorf("cannot write node %s of type %s", node.Key(), node.Type())
+		}
+
+		entry, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return fmt.Errorf("failed to write zip header for %s: %w", name, err)
+		}
+		if content == nil {
+			return nil
+		}
+		return writeContent(entry, content, name)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish zip archive: %w", err)
+	}
+	return nil
+}
+
+// writeContent streams content to the entry named name.
+func writeContent(entry io.Writer, content graph.ContentProvider, name string) error {
+	rc, err := content.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if _, err := io.Copy(entry, rc); err != nil {
+		return fmt.Errorf("failed to write zip entry %s: %w", name, err)
+	}
+	return nil
+}
+
+// entryName joins the node path into an archive entry name, rejecting any key
+// that would not form a single path segment.
+func entryName(segments []string) (string, error) {
+	for _, segment := range segments {
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsAny(segment, `/\`) {
+			return "", fmt.Errorf("refusing to write node with unsafe key %q", segment)
+		}
+	}
+	return path.Join(segments...), nil
+}
@@ -0,0 +1,81 @@
+package zip
+
+import (
+	"bytes"
+	"path"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+	srczip "github.com/sthussey/ska/source/zip"
+)
+
+// describe returns a sorted line per node below root giving its path, type
+// and content or target.
+func describe(t *testing.T, root graph.SkaffoldNode) []string {
+	t.Helper()
+	var lines []string
+	err := graph.Walk(root, func(node graph.SkaffoldNode, depth int, segments []string) error {
+		if depth == 0 {
+			return nil
+		}
+		line := node.Type() + " " + path.Join(segments...)
+		switch n := node.(type) {
+		case *graph.FileNode:
+			content, err := n.Content()
+			if err != nil {
+				return err
+			}
+			line += " " + string(content)
+		case *graph.SymlinkNode:
+			line += " -> " + n.Target()
+		}
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestRoundTrip(t *testing.T) {
+	root := graph.NewDirectoryNode("root")
+	src := graph.NewDirectoryNode("src")
+	empty := graph.NewDirectoryNode("empty")
+	file, err := graph.NewFileNodeFull("main.go", []byte("package main\n"), graph.FILEACTION_COPY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary, err := graph.NewFileNodeFull("logo.bin", []byte{0, 1, 2, 0xff}, graph.FILEACTION_COPY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, add := range []struct{ parent, child graph.SkaffoldNode }{
+		{root, src},
+		{root, empty},
+		{root, binary},
+		{src, file},
+		{root, graph.NewSymlinkNode("current", "src")},
+	} {
+		_ = add.child.SetParent(add.parent)
+		if err := add.parent.AddChild(add.child); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteGraph(root, &buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := srczip.BuildGraph(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := describe(t, root), describe(t, got); !reflect.DeepEqual(have, want) {
+		t.Errorf("read back\n%v\nwant\n%v", have, want)
+	}
+}
@@ -1,6 +1,51 @@
 /*
 Copyright 2025 - Scott Hussey, Jerrod Early
 */
+
+// Package ska is the entry point for the common scaffolding workflow:
+// building a graph from a directory, merging graphs, rendering templates and
+// writing the result to disk, with a single import. Graph wraps that
+// workflow. The node, option and collision types below are aliases of those
+// in the canonical packages, graph, source/fs and sink/fs, which remain the
+// place for anything more specialised.
 package ska
 
+import (
+	"github.com/sthussey/ska/graph"
+	sinkfs "github.com/sthussey/ska/sink/fs"
+	"github.com/sthussey/ska/source/fs"
+)
+
 const Welcome = "Welcome to ska"
+
+// Graph model, see package graph.
+type (
+	SkaffoldNode    = graph.SkaffoldNode
+	DirectoryNode   = graph.DirectoryNode
+	FileNode        = graph.FileNode
+	SymlinkNode     = graph.SymlinkNode
+	CollisionAction = graph.CollisionAction
+	MergeOptions    = graph.MergeOptions
+	Conflict        = graph.Conflict
+)
+
+const (
+	NODETYPE_DIRECTORY = graph.NODETYPE_DIRECTORY
+	NODETYPE_FILE      = graph.NODETYPE_FILE
+	NODETYPE_SYMLINK   = graph.NODETYPE_SYMLINK
+
+	FILEACTION_COPY     = graph.FILEACTION_COPY
+	FILEACTION_TEMPLATE = graph.FILEACTION_TEMPLATE
+
+	DefaultOnCollision   = graph.DefaultOnCollision
+	ErrorOnCollision     = graph.ErrorOnCollision
+	OverwriteOnCollision = graph.OverwriteOnCollision
+	YieldOnCollision     = graph.YieldOnCollision
+)
+
+// Options for building from and writing to the filesystem, see packages
+// source/fs and sink/fs.
+type (
+	BuildOptions = fs.BuildOptions
+	WriteOptions = sinkfs.WriteOptions
+)
@@ -0,0 +1,124 @@
+package ska_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sthussey/ska"
+	"github.com/sthussey/ska/graph"
+	sinkfs "github.com/sthussey/ska/sink/fs"
+	"github.com/sthussey/ska/source/fs"
+)
+
+// TestFacadeTypesAreAliases guards against the facade redefining types that
+// would then drift from the canonical packages.
+func TestFacadeTypesAreAliases(t *testing.T) {
+	tests := []struct {
+		name           string
+		facade, target reflect.Type
+	}{
+		{"SkaffoldNode", reflect.TypeOf((*ska.SkaffoldNode)(nil)), reflect.TypeOf((*graph.SkaffoldNode)(nil))},
+		{"DirectoryNode", reflect.TypeOf(ska.DirectoryNode{}), reflect.TypeOf(graph.DirectoryNode{})},
+		{"FileNode", reflect.TypeOf(ska.FileNode{}), reflect.TypeOf(graph.FileNode{})},
+		{"SymlinkNode", reflect.TypeOf(ska.SymlinkNode{}), reflect.TypeOf(graph.SymlinkNode{})},
+		{"CollisionAction", reflect.TypeOf(ska.CollisionAction(0)), reflect.TypeOf(graph.CollisionAction(0))},
+		{"MergeOptions", reflect.TypeOf(ska.MergeOptions{}), reflect.TypeOf(graph.MergeOptions{})},
+		{"BuildOptions", reflect.TypeOf(ska.BuildOptions{}), reflect.TypeOf(fs.BuildOptions{})},
+		{"WriteOptions", reflect.TypeOf(ska.WriteOptions{}), reflect.TypeOf(sinkfs.WriteOptions{})},
+	}
+	for _, tt := range tests {
+		if tt.facade != tt.target {
+			t.Errorf("ska.%s is %v, want an alias of %v", tt.name, tt.facade, tt.target)
+		}
+	}
+
+	if ska.YieldOnCollision != graph.YieldOnCollision || ska.FILEACTION_TEMPLATE != graph.FILEACTION_TEMPLATE {
+		t.Error("facade constants differ from the canonical ones")
+	}
+}
+
+func TestGraphWorkflow(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"base/template/README.md.tmpl": "# {{.Name}}\n",
+		"extra/template/LICENSE":       "MIT\n",
+	}
+	for p, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	base, err := ska.Build(filepath.Join(dir, "base", "template"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra, err := ska.Build(filepath.Join(dir, "extra", "template"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := ska.Merge(base, extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := merged.Render(map[string]any{"Name": "demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := rendered.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"README.md", "LICENSE"} {
+		if !strings.Contains(out.String(), name) {
+			t.Errorf("printed graph does not list %s:\n%s", name, out.String())
+		}
+	}
+
+	dest := filepath.Join(dir, "out")
+	if err := rendered.WriteToDir(dest); err != nil {
+		t.Fatal(err)
+	}
+	for p, want := range map[string]string{"README.md": "# demo\n", "LICENSE": "MIT\n"} {
+		got, err := os.ReadFile(filepath.Join(dest, p))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", p, got, want)
+		}
+	}
+
+	// The input graphs are left as built
+	if _, err := graph.FindByPath(base.Root(), "README.md.tmpl"); err != nil {
+		t.Error(err)
+	}
+	if _, err := graph.FindByPath(base.Root(), "LICENSE"); err == nil {
+		t.Error("merging modified the control graph")
+	}
+}
+
+func TestGraphEmpty(t *testing.T) {
+	var g ska.Graph
+	if err := g.Print(&bytes.Buffer{}); err == nil {
+		t.Error("printing an empty graph succeeded")
+	}
+	if _, err := g.Render(nil); err == nil {
+		t.Error("rendering an empty graph succeeded")
+	}
+	if err := g.WriteToDir(t.TempDir()); err == nil {
+		t.Error("writing an empty graph succeeded")
+	}
+	if _, err := ska.Merge(g); err == nil {
+		t.Error("merging into an empty graph succeeded")
+	}
+}
@@ -0,0 +1,67 @@
+// Package embedfs builds a scaffold graph from an io/fs file system, such as
+// an embed.FS holding templates compiled into a binary.
+package embedfs
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// BuildGraph walks fsys from the directory root, a slash-separated path as
+// accepted by fs.ValidPath, and builds the graph it describes. The root node
+// is keyed by the final segment of root. Content is read into the graph as it
+// is walked. Modes are not recorded, since embed.FS reports every entry as
+// read-only, so nodes keep the default modes; symlinks and other special
+// entries are errors.
+func BuildGraph(fsys fs.FS, root string) (graph.SkaffoldNode, error) {
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("root path %s is not a directory", root)
+	}
+
+	rootNode := graph.NewDirectoryNode(path.Base(root))
+	dirs := map[string]*graph.DirectoryNode{root: rootNode}
+
+	err = fs.WalkDir(fsys, root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		if p == root {
+			return nil
+		}
+
+		// WalkDir visits a directory before its entries, so the parent exists
+		parent := dirs[path.Dir(p)]
+
+		var node graph.SkaffoldNode
+		switch {
+		case entry.IsDir():
+			dirNode := graph.NewDirectoryNode(entry.Name())
+			dirs[p] = dirNode
+			node = dirNode
+		case entry.Type().IsRegular():
+			data, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return fmt.Errorf("failed to read file %s: %w", p, err)
+			}
+			fileNode := graph.NewFileNode(entry.Name())
+			fileNode.SetContent(data)
+			node = fileNode
+		default:
+			return fmt.Errorf("entry %s has unsupported type %s", p, entry.Type())
+		}
+
+		_ = node.SetParent(parent)
+		return parent.AddChild(node)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rootNode, nil
+}
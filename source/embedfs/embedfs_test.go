@@ -0,0 +1,86 @@
+package embedfs
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sthussey/ska/graph"
+)
+
+func TestBuildGraph(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/app/README.md.tmpl":  {Data: []byte("# {{.Name}}\n")},
+		"templates/app/src/main.go":     {Data: []byte("package main\n")},
+		"templates/app/empty":           {Mode: fs.ModeDir},
+		"templates/other/ignored.txt":   {Data: []byte("not part of app")},
+		"templates/app/bin/run.sh":      {Data: []byte("#!/bin/sh\n"), Mode: 0o755},
+		"templates/app/docs/index.html": {Data: []byte("<html></html>")},
+	}
+
+	root, err := BuildGraph(fsys, "templates/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Key() != "app" {
+		t.Errorf("root key = %q, want app", root.Key())
+	}
+
+	tests := []struct {
+		path    string
+		content string
+		action  string
+	}{
+		{"README.md.tmpl", "# {{.Name}}\n", graph.FILEACTION_TEMPLATE},
+		{"src/main.go", "package main\n", graph.FILEACTION_COPY},
+		{"bin/run.sh", "#!/bin/sh\n", graph.FILEACTION_COPY},
+	}
+	for _, tt := range tests {
+		node, err := graph.FindByPath(root, tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := node.(*graph.FileNode)
+		content, err := file.Content()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != tt.content || file.Action() != tt.action {
+			t.Errorf("%s = %q (%s), want %q (%s)", tt.path, content, file.Action(), tt.content, tt.action)
+		}
+		// Modes reported by the file system are not recorded
+		if file.Mode() != graph.DefaultFileMode {
+			t.Errorf("%s has mode %v, want the default", tt.path, file.Mode())
+		}
+	}
+
+	if node, err := graph.FindByPath(root, "empty"); err != nil || node.Type() != graph.NODETYPE_DIRECTORY {
+		t.Errorf("empty directory = %v, %v; want a directory node", node, err)
+	}
+	if _, err := graph.FindByPath(root, "ignored.txt"); err == nil {
+		t.Error("a file outside the root was included")
+	}
+}
+
+func TestBuildGraphErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"file.txt":      {Data: []byte("x")},
+		"links/current": {Data: []byte("target"), Mode: fs.ModeSymlink},
+	}
+
+	tests := []struct {
+		name string
+		root string
+	}{
+		{"missing root", "missing"},
+		{"file root", "file.txt"},
+		{"symlink entry", "links"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BuildGraph(fsys, tt.root); err == nil {
+				t.Errorf("BuildGraph(%q) succeeded", tt.root)
+			}
+		})
+	}
+}
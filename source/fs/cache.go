@@ -0,0 +1,134 @@
+package fs
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const hashCacheVersion = 1
+
+// HashCache is an on-disk cache of file hashes and content types keyed by
+// absolute path, modification time, size and hash algorithm, so one cache can
+// serve builds of several roots without handing the hash of a file in one
+// tree to a file in another. The cache is stored as JSON and is safe
+// to delete at any time; a missing or unreadable cache file simply starts empty.
+// A HashCache is safe for concurrent use.
+type HashCache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]hashCacheEntry
+	dirty   bool
+}
+
+type hashCacheFile struct {
+	Version int                       `json:"version"`
+	Entries map[string]hashCacheEntry `json:"entries"`
+}
+
+type hashCacheEntry struct {
+	ModTime     int64  `json:"mtime"`
+	Size        int64  `json:"size"`
+	Algorithm   string `json:"algorithm"`
+	DataHash    string `json:"datahash"`
+	ContentType string `json:"content_type"`
+}
+
+// NewHashCache creates a HashCache backed by the file at path, loading any
+// entries already stored there.
+func NewHashCache(path string) (*HashCache, error) {
+	c := &HashCache{
+		path:    path,
+		entries: make(map[string]hashCacheEntry),
+	}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hash cache %s: %w", path, err)
+	}
+
+	// A corrupt or outdated cache is discarded rather than failing the build
+	var file hashCacheFile
+	if err := json.Unmarshal(raw, &file); err != nil || file.Version != hashCacheVersion {
+		return c, nil
+	}
+	if file.Entries != nil {
+		c.entries = file.Entries
+	}
+	return c, nil
+}
+
+// Save writes the cache back to disk if any entries changed since it was loaded.
+func (c *HashCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	raw, err := json.Marshal(hashCacheFile{Version: hashCacheVersion, Entries: c.entries})
+	if err != nil {
+		return fmt.Errorf("failed to encode hash cache: %w", err)
+	}
+
+	// Write to a temp file and rename so an interrupted save never leaves a partial cache
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create hash cache %s: %w", c.path, err)
+	}
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write hash cache %s: %w", c.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write hash cache %s: %w", c.path, err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write hash cache %s: %w", c.path, err)
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// lookup returns the cached hash and content type for the absolute path if the entry is
+// still valid for the file described by info and was hashed with algorithm.
+func (c *HashCache) lookup(path string, info os.FileInfo, algorithm string) ([]byte, string, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[path]
+	c.mu.Unlock()
+	if !ok || entry.ModTime != info.ModTime().UnixNano() || entry.Size != info.Size() || entry.Algorithm != algorithm {
+		return nil, "", false
+	}
+	hash, err := hex.DecodeString(entry.DataHash)
+	if err != nil {
+		return nil, "", false
+	}
+	return hash, entry.ContentType, true
+}
+
+// store records the hash, computed with algorithm, and content type for the file at the absolute path.
+func (c *HashCache) store(path string, info os.FileInfo, algorithm string, hash []byte, contentType string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[path] = hashCacheEntry{
+		ModTime:     info.ModTime().UnixNano(),
+		Size:        info.Size(),
+		Algorithm:   algorithm,
+		DataHash:    hex.EncodeToString(hash),
+		ContentType: contentType,
+	}
+	c.dirty = true
+}
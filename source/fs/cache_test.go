@@ -0,0 +1,103 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// cachedHash builds the tree at dir with cache and returns the hash of a.txt.
+func cachedHash(t *testing.T, dir string, cache *HashCache) []byte {
+	t.Helper()
+	root, err := BuildGraphWithOptions(context.Background(), dir, BuildOptions{Cache: cache})
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := graph.FindByPath(root, "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return node.(*graph.FileNode).DataHash()
+}
+
+func TestHashCacheSharedAcrossRoots(t *testing.T) {
+	// Two trees hold a.txt with the same size and modification time but
+	// different content, so only the path tells their entries apart
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	contents := []string{"first", "other"}
+	roots := make([]string, len(contents))
+	for i, content := range contents {
+		roots[i] = writeManifestTree(t, map[string]string{"a.txt": content})
+		if err := os.Chtimes(filepath.Join(roots[i], "a.txt"), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+	cache, err := NewHashCache(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, root := range roots {
+		want := sha256.Sum256([]byte(contents[i]))
+		if got := cachedHash(t, root, cache); !bytes.Equal(got, want[:]) {
+			t.Errorf("a.txt in root %d hashed %x, want %x", i, got, want)
+		}
+	}
+
+	// The saved cache serves both roots from their own entries
+	reloaded, err := NewHashCache(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.entries) != 2 {
+		t.Fatalf("cache holds %d entries, want 2", len(reloaded.entries))
+	}
+	for i := len(roots) - 1; i >= 0; i-- {
+		want := sha256.Sum256([]byte(contents[i]))
+		if got := cachedHash(t, roots[i], reloaded); !bytes.Equal(got, want[:]) {
+			t.Errorf("a.txt in root %d hashed %x from the cache, want %x", i, got, want)
+		}
+	}
+}
+
+func TestHashCacheReusesUnchangedEntries(t *testing.T) {
+	dir := writeManifestTree(t, map[string]string{"a.txt": "first"})
+	path := filepath.Join(dir, "a.txt")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := NewHashCache(filepath.Join(t.TempDir(), "cache.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := cachedHash(t, dir, cache)
+
+	// Rewriting the file with the same size and modification time is not
+	// noticed, which shows the cached entry was used
+	if err := os.WriteFile(path, []byte("again"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if got := cachedHash(t, dir, cache); !bytes.Equal(got, first) {
+		t.Errorf("hash = %x, want the cached %x", got, first)
+	}
+
+	// A change in size invalidates the entry
+	if err := os.WriteFile(path, []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want := sha256.Sum256([]byte("changed"))
+	if got := cachedHash(t, dir, cache); !bytes.Equal(got, want[:]) {
+		t.Errorf("hash = %x, want %x", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// GraphEstimate summarizes the size of the graph a build would produce.
+type GraphEstimate struct {
+	Directories int   // Number of directory nodes, including the root
+	Files       int   // Number of file nodes
+	Symlinks    int   // Number of symlink nodes
+	TotalBytes  int64 // Sum of file sizes in bytes
+}
+
+// Nodes returns the total number of nodes the build would produce.
+func (e GraphEstimate) Nodes() int {
+	return e.Directories + e.Files + e.Symlinks
+}
+
+// EstimateGraph performs a lightweight, stat-only walk of rootPath and returns
+// the approximate size of the graph BuildGraphWithOptions would build with the
+// same options. No file content is read.
+func EstimateGraph(rootPath string, opts BuildOptions) (GraphEstimate, error) {
+	estimate := GraphEstimate{}
+
+	b, err := newBuilder(context.Background(), rootPath, opts)
+	if err != nil {
+		return estimate, err
+	}
+
+	estimate.Directories++
+	_, err = b.estimateDir(b.root, &estimate, 1)
+	return estimate, err
+}
+
+// estimateDir accumulates the entries under dirPath, which lie at depth, into
+// estimate, applying the same filters as walkDir. It reports whether any
+// entries were counted.
+func (b *builder) estimateDir(dirPath string, estimate *GraphEstimate, depth int) (bool, error) {
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read directory %s: %w", dirPath, err)
+	}
+
+	counted := false
+	for _, entry := range entries {
+		fullPath := filepath.Join(dirPath, entry.Name())
+
+		ignored, err := b.ignorePath(fullPath, entry.IsDir())
+		if err != nil {
+			return false, err
+		}
+		if ignored {
+			continue
+		}
+
+		if entry.IsDir() && entry.Type()&os.ModeSymlink == 0 {
+			added := false
+			if !b.atMaxDepth(depth) {
+				added, err = b.estimateDir(fullPath, estimate, depth+1)
+				if err != nil {
+					return false, err
+				}
+			}
+			if added || len(b.opts.Include) == 0 {
+				estimate.Directories++
+				counted = true
+			}
+			continue
+		}
+
+		include, err := b.includeFile(fullPath)
+		if err != nil {
+			return false, err
+		}
+		if !include {
+			continue
+		}
+
+		if entry.Type()&os.ModeSymlink != 0 {
+			estimate.Symlinks++
+			counted = true
+			continue
+		}
+		if !entry.Type().IsRegular() || b.isManifest(fullPath) {
+			continue
+		}
+
+		counted = true
+		info, err := entry.Info()
+		if err != nil {
+			return false, fmt.Errorf("failed to stat file %s: %w", fullPath, err)
+		}
+		estimate.Files++
+		estimate.TotalBytes += info.Size()
+	}
+	return counted, nil
+}
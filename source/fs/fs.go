@@ -0,0 +1,704 @@
+// Package fs builds a scaffold graph by walking a directory tree on disk.
+package fs
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"text/template"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// BuildOptions controls how BuildGraphWithOptions walks a directory tree.
+type BuildOptions struct {
+	// Cache, if set, supplies hashes and content types for files whose path,
+	// modification time and size are unchanged, so they are not re-read.
+	// The cache is saved once the build completes.
+	Cache *HashCache
+	// CaptureXattrs records the extended attributes of each file and directory
+	// on its node. It has no effect on platforms without xattr support.
+	CaptureXattrs bool
+	// CaptureOwnership records the numeric owner and group of each file and
+	// directory on its node. It has no effect on platforms without uid/gid.
+	CaptureOwnership bool
+	// Classifier derives the action of each file from its path relative to
+	// the root, defaulting to graph.DefaultClassifier. A custom classifier
+	// replaces the default, so append to it to keep .tmpl files as templates.
+	// Directory defaults and manifest rules take precedence over it.
+	Classifier graph.Classifier
+	// ValidateTemplates parses the content of every TEMPLATE file during the
+	// build and records any parse error on the node for graph.Validate to report.
+	ValidateTemplates bool
+	// DefaultActions sets a default file action on directories, keyed by their
+	// slash-separated path relative to the root ("." for the root itself).
+	DefaultActions map[string]string
+	// ContentTyper detects file content types, defaulting to graph.DefaultContentTyper.
+	// Cached content types are reused as-is, so use a separate cache per typer.
+	ContentTyper graph.ContentTyper
+	// Include limits the build to files whose path relative to the root matches
+	// at least one pattern, keeping only the directories that lead to them.
+	// Patterns without a slash match file names at any depth and "**" matches
+	// any number of directories, e.g. "*.tf" or "modules/**/*.tf".
+	Include []string
+	// Ignore excludes paths relative to the root using .gitignore semantics:
+	// "!" negates a pattern, a trailing "/" matches only directories and the
+	// last matching pattern wins. Ignored directories are not walked, and
+	// ignore patterns take precedence over Include.
+	Ignore []string
+	// UseGitignore reads ignore patterns from a .gitignore file at the root,
+	// applied before any patterns in Ignore.
+	UseGitignore bool
+	// UseManifest applies the rules of a ManifestFile at the root, which set
+	// the action, collision action or name of files by pattern. The manifest
+	// itself is left out of the graph.
+	UseManifest bool
+	// MaxDepth, if positive, limits how deep the walk descends. Entries of the
+	// root are at depth 1, and directories at MaxDepth are kept without their
+	// children and marked as truncated.
+	MaxDepth int
+	// MaxFileSize, if positive, is the size in bytes above which files are not
+	// read. Such files are kept, with their size, but marked as having their
+	// content skipped.
+	MaxFileSize int64
+	// InlineThreshold, if positive, is the size in bytes up to which file
+	// content is read into memory during the build and kept on the node, so
+	// graphs of many small files can be rendered, compared or deduplicated
+	// without returning to disk. Larger files are streamed from disk when
+	// their content is needed.
+	InlineThreshold int64
+	// ContentCache, if set, keeps the content of files streamed from disk in
+	// memory once read, within the cache's limits, so content read again is
+	// not re-read. Content is cached by hash, so one cache may be shared by
+	// several builds.
+	ContentCache *graph.ContentCache
+	// HashAlgorithm selects the algorithm used to hash file content, one of
+	// the graph.HASHALGORITHM_* constants, defaulting to graph.DefaultHashAlgorithm.
+	HashAlgorithm string
+	// Parallelism is the number of files read and hashed concurrently,
+	// defaulting to runtime.NumCPU(). The graph is identical regardless of
+	// the setting; 1 processes files sequentially.
+	Parallelism int
+}
+
+type builder struct {
+	ctx      context.Context
+	root     string
+	opts     BuildOptions
+	ignore   []ignoreRule
+	manifest []manifestRule
+	files    []fileJob // File nodes awaiting processFiles, in graph order
+}
+
+// fileJob pairs a file node with the path its content is read from.
+type fileJob struct {
+	path string
+	node *graph.FileNode
+}
+
+// BuildGraph walks the directory tree starting at rootPath and builds a graph.
+func BuildGraph(rootPath string) (graph.SkaffoldNode, error) {
+	return BuildGraphWithOptions(context.Background(), rootPath, BuildOptions{})
+}
+
+// BuildGraphWithOptions walks the directory tree starting at rootPath and builds a graph
+// using the provided options. The walk stops between entries once ctx is done,
+// returning the context's error. Special files such as FIFOs, sockets and
+// devices have no content to scaffold and are left out of the graph.
+func BuildGraphWithOptions(ctx context.Context, rootPath string, opts BuildOptions) (graph.SkaffoldNode, error) {
+	b, err := newBuilder(ctx, rootPath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the root node using the base name of the absolute path
+	rootNode := graph.NewDirectoryNode(filepath.Base(b.root))
+
+	// Start the recursive walk
+	info, err := os.Stat(b.root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", b.root, err)
+	}
+	rootNode.SetMode(info.Mode())
+	err = b.captureXattrs(b.root, rootNode)
+	if err != nil {
+		return nil, err
+	}
+	err = b.captureOwner(b.root, rootNode)
+	if err != nil {
+		return nil, err
+	}
+	err = b.setDefaultAction(b.root, rootNode)
+	if err != nil {
+		return nil, err
+	}
+	_, err = b.walkDir(b.root, rootNode, 1)
+	if err != nil {
+		return nil, err // Error already contains context from walkDir
+	}
+	// Directory defaults must be applied before templates are validated
+	graph.ApplyDefaultActions(rootNode)
+
+	err = b.processFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Cache != nil {
+		if err := opts.Cache.Save(); err != nil {
+			return nil, err
+		}
+	}
+
+	return rootNode, nil
+}
+
+// BuildMerged builds a graph from each of roots and unions them in order, as
+// BuildMergedWithOptions does with the default build options.
+func BuildMerged(opts graph.MergeOptions, roots ...string) (graph.SkaffoldNode, error) {
+	return BuildMergedWithOptions(context.Background(), BuildOptions{}, opts, roots...)
+}
+
+// BuildMergedWithOptions builds a graph from each of roots using buildOpts
+// and unions them into the first with graph.Union using mergeOpts, so later
+// roots overlay earlier ones. Every graph is keyed by the base name of the
+// first root, so roots with different names can be merged. Collisions name
+// the root being merged as well as the path within it. buildOpts.Cache is
+// not used, since a hash cache serves the files of a single root.
+func BuildMergedWithOptions(ctx context.Context, buildOpts BuildOptions, mergeOpts graph.MergeOptions, roots ...string) (graph.SkaffoldNode, error) {
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("no roots to build a merged graph from")
+	}
+	buildOpts.Cache = nil
+
+	merged, err := BuildGraphWithOptions(ctx, roots[0], buildOpts)
+	if err != nil {
+		return nil, err
+	}
+	for _, root := range roots[1:] {
+		overlay, err := BuildGraphWithOptions(ctx, root, buildOpts)
+		if err != nil {
+			return nil, err
+		}
+		if err := overlay.(*graph.DirectoryNode).SetKey(merged.Key()); err != nil {
+			return nil, err
+		}
+		merged, err = graph.Union(merged, mergeOpts, overlay)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge %s into %s: %w", root, roots[0], err)
+		}
+	}
+	return merged, nil
+}
+
+// newBuilder resolves and checks the root path and normalizes the options for
+// a walk that stops once ctx is done.
+func newBuilder(ctx context.Context, rootPath string, opts BuildOptions) (*builder, error) {
+	absRootPath, err := filepath.Abs(rootPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %s: %w", rootPath, err)
+	}
+
+	// Get info about the root path
+	info, err := os.Stat(absRootPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat root path %s: %w", absRootPath, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("root path %s is not a directory", absRootPath)
+	}
+
+	// Normalize the configured directory paths so lookups match the walker's relative paths
+	if opts.DefaultActions != nil {
+		defaults := make(map[string]string, len(opts.DefaultActions))
+		for p, action := range opts.DefaultActions {
+			cleaned, err := graph.CleanPath(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid default action path: %w", err)
+			}
+			defaults[cleaned] = action
+		}
+		opts.DefaultActions = defaults
+	}
+
+	if opts.Classifier == nil {
+		opts.Classifier = graph.DefaultClassifier
+	}
+	if err := opts.Classifier.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid classifier: %w", err)
+	}
+
+	for _, pattern := range opts.Include {
+		if err := graph.ValidateGlob(pattern); err != nil {
+			return nil, fmt.Errorf("invalid include pattern: %w", err)
+		}
+	}
+
+	if opts.HashAlgorithm == "" {
+		opts.HashAlgorithm = graph.DefaultHashAlgorithm
+	}
+	if _, err := graph.NewHasher(opts.HashAlgorithm); err != nil {
+		return nil, err
+	}
+
+	ignore, err := loadIgnoreRules(absRootPath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []manifestRule
+	if opts.UseManifest {
+		rules, err = readManifest(filepath.Join(absRootPath, ManifestFile))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &builder{ctx: ctx, root: absRootPath, opts: opts, ignore: ignore, manifest: rules}, nil
+}
+
+// walkDir recursively walks the directory structure under dirPath, whose
+// entries lie at depth, and adds nodes to the parentNode. It reports whether
+// any nodes were added.
+func (b *builder) walkDir(dirPath string, parentNode *graph.DirectoryNode, depth int) (bool, error) {
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read directory %s: %w", dirPath, err)
+	}
+
+	for _, entry := range entries {
+		if err := b.ctx.Err(); err != nil {
+			return false, err
+		}
+
+		// Construct the full path for the current entry
+		fullPath := filepath.Join(dirPath, entry.Name())
+
+		ignored, err := b.ignorePath(fullPath, entry.IsDir())
+		if err != nil {
+			return false, err
+		}
+		if ignored {
+			continue
+		}
+
+		if entry.Type()&os.ModeSymlink != 0 {
+			// Links are recorded as nodes rather than followed
+			include, err := b.includeFile(fullPath)
+			if err != nil {
+				return false, err
+			}
+			if !include {
+				continue
+			}
+
+			target, err := os.Readlink(fullPath)
+			if err != nil {
+				return false, fmt.Errorf("failed to read symlink %s: %w", fullPath, err)
+			}
+
+			linkNode := graph.NewSymlinkNode(entry.Name(), target)
+			_ = linkNode.SetParent(parentNode)
+			if err := parentNode.AddChild(linkNode); err != nil {
+				return false, err
+			}
+
+			err = b.captureOwner(fullPath, linkNode)
+			if err != nil {
+				return false, err
+			}
+		} else if entry.IsDir() {
+			// Create a new directory node
+			dirNode := graph.NewDirectoryNode(entry.Name())
+
+			// Set parent relationship (error ignored as SetParent currently always returns nil)
+			_ = dirNode.SetParent(parentNode)
+
+			err = captureMode(fullPath, entry, dirNode)
+			if err != nil {
+				return false, err
+			}
+
+			err = b.captureXattrs(fullPath, dirNode)
+			if err != nil {
+				return false, err
+			}
+
+			err = b.captureOwner(fullPath, dirNode)
+			if err != nil {
+				return false, err
+			}
+
+			err = b.setDefaultAction(fullPath, dirNode)
+			if err != nil {
+				return false, err
+			}
+
+			// Recursively walk the subdirectory unless it is at the depth limit
+			added := false
+			if b.atMaxDepth(depth) {
+				dirNode.SetTruncated(true)
+			} else {
+				added, err = b.walkDir(fullPath, dirNode, depth+1)
+				if err != nil {
+					return false, err // Propagate errors from deeper levels
+				}
+			}
+
+			// With include patterns, only directories leading to included files are kept
+			if added || len(b.opts.Include) == 0 {
+				if err := parentNode.AddChild(dirNode); err != nil {
+					return false, err
+				}
+			}
+		} else if entry.Type().IsRegular() {
+			include, err := b.includeFile(fullPath)
+			if err != nil {
+				return false, err
+			}
+			if !include || b.isManifest(fullPath) {
+				continue
+			}
+
+			rel, err := b.relPath(fullPath)
+			if err != nil {
+				return false, err
+			}
+			settings := b.fileSettings(rel)
+
+			// Create a new file node, named as the manifest says
+			name := entry.Name()
+			if settings.rename != "" {
+				name = settings.rename
+			}
+			fileNode := graph.NewFileNodeWithClassifier(name, path.Join(path.Dir(rel), name), b.opts.Classifier)
+			if settings.action != "" {
+				// Checked when the manifest was read
+				_ = fileNode.SetAction(settings.action)
+			}
+			fileNode.SetCollisionAction(settings.onCollision)
+			err = captureMode(fullPath, entry, fileNode)
+			if err != nil {
+				return false, err
+			}
+
+			// Set parent relationship (error ignored as SetParent currently always returns nil)
+			_ = fileNode.SetParent(parentNode)
+			if err := parentNode.AddChild(fileNode); err != nil {
+				return false, err
+			}
+
+			// Reading and hashing is deferred to processFiles so it can run in parallel
+			b.files = append(b.files, fileJob{path: fullPath, node: fileNode})
+		}
+	}
+	return len(parentNode.Children()) > 0, nil
+}
+
+// processFiles reads, hashes and captures the attributes of every queued
+// file using a bounded pool of workers. Each worker only touches its own
+// node, so the graph built by walkDir stays deterministic. If any file fails,
+// the error of the earliest failing file in graph order is returned.
+func (b *builder) processFiles() error {
+	workers := b.opts.Parallelism
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = min(workers, len(b.files))
+
+	errs := make([]error, len(b.files))
+	if workers <= 1 {
+		for i, job := range b.files {
+			if errs[i] = b.processFile(job.path, job.node); errs[i] != nil {
+				break
+			}
+		}
+		return firstError(errs)
+	}
+
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// Drain remaining jobs without work once any file has failed
+				if failed.Load() {
+					continue
+				}
+				if errs[i] = b.processFile(b.files[i].path, b.files[i].node); errs[i] != nil {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+	for i := range b.files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return firstError(errs)
+}
+
+// firstError returns the first non-nil error in errs.
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processFile hashes the file at path and records its content resolver and
+// captured attributes on fileNode.
+func (b *builder) processFile(path string, fileNode *graph.FileNode) error {
+	if err := b.ctx.Err(); err != nil {
+		return err
+	}
+
+	err := b.hashFile(path, fileNode)
+	if err != nil {
+		return err
+	}
+	if !fileNode.ContentSkipped() && !b.inline(fileNode.Size()) {
+		var provider graph.ContentProvider = &fileProvider{
+			path:      path,
+			size:      fileNode.Size(),
+			algorithm: fileNode.HashAlgorithm(),
+			hash:      fileNode.DataHash(),
+		}
+		if b.opts.ContentCache != nil {
+			key := fileNode.HashAlgorithm() + ":" + hex.EncodeToString(fileNode.DataHash())
+			provider = b.opts.ContentCache.Provider(key, provider)
+		}
+		fileNode.SetContentProvider(provider)
+	}
+
+	err = b.captureXattrs(path, fileNode)
+	if err != nil {
+		return err
+	}
+
+	err = b.captureOwner(path, fileNode)
+	if err != nil {
+		return err
+	}
+
+	return b.validateTemplate(path, fileNode)
+}
+
+// captureMode records the permission bits of the directory entry at path on node.
+func captureMode(path string, entry os.DirEntry, node interface{ SetMode(os.FileMode) }) error {
+	info, err := entry.Info()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	node.SetMode(info.Mode())
+	return nil
+}
+
+// relPath returns the slash-separated path of path relative to the build root.
+func (b *builder) relPath(path string) (string, error) {
+	rel, err := filepath.Rel(b.root, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to get relative path for %s: %w", path, err)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// atMaxDepth reports whether directories at depth are at the depth limit.
+func (b *builder) atMaxDepth(depth int) bool {
+	return b.opts.MaxDepth > 0 && depth >= b.opts.MaxDepth
+}
+
+// ignorePath reports whether the entry at path matches the ignore patterns.
+func (b *builder) ignorePath(path string, isDir bool) (bool, error) {
+	if len(b.ignore) == 0 {
+		return false, nil
+	}
+	rel, err := b.relPath(path)
+	if err != nil {
+		return false, err
+	}
+	return matchIgnore(b.ignore, rel, isDir), nil
+}
+
+// includeFile reports whether the file at path passes the include patterns.
+func (b *builder) includeFile(path string) (bool, error) {
+	if len(b.opts.Include) == 0 {
+		return true, nil
+	}
+	rel, err := b.relPath(path)
+	if err != nil {
+		return false, err
+	}
+	for _, pattern := range b.opts.Include {
+		if graph.MatchGlob(pattern, rel) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// setDefaultAction applies any configured default action for the directory at path.
+func (b *builder) setDefaultAction(path string, dirNode *graph.DirectoryNode) error {
+	rel, err := b.relPath(path)
+	if err != nil {
+		return err
+	}
+	action, ok := b.opts.DefaultActions[rel]
+	if !ok {
+		return nil
+	}
+	return dirNode.SetDefaultAction(action)
+}
+
+// captureXattrs records the extended attributes of path on node when enabled.
+func (b *builder) captureXattrs(path string, node interface{ SetXattr(string, []byte) }) error {
+	if !b.opts.CaptureXattrs {
+		return nil
+	}
+	attrs, err := readXattrs(path)
+	if err != nil {
+		return err
+	}
+	for name, value := range attrs {
+		node.SetXattr(name, value)
+	}
+	return nil
+}
+
+// validateTemplate parses template files when enabled, recording parse errors on the node
+// rather than failing the build.
+func (b *builder) validateTemplate(path string, fileNode *graph.FileNode) error {
+	if !b.opts.ValidateTemplates || fileNode.Action() != graph.FILEACTION_TEMPLATE || fileNode.ContentSkipped() {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read template %s: %w", path, err)
+	}
+	_, err = template.New(fileNode.Key()).Parse(string(content))
+	fileNode.SetTemplateError(err)
+	return nil
+}
+
+// contentTyper returns the configured content type detector or the default.
+func (b *builder) contentTyper() graph.ContentTyper {
+	if b.opts.ContentTyper != nil {
+		return b.opts.ContentTyper
+	}
+	return graph.DefaultContentTyper
+}
+
+// captureOwner records the numeric owner and group of path on node when enabled.
+func (b *builder) captureOwner(path string, node interface{ SetOwner(int, int) }) error {
+	if !b.opts.CaptureOwnership {
+		return nil
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if uid, gid, ok := fileOwner(info); ok {
+		node.SetOwner(uid, gid)
+	}
+	return nil
+}
+
+// hashFile computes the content hash and content type for the file at path,
+// consulting the cache first when one is configured. Files larger than
+// MaxFileSize are only sized and marked as skipped, and files within
+// InlineThreshold are read into memory instead.
+func (b *builder) hashFile(path string, fileNode *graph.FileNode) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat file %s: %w", path, err)
+	}
+	// The entry may have been replaced since the walk, and opening a FIFO blocks
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("failed to read file %s: it is no longer a regular file", path)
+	}
+	if b.opts.MaxFileSize > 0 && info.Size() > b.opts.MaxFileSize {
+		fileNode.SkipContent(info.Size(), fmt.Errorf("content of %s was not read because it exceeds the maximum file size", path))
+		return nil
+	}
+	if b.inline(info.Size()) {
+		return b.inlineFile(path, fileNode)
+	}
+
+	if b.opts.Cache != nil {
+		if hash, contentType, ok := b.opts.Cache.lookup(path, info, b.opts.HashAlgorithm); ok {
+			fileNode.SetContentInfo(hash, b.opts.HashAlgorithm, info.Size(), contentType)
+			return nil
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	// Content type detection only needs the first 512 bytes
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	head = head[:n]
+
+	// Stream the rest of the file through the hash rather than reading it into memory
+	hasher, err := graph.NewHasher(b.opts.HashAlgorithm)
+	if err != nil {
+		return err
+	}
+	hasher.Write(head)
+	if _, err := io.Copy(hasher, file); err != nil {
+		return fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+
+	hash := hasher.Sum(nil)
+	contentType := b.contentTyper().ContentType(fileNode.Key(), head)
+	fileNode.SetContentInfo(hash, b.opts.HashAlgorithm, info.Size(), contentType)
+
+	if b.opts.Cache != nil {
+		b.opts.Cache.store(path, info, b.opts.HashAlgorithm, hash, contentType)
+	}
+	return nil
+}
+
+// inline reports whether content of size bytes is kept in memory.
+func (b *builder) inline(size int64) bool {
+	return b.opts.InlineThreshold > 0 && size <= b.opts.InlineThreshold
+}
+
+// inlineFile reads the whole file at path into memory and stores it on
+// fileNode along with its hash and content type.
+func (b *builder) inlineFile(path string, fileNode *graph.FileNode) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+
+	hasher, err := graph.NewHasher(b.opts.HashAlgorithm)
+	if err != nil {
+		return err
+	}
+	hasher.Write(content)
+	contentType := b.contentTyper().ContentType(fileNode.Key(), content[:min(len(content), 512)])
+	fileNode.SetContentInfo(hasher.Sum(nil), b.opts.HashAlgorithm, int64(len(content)), contentType)
+	fileNode.SetContentProvider(graph.BytesProvider(content))
+	return fileNode.LoadContent()
+}
@@ -0,0 +1,289 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+	"github.com/sthussey/ska/graphtest"
+	sinkfs "github.com/sthussey/ska/sink/fs"
+)
+
+func TestBuildGraphClassifier(t *testing.T) {
+	dir := writeManifestTree(t, map[string]string{
+		"templates/app.conf":      "name = {{ .Name }}\n",
+		"templates/logo.png":      "png",
+		"main.go.tmpl":            "package {{ .Name }}\n",
+		"README.md":               "# readme\n",
+		"templates/renamed/a.txt": "a",
+		ManifestFile:              "rules:\n  - match: templates/logo.png\n    action: COPY\n  - match: templates/renamed/a.txt\n    rename: b.txt\n",
+	})
+	classifier := graph.Classifier{{Pattern: "templates/**", Action: graph.FILEACTION_TEMPLATE}}
+
+	root, err := BuildGraphWithOptions(context.Background(), dir, BuildOptions{Classifier: classifier, UseManifest: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path   string
+		action string
+	}{
+		{path: "templates/app.conf", action: graph.FILEACTION_TEMPLATE},
+		// Manifest rules take precedence over the classifier
+		{path: "templates/logo.png", action: graph.FILEACTION_COPY},
+		// A custom classifier replaces the .tmpl default
+		{path: "main.go.tmpl", action: graph.FILEACTION_COPY},
+		{path: "README.md", action: graph.FILEACTION_COPY},
+		{path: "templates/renamed/b.txt", action: graph.FILEACTION_TEMPLATE},
+	}
+	for _, tt := range tests {
+		node, err := graph.FindByPath(root, tt.path)
+		if err != nil {
+			t.Errorf("FindByPath(%s): %v", tt.path, err)
+			continue
+		}
+		if got := node.(*graph.FileNode).Action(); got != tt.action {
+			t.Errorf("%s has action %s, want %s", tt.path, got, tt.action)
+		}
+	}
+}
+
+func TestBuildGraphInvalidClassifier(t *testing.T) {
+	dir := writeManifestTree(t, map[string]string{"a.txt": "a"})
+	classifier := graph.Classifier{{Pattern: "*.txt", Action: "MOVE"}}
+	_, err := BuildGraphWithOptions(context.Background(), dir, BuildOptions{Classifier: classifier})
+	if err == nil || !strings.Contains(err.Error(), "invalid classifier") {
+		t.Errorf("BuildGraphWithOptions error = %v, want an invalid classifier", err)
+	}
+}
+
+func TestBuildGraphInlineThreshold(t *testing.T) {
+	dir := writeManifestTree(t, map[string]string{"small.txt": "abcd", "large.txt": "0123456789"})
+	root, err := BuildGraphWithOptions(context.Background(), dir, BuildOptions{InlineThreshold: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"small.txt", "large.txt"} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		path    string
+		want    string
+		inlined bool
+	}{
+		{path: "small.txt", want: "abcd", inlined: true},
+		{path: "large.txt"},
+	}
+	for _, tt := range tests {
+		node, err := graph.FindByPath(root, tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := node.(*graph.FileNode)
+		content, err := file.Content()
+		if tt.inlined {
+			if err != nil || string(content) != tt.want {
+				t.Errorf("%s content = %q, %v; want it kept in memory", tt.path, content, err)
+			}
+		} else if err == nil {
+			t.Errorf("%s was read into memory above the threshold", tt.path)
+		}
+	}
+
+	node, _ := graph.FindByPath(root, "small.txt")
+	small := node.(*graph.FileNode)
+	sum := sha256.Sum256([]byte("abcd"))
+	if !bytes.Equal(small.DataHash(), sum[:]) || small.Size() != 4 || small.ContentType() == "" {
+		t.Errorf("small.txt has hash %x, size %d and type %q", small.DataHash(), small.Size(), small.ContentType())
+	}
+}
+
+// writeTree writes a generated graph to a temporary directory and returns its path.
+func writeTree(tb testing.TB, seed int64, opts graphtest.GenOptions) string {
+	tb.Helper()
+	dest := filepath.Join(tb.TempDir(), "tree")
+	if err := sinkfs.WriteGraph(graphtest.Generate(seed, opts), dest); err != nil {
+		tb.Fatal(err)
+	}
+	return dest
+}
+
+func TestBuildGraphParallelMatchesSequential(t *testing.T) {
+	for _, seed := range []int64{1, 2, 3} {
+		dir := writeTree(t, seed, graphtest.GenOptions{MaxDepth: 4, MaxBreadth: 6, TemplateRatio: 0.3, BinaryRatio: 0.2})
+
+		sequential, err := BuildGraphWithOptions(context.Background(), dir, BuildOptions{Parallelism: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, workers := range []int{2, 8, 64} {
+			parallel, err := BuildGraphWithOptions(context.Background(), dir, BuildOptions{Parallelism: workers})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if equal, diff := graph.EqualDetailed(sequential, parallel); !equal {
+				t.Errorf("seed %d with %d workers: graphs differ: %s", seed, workers, diff)
+			}
+
+			want, err := graph.GraphHash(sequential)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := graph.GraphHash(parallel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("seed %d with %d workers: graph hashes differ", seed, workers)
+			}
+		}
+	}
+}
+
+func BenchmarkBuildGraph(b *testing.B) {
+	dir := writeTree(b, 1, graphtest.GenOptions{MaxDepth: 4, MaxBreadth: 8, MaxFileSize: 64 << 10})
+	for _, bm := range []struct {
+		name    string
+		workers int
+	}{
+		{name: "sequential", workers: 1},
+		{name: "parallel", workers: 0},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := BuildGraphWithOptions(context.Background(), dir, BuildOptions{Parallelism: bm.workers}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildGraphCancelled(t *testing.T) {
+	dir := writeTree(t, 1, graphtest.GenOptions{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, workers := range []int{1, 4} {
+		_, err := BuildGraphWithOptions(ctx, dir, BuildOptions{Parallelism: workers})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("with %d workers: err = %v, want context.Canceled", workers, err)
+		}
+	}
+}
+
+func TestBuildGraphContentCache(t *testing.T) {
+	dir := writeManifestTree(t, map[string]string{"a.txt": "same", "b.txt": "same", "c.txt": "other"})
+	cache := graph.NewContentCache(1024, 0)
+	root, err := BuildGraphWithOptions(context.Background(), dir, BuildOptions{ContentCache: cache})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(p string) (string, error) {
+		node, err := graph.FindByPath(root, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := node.(*graph.FileNode).Content()
+		return string(content), err
+	}
+	if got, err := read("a.txt"); err != nil || got != "same" {
+		t.Fatalf("a.txt content = %q, %v; want same", got, err)
+	}
+
+	// Cached content is served without returning to disk, and is shared by
+	// files with the same content
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range []string{"a.txt", "b.txt"} {
+		if got, err := read(p); err != nil || got != "same" {
+			t.Errorf("%s content = %q, %v; want it served from the cache", p, got, err)
+		}
+	}
+	if _, err := read("c.txt"); err == nil {
+		t.Error("c.txt was read without being cached")
+	}
+	if cache.Len() != 1 {
+		t.Errorf("cache holds %d entries, want 1", cache.Len())
+	}
+}
+
+func TestBuildMerged(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"base/README.md":         "base",
+		"base/shared.txt":        "base",
+		"overlay/src/main.go":    "package main",
+		"overlay/shared.txt":     "overlay",
+		"overlay/nested/new.txt": "new",
+	}
+	for p, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	base, overlay := filepath.Join(dir, "base"), filepath.Join(dir, "overlay")
+
+	merged, err := BuildMerged(graph.MergeOptions{DefaultCollisionAction: graph.YieldOnCollision}, base, overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Key() != "base" {
+		t.Errorf("root key = %q, want %q", merged.Key(), "base")
+	}
+	for p, want := range map[string]string{"README.md": "base", "shared.txt": "overlay", "src/main.go": "package main", "nested/new.txt": "new"} {
+		node, err := graph.FindByPath(merged, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := node.(*graph.FileNode).Content()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != want {
+			t.Errorf("%s = %q, want %q", p, content, want)
+		}
+	}
+
+	_, err = BuildMerged(graph.MergeOptions{}, base, overlay)
+	if err == nil {
+		t.Fatal("merging roots with a content collision succeeded")
+	}
+	if !strings.Contains(err.Error(), overlay) || !strings.Contains(err.Error(), "shared.txt") {
+		t.Errorf("error %q does not name the overlay and the colliding path", err)
+	}
+
+	if _, err := BuildMerged(graph.MergeOptions{}); err == nil {
+		t.Error("building a merged graph from no roots succeeded")
+	}
+
+	// A hash cache is not shared across the roots
+	cache, err := NewHashCache(filepath.Join(dir, "cache.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := BuildOptions{Cache: cache}
+	if _, err := BuildMergedWithOptions(context.Background(), opts, graph.MergeOptions{DefaultCollisionAction: graph.YieldOnCollision}, base, overlay); err != nil {
+		t.Fatal(err)
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("merged build stored %d entries in the hash cache, want none", len(cache.entries))
+	}
+}
@@ -0,0 +1,59 @@
+//go:build unix
+
+package fs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sthussey/ska/graph"
+)
+
+func TestBuildGraphSkipsSpecialFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Mkfifo(filepath.Join(dir, "fifo"), 0o644); err != nil {
+		t.Skipf("cannot create a FIFO: %v", err)
+	}
+
+	// Opening the FIFO for reading would block the build forever
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	var root graph.SkaffoldNode
+	go func() {
+		var err error
+		root, err = BuildGraphWithOptions(ctx, dir, BuildOptions{})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-ctx.Done():
+		t.Fatal("build blocked on a FIFO")
+	}
+
+	if _, err := graph.FindByPath(root, "a.txt"); err != nil {
+		t.Error(err)
+	}
+	if _, err := graph.FindByPath(root, "fifo"); err == nil {
+		t.Error("the FIFO was added to the graph")
+	}
+
+	estimate, err := EstimateGraph(dir, BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if estimate.Files != 1 {
+		t.Errorf("estimated %d files, want 1", estimate.Files)
+	}
+}
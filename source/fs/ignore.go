@@ -0,0 +1,121 @@
+package fs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// GitignoreFile is the name of the ignore file read from the build root when
+// BuildOptions.UseGitignore is set.
+const GitignoreFile = ".gitignore"
+
+// ignoreRule is a single parsed gitignore-style pattern.
+type ignoreRule struct {
+	pattern  string
+	negate   bool // Pattern started with "!" and re-includes matching paths
+	dir_only bool // Pattern ended with "/" and only matches directories
+}
+
+// parseIgnoreRule parses a gitignore-style line, returning false for blank
+// lines and comments.
+func parseIgnoreRule(line string) (ignoreRule, bool, error) {
+	rule := ignoreRule{}
+
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false, nil
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		// A leading backslash escapes a literal "!" or "#"
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dir_only = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false, nil
+	}
+
+	if err := graph.ValidateGlob(line); err != nil {
+		return rule, false, fmt.Errorf("invalid ignore pattern: %w", err)
+	}
+	rule.pattern = line
+	return rule, true, nil
+}
+
+// readIgnoreFile parses the gitignore-style file at path. A missing file
+// yields no rules.
+func readIgnoreFile(path string) ([]ignoreRule, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ignore file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		rule, ok, err := parseIgnoreRule(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ignore file %s: %w", path, err)
+		}
+		if ok {
+			rules = append(rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ignore file %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// loadIgnoreRules combines the root .gitignore, if requested, with the
+// configured ignore patterns, which take precedence by coming last.
+func loadIgnoreRules(root string, opts BuildOptions) ([]ignoreRule, error) {
+	var rules []ignoreRule
+	if opts.UseGitignore {
+		fileRules, err := readIgnoreFile(filepath.Join(root, GitignoreFile))
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, fileRules...)
+	}
+	for _, pattern := range opts.Ignore {
+		rule, ok, err := parseIgnoreRule(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// matchIgnore reports whether the slash-separated relPath is ignored. As with
+// .gitignore, the last matching rule wins.
+func matchIgnore(rules []ignoreRule, relPath string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.dir_only && !isDir {
+			continue
+		}
+		if graph.MatchGlob(rule.pattern, relPath) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,126 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sthussey/ska/graph"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile is the name of the manifest read from the build root when
+// BuildOptions.UseManifest is set. The manifest assigns settings to files by
+// path pattern, with later rules overriding earlier ones:
+//
+//	rules:
+//	  - match: "config/*.conf"
+//	    action: TEMPLATE
+//	  - match: LICENSE
+//	    on_collision: OVERWRITE
+//	  - match: gitignore
+//	    rename: .gitignore
+//
+// Patterns are matched against the path relative to the root as with
+// BuildOptions.Include, and rename gives the file a new name in the same
+// directory.
+const ManifestFile = ".ska.yaml"
+
+// manifest is the YAML representation of ManifestFile.
+type manifest struct {
+	Rules []manifestRule `yaml:"rules"`
+}
+
+type manifestRule struct {
+	Match       string `yaml:"match"`
+	Action      string `yaml:"action"`
+	OnCollision string `yaml:"on_collision"`
+	Rename      string `yaml:"rename"`
+}
+
+// fileSettings holds the manifest settings for one file; empty fields are unset.
+type fileSettings struct {
+	action      string
+	onCollision graph.CollisionAction
+	rename      string
+}
+
+// readManifest reads and checks the rules of the manifest at path, returning
+// none if the file does not exist.
+func readManifest(path string) ([]manifestRule, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var m manifest
+	dec := yaml.NewDecoder(file)
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+
+	for i, rule := range m.Rules {
+		if err := checkManifestRule(rule); err != nil {
+			return nil, fmt.Errorf("invalid rule %d in manifest %s: %w", i+1, path, err)
+		}
+	}
+	return m.Rules, nil
+}
+
+// checkManifestRule returns an error if any setting of rule is malformed.
+func checkManifestRule(rule manifestRule) error {
+	if rule.Match == "" {
+		return fmt.Errorf("missing match pattern")
+	}
+	if err := graph.ValidateGlob(rule.Match); err != nil {
+		return err
+	}
+	if rule.Action != "" && rule.Action != graph.FILEACTION_COPY && rule.Action != graph.FILEACTION_TEMPLATE {
+		return fmt.Errorf("invalid action %s", rule.Action)
+	}
+	if rule.OnCollision != "" {
+		if _, err := graph.ParseCollisionAction(rule.OnCollision); err != nil {
+			return err
+		}
+	}
+	if rule.Rename == "." || rule.Rename == ".." || strings.ContainsAny(rule.Rename, `/\`) {
+		return fmt.Errorf("invalid rename %q", rule.Rename)
+	}
+	return nil
+}
+
+// fileSettings returns the combined settings of the manifest rules matching
+// the file at the slash-separated relPath.
+func (b *builder) fileSettings(relPath string) fileSettings {
+	var settings fileSettings
+	for _, rule := range b.manifest {
+		if !graph.MatchGlob(rule.Match, relPath) {
+			continue
+		}
+		if rule.Action != "" {
+			settings.action = rule.Action
+		}
+		if rule.OnCollision != "" {
+			// Rules were checked when the manifest was read
+			settings.onCollision, _ = graph.ParseCollisionAction(rule.OnCollision)
+		}
+		if rule.Rename != "" {
+			settings.rename = rule.Rename
+		}
+	}
+	return settings
+}
+
+// isManifest reports whether path is the manifest in use, which is left out
+// of the graph.
+func (b *builder) isManifest(path string) bool {
+	return b.opts.UseManifest && path == filepath.Join(b.root, ManifestFile)
+}
@@ -0,0 +1,120 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// writeManifestTree creates files, keyed by slash-separated path, under a new
+// temporary directory and returns it.
+func writeManifestTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for p, content := range files {
+		full := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestManifestRules(t *testing.T) {
+	dir := writeManifestTree(t, map[string]string{
+		ManifestFile: `rules:
+  - match: "config/*.conf"
+    action: TEMPLATE
+  - match: config/static.conf
+    action: COPY
+  - match: LICENSE
+    on_collision: OVERWRITE
+  - match: gitignore
+    rename: .gitignore
+`,
+		"config/app.conf":    "name = {{ .Name }}\n",
+		"config/static.conf": "name = {{ literal }}\n",
+		"LICENSE":            "MIT\n",
+		"gitignore":          "bin/\n",
+	})
+
+	root, err := BuildGraphWithOptions(context.Background(), dir, BuildOptions{UseManifest: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := graph.FindByPath(root, ManifestFile); err == nil {
+		t.Errorf("%s was included in the graph", ManifestFile)
+	}
+	if _, err := graph.FindByPath(root, "gitignore"); err == nil {
+		t.Error("gitignore kept its original name")
+	}
+
+	tests := []struct {
+		path        string
+		action      string
+		onCollision graph.CollisionAction
+	}{
+		{path: "config/app.conf", action: graph.FILEACTION_TEMPLATE},
+		{path: "config/static.conf", action: graph.FILEACTION_COPY},
+		{path: "LICENSE", action: graph.FILEACTION_COPY, onCollision: graph.OverwriteOnCollision},
+		{path: ".gitignore", action: graph.FILEACTION_COPY},
+	}
+	for _, tt := range tests {
+		node, err := graph.FindByPath(root, tt.path)
+		if err != nil {
+			t.Errorf("FindByPath(%s): %v", tt.path, err)
+			continue
+		}
+		file := node.(*graph.FileNode)
+		if file.Action() != tt.action || file.CollisionAction() != tt.onCollision {
+			t.Errorf("%s has action %s and collision action %s, want %s and %s",
+				tt.path, file.Action(), file.CollisionAction(), tt.action, tt.onCollision)
+		}
+	}
+}
+
+func TestManifestIgnoredUnlessEnabled(t *testing.T) {
+	dir := writeManifestTree(t, map[string]string{
+		ManifestFile: "rules:\n  - match: a.txt\n    rename: b.txt\n",
+		"a.txt":      "a\n",
+	})
+	root, err := BuildGraph(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{ManifestFile, "a.txt"} {
+		if _, err := graph.FindByPath(root, p); err != nil {
+			t.Errorf("FindByPath(%s) without UseManifest: %v", p, err)
+		}
+	}
+}
+
+func TestManifestErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  string
+	}{
+		{name: "missing match", manifest: "rules:\n  - action: COPY\n", wantErr: "missing match pattern"},
+		{name: "bad action", manifest: "rules:\n  - match: a.txt\n    action: MOVE\n", wantErr: "invalid action MOVE"},
+		{name: "bad collision action", manifest: "rules:\n  - match: a.txt\n    on_collision: MERGE\n", wantErr: "unknown collision action MERGE"},
+		{name: "rename with separator", manifest: "rules:\n  - match: a.txt\n    rename: sub/b.txt\n", wantErr: "invalid rename"},
+		{name: "unknown field", manifest: "rules:\n  - match: a.txt\n    mode: 0755\n", wantErr: "failed to parse manifest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeManifestTree(t, map[string]string{ManifestFile: tt.manifest, "a.txt": "a\n"})
+			_, err := BuildGraphWithOptions(context.Background(), dir, BuildOptions{UseManifest: true})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("BuildGraphWithOptions error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package fs
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/sthussey/ska/graph"
+)
+
+// fileProvider streams the content of a file on disk, failing if the content
+// no longer matches the hash recorded during the build.
+type fileProvider struct {
+	path      string
+	size      int64
+	algorithm string
+	hash      []byte
+}
+
+func (p *fileProvider) Open() (io.ReadCloser, error) {
+	hasher, err := graph.NewHasher(p.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", p.path, err)
+	}
+	return &verifyingReader{file: file, hasher: hasher, provider: p}, nil
+}
+
+func (p *fileProvider) Size() int64 {
+	return p.size
+}
+
+// verifyingReader hashes a file as it is read and reports a change in place
+// of the end of the file when the hash no longer matches.
+type verifyingReader struct {
+	file     *os.File
+	hasher   hash.Hash
+	provider *fileProvider
+}
+
+func (r *verifyingReader) Read(buf []byte) (int, error) {
+	n, err := r.file.Read(buf)
+	r.hasher.Write(buf[:n])
+	if err == io.EOF && !bytes.Equal(r.hasher.Sum(nil), r.provider.hash) {
+		return n, fmt.Errorf("file %s changed since the graph was built", r.provider.path)
+	}
+	if err != nil && err != io.EOF {
+		return n, fmt.Errorf("failed to read file %s: %w", r.provider.path, err)
+	}
+	return n, err
+}
+
+func (r *verifyingReader) Close() error {
+	return r.file.Close()
+}
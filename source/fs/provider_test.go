@@ -0,0 +1,54 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+)
+
+func TestFileProviderVerifiesContent(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"same.txt": "unchanged\n", "changed.txt": "original\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	root, err := BuildGraph(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "changed.txt"), []byte("modified\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr string
+	}{
+		{path: "same.txt", want: "unchanged\n"},
+		{path: "changed.txt", wantErr: "changed since the graph was built"},
+	}
+	for _, tt := range tests {
+		node, err := graph.FindByPath(root, tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Content is streamed from disk rather than held in memory
+		content, err := node.(*graph.FileNode).Content()
+		switch {
+		case tt.wantErr != "":
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: Content error = %v, want %q", tt.path, err, tt.wantErr)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", tt.path, err)
+		case string(content) != tt.want:
+			t.Errorf("%s: Content = %q, want %q", tt.path, content, tt.want)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package fs
+
+// readXattrs is a no-op on platforms without xattr support.
+func readXattrs(path string) (map[string][]byte, error) {
+	return nil, nil
+}
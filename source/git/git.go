@@ -0,0 +1,98 @@
+// Package git builds graphs from git repositories by cloning them with the
+// git command line tool.
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sthussey/ska/graph"
+	"github.com/sthussey/ska/source/fs"
+)
+
+// GitOptions controls how a repository is cloned before its graph is built.
+type GitOptions struct {
+	// Ref is the branch or tag to check out, defaulting to the remote HEAD.
+	Ref string
+	// Depth limits the clone to the given number of commits; zero clones the full history.
+	Depth int
+	// SingleBranch fetches only the history of Ref (or the remote HEAD).
+	SingleBranch bool
+	// Build holds the options used to build the graph from the checkout.
+	Build fs.BuildOptions
+}
+
+// BuildGraph clones the repository at url into a temporary directory and
+// builds a graph from its working tree, excluding the .git directory. File
+// content is loaded into memory because the temporary clone is always
+// removed before returning. Cancelling ctx stops the clone or the build.
+func BuildGraph(ctx context.Context, url string, opts GitOptions) (graph.SkaffoldNode, error) {
+	tmpDir, err := os.MkdirTemp("", "ska-git-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clone directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Clone into a directory named after the repository so the graph root is too
+	cloneDir := filepath.Join(tmpDir, repoName(url))
+
+	args := []string{"clone", "--quiet"}
+	if opts.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+	}
+	if opts.SingleBranch {
+		args = append(args, "--single-branch")
+	}
+	if opts.Ref != "" {
+		args = append(args, "--branch", opts.Ref)
+	}
+	args = append(args, "--", url, cloneDir)
+
+	out, err := exec.CommandContext(ctx, "git", args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone %s: %w: %s", url, err, strings.TrimSpace(string(out)))
+	}
+
+	if err := os.RemoveAll(filepath.Join(cloneDir, ".git")); err != nil {
+		return nil, fmt.Errorf("failed to remove git metadata from clone of %s: %w", url, err)
+	}
+
+	root, err := fs.BuildGraphWithOptions(ctx, cloneDir, opts.Build)
+	if err != nil {
+		return nil, err
+	}
+
+	// File content is read lazily from disk, so load it before the clone is removed
+	err = graph.Walk(root, func(node graph.SkaffoldNode, depth int, segments []string) error {
+		// Skipped files have no content to load, only the reason it was skipped
+		if fileNode, ok := node.(*graph.FileNode); ok && !fileNode.ContentSkipped() {
+			return fileNode.LoadContent()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// repoName derives a directory name from a repository URL, e.g.
+// "https://github.com/sthussey/ska.git" becomes "ska".
+func repoName(url string) string {
+	name := strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	// scp-like URLs such as git@host:owner/repo separate the path with a colon
+	if idx := strings.LastIndexAny(name, "/:"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	name = path.Clean(name)
+	if name == "" || name == "." || name == ".." {
+		return "repo"
+	}
+	return name
+}
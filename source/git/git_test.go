@@ -0,0 +1,93 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+	"github.com/sthussey/ska/source/fs"
+)
+
+// initRepo creates a git repository holding files and returns its path.
+func initRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := filepath.Join(t.TempDir(), "repo")
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+		}
+	}
+	return dir
+}
+
+func TestBuildGraphMaxFileSize(t *testing.T) {
+	repo := initRepo(t, map[string]string{
+		"small.txt": "small",
+		"large.txt": strings.Repeat("x", 100),
+	})
+
+	root, err := BuildGraph(context.Background(), repo, GitOptions{Build: fs.BuildOptions{MaxFileSize: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Key() != "repo" {
+		t.Errorf("root key = %q, want repo", root.Key())
+	}
+	if _, err := graph.FindByPath(root, ".git"); err == nil {
+		t.Error("the .git directory was kept")
+	}
+
+	small, err := graph.FindByPath(root, "small.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := small.(*graph.FileNode).Content()
+	if err != nil || string(content) != "small" {
+		t.Errorf("small.txt = %q, %v; want small", content, err)
+	}
+
+	large, err := graph.FindByPath(root, "large.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := large.(*graph.FileNode); !f.ContentSkipped() || f.Size() != 100 {
+		t.Errorf("large.txt skipped = %v with size %d, want skipped with size 100", f.ContentSkipped(), f.Size())
+	}
+}
+func TestRepoName(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/sthussey/ska.git": "ska",
+		"https://github.com/sthussey/ska/":    "ska",
+		"git@github.com:sthussey/ska.git":     "ska",
+		"/srv/git/project":                    "project",
+		"https://example.com/..":              "repo",
+	}
+	for url, want := range tests {
+		if got := repoName(url); got != want {
+			t.Errorf("repoName(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Package http builds a scaffold graph from a tar, gzipped tar or zip archive
+// downloaded from a URL, such as a release tarball.
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sthussey/ska/graph"
+	"github.com/sthussey/ska/source/tar"
+	"github.com/sthussey/ska/source/zip"
+)
+
+const FORMAT_TAR = "TAR"
+const FORMAT_TARGZ = "TARGZ"
+const FORMAT_ZIP = "ZIP"
+
+// HTTPOptions controls how an archive is downloaded.
+type HTTPOptions struct {
+	// Client sends the request, defaulting to http.DefaultClient.
+	Client *http.Client
+	// Timeout bounds the whole download; zero waits as long as the context allows.
+	Timeout time.Duration
+	// Format is one of the FORMAT_* constants, detected from the response when empty.
+	Format string
+	// DuplicatePolicy resolves archive entries appearing at the same path
+	// more than once, defaulting to tar.LastWins.
+	DuplicatePolicy tar.DuplicatePolicy
+	// DecompressExtensions lists extensions, such as ".gz", of individually
+	// compressed files in the archive to decompress, as the tar and zip
+	// sources do.
+	DecompressExtensions []string
+}
+
+// BuildGraph downloads the archive at url and builds the graph it describes.
+func BuildGraph(url string) (graph.SkaffoldNode, error) {
+	return BuildGraphWithOptions(context.Background(), url, HTTPOptions{})
+}
+
+// BuildGraphWithOptions downloads the archive at url using the provided
+// options, stopping when ctx is done, and builds the graph it describes with
+// the tar or zip source. Unless opts.Format is set, the format is detected
+// from the response content type, then from the extension of the URL path
+// and finally from the leading bytes of the archive.
+func BuildGraphWithOptions(ctx context.Context, url string, opts HTTPOptions) (graph.SkaffoldNode, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", url, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	// The whole archive is held in memory since zip needs random access
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+
+	format := opts.Format
+	if format == "" {
+		format = detectFormat(resp.Header.Get("Content-Type"), url, data)
+	}
+
+	tarOpts := tar.BuildOptions{DuplicatePolicy: opts.DuplicatePolicy, DecompressExtensions: opts.DecompressExtensions}
+	zipOpts := zip.BuildOptions{DuplicatePolicy: opts.DuplicatePolicy, DecompressExtensions: opts.DecompressExtensions}
+	switch format {
+	case FORMAT_TAR:
+		return tar.BuildGraphWithOptions(bytes.NewReader(data), tarOpts)
+	case FORMAT_TARGZ:
+		return tar.BuildGraphGzWithOptions(bytes.NewReader(data), tarOpts)
+	case FORMAT_ZIP:
+		return zip.BuildGraphWithOptions(bytes.NewReader(data), int64(len(data)), zipOpts)
+	case "":
+		return nil, fmt.Errorf("cannot detect the archive format of %s", url)
+	default:
+		return nil, fmt.Errorf("unknown archive format %s", format)
+	}
+}
+
+// detectFormat returns the archive format indicated by the content type, the
+// extension of the path of rawURL or the leading bytes of data, in that
+// order, or an empty string if none of them is recognized.
+func detectFormat(contentType, rawURL string, data []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/x-tar":
+		return FORMAT_TAR
+	case "application/gzip", "application/x-gzip", "application/x-gtar", "application/x-compressed-tar":
+		return FORMAT_TARGZ
+	case "application/zip", "application/x-zip-compressed":
+		return FORMAT_ZIP
+	}
+
+	p := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		p = u.Path
+	}
+	p = strings.ToLower(p)
+	switch {
+	case strings.HasSuffix(p, ".tar"):
+		return FORMAT_TAR
+	case strings.HasSuffix(p, ".tar.gz"), strings.HasSuffix(p, ".tgz"):
+		return FORMAT_TARGZ
+	case strings.HasSuffix(p, ".zip"):
+		return FORMAT_ZIP
+	}
+
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return FORMAT_TARGZ
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")), bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		return FORMAT_ZIP
+	case len(data) > 262 && string(data[257:262]) == "ustar":
+		return FORMAT_TAR
+	}
+	return ""
+}
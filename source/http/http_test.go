@@ -0,0 +1,136 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sthussey/ska/graph"
+	sinktar "github.com/sthussey/ska/sink/tar"
+	sinkzip "github.com/sthussey/ska/sink/zip"
+)
+
+// archive is a response served by newServer.
+type archive struct {
+	contentType string
+	body        []byte
+}
+
+// newServer serves the archives keyed by URL path and fails other requests.
+func newServer(t *testing.T, archives map[string]archive) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a, ok := archives[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if a.contentType != "" {
+			w.Header().Set("Content-Type", a.contentType)
+		}
+		_, _ = w.Write(a.body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestBuildGraph(t *testing.T) {
+	root, err := graph.NewBuilder(".").
+		Dir("cmd", func(b *graph.Builder) { b.File("main.go.tmpl").Content([]byte("package {{.Package}}\n")) }).
+		File("README.md").Content([]byte("# Project\n")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tarball, tgz, zipped bytes.Buffer
+	if err := sinktar.WriteGraph(root, &tarball); err != nil {
+		t.Fatal(err)
+	}
+	if err := sinktar.WriteGraphGz(root, &tgz); err != nil {
+		t.Fatal(err)
+	}
+	if err := sinkzip.WriteGraph(root, &zipped); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newServer(t, map[string]archive{
+		"/release.tar.gz":   {contentType: "application/gzip", body: tgz.Bytes()},
+		"/by-extension.zip": {contentType: "application/octet-stream", body: zipped.Bytes()},
+		"/sniffed-tgz":      {body: tgz.Bytes()},
+		"/sniffed-tar":      {body: tarball.Bytes()},
+		"/sniffed-zip":      {body: zipped.Bytes()},
+	})
+
+	for _, p := range []string{"/release.tar.gz", "/by-extension.zip", "/sniffed-tgz", "/sniffed-tar", "/sniffed-zip"} {
+		t.Run(p, func(t *testing.T) {
+			got, err := BuildGraph(srv.URL + p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range []string{"cmd/main.go.tmpl", "README.md"} {
+				node, err := graph.FindByPath(got, want)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if node.(*graph.FileNode).DataHash() == nil {
+					t.Errorf("%s has no content", want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildGraphErrors(t *testing.T) {
+	srv := newServer(t, map[string]archive{
+		"/unknown": {contentType: "text/plain", body: []byte("not an archive")},
+	})
+
+	tests := []struct {
+		name string
+		url  string
+		opts HTTPOptions
+		want string
+	}{
+		{name: "not found", url: srv.URL + "/missing.tar.gz", want: "404"},
+		{name: "undetected format", url: srv.URL + "/unknown", want: "cannot detect the archive format"},
+		{name: "unknown format", url: srv.URL + "/unknown", opts: HTTPOptions{Format: "RAR"}, want: "unknown archive format RAR"},
+		{name: "corrupt archive", url: srv.URL + "/unknown", opts: HTTPOptions{Format: FORMAT_ZIP}, want: "zip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := BuildGraphWithOptions(context.Background(), tt.url, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildGraphTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	// The handler must return before the server can close
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+
+	_, err := BuildGraphWithOptions(context.Background(), srv.URL+"/slow.tar", HTTPOptions{Timeout: 50 * time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := BuildGraphWithOptions(ctx, srv.URL+"/slow.tar", HTTPOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,82 @@
+// Package multi builds graphs from a source holding a catalog of templates,
+// where each template is a subdirectory of a templates directory.
+package multi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sthussey/ska/graph"
+	"github.com/sthussey/ska/source/fs"
+)
+
+// TemplatesDir is the directory under a source root that holds the template catalog.
+const TemplatesDir = "templates"
+
+// ListTemplates returns the sorted names of the templates available under root.
+// Templates are the subdirectories of root/templates, or of root itself when it
+// has no templates directory.
+func ListTemplates(root string) ([]string, error) {
+	catalog, err := catalogDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(catalog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template catalog %s: %w", catalog, err)
+	}
+
+	names := make([]string, 0)
+	for _, entry := range entries {
+		// Hidden directories such as .git are never templates
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// BuildTemplate builds the graph for the named template under root.
+func BuildTemplate(root, name string) (graph.SkaffoldNode, error) {
+	return BuildTemplateWithOptions(context.Background(), root, name, fs.BuildOptions{})
+}
+
+// BuildTemplateWithOptions builds the graph for the named template under root
+// using the provided build options, stopping once ctx is done.
+func BuildTemplateWithOptions(ctx context.Context, root, name string, opts fs.BuildOptions) (graph.SkaffoldNode, error) {
+	names, err := ListTemplates(root)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := sort.SearchStrings(names, name)
+	if idx == len(names) || names[idx] != name {
+		return nil, fmt.Errorf("template %s not found in %s (available: %s)", name, root, strings.Join(names, ", "))
+	}
+
+	catalog, err := catalogDir(root)
+	if err != nil {
+		return nil, err
+	}
+	return fs.BuildGraphWithOptions(ctx, filepath.Join(catalog, name), opts)
+}
+
+// catalogDir returns the directory whose subdirectories are the templates under root.
+func catalogDir(root string) (string, error) {
+	catalog := filepath.Join(root, TemplatesDir)
+	info, err := os.Stat(catalog)
+	if err == nil && info.IsDir() {
+		return catalog, nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to stat template catalog %s: %w", catalog, err)
+	}
+	return root, nil
+}
@@ -0,0 +1,67 @@
+package multi
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// catalog creates the named template directories, each holding a README, under dir.
+func catalog(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "README.md"), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		catalog string
+	}{
+		{"templates directory", TemplatesDir},
+		{"root directory", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			catalog(t, filepath.Join(root, tt.catalog), "web", "api", ".git")
+
+			names, err := ListTemplates(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"api", "web"}; !reflect.DeepEqual(names, want) {
+				t.Errorf("ListTemplates = %v, want %v", names, want)
+			}
+		})
+	}
+}
+
+func TestBuildTemplate(t *testing.T) {
+	root := t.TempDir()
+	catalog(t, filepath.Join(root, TemplatesDir), "api", "web")
+
+	node, err := BuildTemplate(root, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Key() != "web" {
+		t.Errorf("root key = %q, want web", node.Key())
+	}
+	children := node.Children()
+	if len(children) != 1 || children[0].Key() != "README.md" {
+		t.Errorf("children = %v, want only README.md", children)
+	}
+
+	if _, err := BuildTemplate(root, "cli"); err == nil {
+		t.Error("BuildTemplate succeeded for a template not in the catalog")
+	}
+}
@@ -0,0 +1,199 @@
+// Package tar builds a scaffold graph from a tar archive.
+package tar
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sthussey/ska/graph"
+	"github.com/sthussey/ska/internal/archive"
+)
+
+// xattrPrefix marks PAX records holding extended attributes.
+const xattrPrefix = "SCHILY.xattr."
+
+// DuplicatePolicy determines which entry is kept when an archive holds a
+// file or symlink at the same path more than once.
+type DuplicatePolicy = archive.DuplicatePolicy
+
+const (
+	LastWins         = archive.LastWins
+	FirstWins        = archive.FirstWins
+	ErrorOnDuplicate = archive.ErrorOnDuplicate
+)
+
+// BuildOptions controls how BuildGraphWithOptions reads an archive.
+type BuildOptions struct {
+	// CaptureOwnership records the numeric owner and group stored in each
+	// entry header on its node, as written by the tar sink.
+	CaptureOwnership bool
+	// DuplicatePolicy resolves files and symlinks appearing at the same path
+	// more than once. The zero value, LastWins, matches extracting the archive.
+	DuplicatePolicy DuplicatePolicy
+	// DecompressExtensions lists extensions, such as ".gz", of individually
+	// compressed files to decompress. Such files are keyed without the
+	// extension and record the encoding under graph.METADATA_CONTENT_ENCODING.
+	// Extensions of encodings that cannot be decoded are kept as they are.
+	DecompressExtensions []string
+}
+
+// BuildGraph reads a tar stream and builds the graph it describes. The root
+// directory stands for the archive itself and is keyed ".". Entries may appear
+// in any order, with missing parent directories created as needed, and a
+// later entry replaces an earlier one at the same path unless
+// BuildOptions.DuplicatePolicy says otherwise. Hard links become
+// copies of the file they link to. Entry modes and extended attributes
+// recorded in PAX headers are kept, while device, FIFO and other special entries are errors.
+func BuildGraph(r io.Reader) (graph.SkaffoldNode, error) {
+	return BuildGraphWithOptions(r, BuildOptions{})
+}
+
+// BuildGraphWithOptions reads a tar stream and builds the graph it describes
+// as BuildGraph does, using the provided options.
+func BuildGraphWithOptions(r io.Reader, opts BuildOptions) (graph.SkaffoldNode, error) {
+	tree := archive.NewTreeWithPolicy(opts.DuplicatePolicy)
+	files := make(map[string]*graph.FileNode)
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar archive: %w", err)
+		}
+
+		p, err := archive.Clean(hdr.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			dir, err := tree.Dir(p)
+			if err != nil {
+				return nil, err
+			}
+			dir.SetMode(hdr.FileInfo().Mode())
+			setXattrs(dir, hdr)
+			setOwner(dir, hdr, opts)
+		case tar.TypeReg:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read tar entry %s: %w", hdr.Name, err)
+			}
+			filePath, encoding := archive.TrimEncoding(p, opts.DecompressExtensions)
+			if encoding != "" {
+				if data, err = archive.Decode(p, data, encoding); err != nil {
+					return nil, err
+				}
+			}
+			if err := addFile(tree, files, filePath, data, encoding, hdr, opts); err != nil {
+				return nil, err
+			}
+		case tar.TypeLink:
+			linked, err := archive.Clean(hdr.Linkname)
+			if err != nil {
+				return nil, err
+			}
+			linked, _ = archive.TrimEncoding(linked, opts.DecompressExtensions)
+			source, ok := files[linked]
+			if !ok {
+				return nil, fmt.Errorf("tar entry %s links to %s, which is not an earlier file", hdr.Name, hdr.Linkname)
+			}
+			data, err := source.Content()
+			if err != nil {
+				return nil, err
+			}
+			// The link shares the content of the file it links to, which
+			// was decompressed only if stored with the same encoding
+			filePath, encoding := archive.TrimEncoding(p, opts.DecompressExtensions)
+			if encoding != source.Metadata()[graph.METADATA_CONTENT_ENCODING] {
+				return nil, fmt.Errorf("tar entry %s links to %s, which is not stored with the same encoding", hdr.Name, hdr.Linkname)
+			}
+			if err := addFile(tree, files, filePath, data, encoding, hdr, opts); err != nil {
+				return nil, err
+			}
+		case tar.TypeSymlink:
+			linkNode := graph.NewSymlinkNode(name(p), hdr.Linkname)
+			setOwner(linkNode, hdr, opts)
+			added, err := tree.Add(p, linkNode)
+			if err != nil {
+				return nil, err
+			}
+			if added {
+				delete(files, p)
+			}
+		case tar.TypeXGlobalHeader:
+			// Global PAX headers carry archive-wide defaults, not entries
+		default:
+			return nil, fmt.Errorf("tar entry %s has unsupported type %q", hdr.Name, hdr.Typeflag)
+		}
+	}
+	return tree.Root(), nil
+}
+
+// BuildGraphGz reads a gzip-compressed tar stream and builds the graph it
+// describes, as BuildGraph does.
+func BuildGraphGz(r io.Reader) (graph.SkaffoldNode, error) {
+	return BuildGraphGzWithOptions(r, BuildOptions{})
+}
+
+// BuildGraphGzWithOptions reads a gzip-compressed tar stream and builds the
+// graph it describes, as BuildGraphWithOptions does.
+func BuildGraphGzWithOptions(r io.Reader, opts BuildOptions) (graph.SkaffoldNode, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gzip stream: %w", err)
+	}
+	defer zr.Close()
+	return BuildGraphWithOptions(zr, opts)
+}
+
+// addFile adds a file holding data at the cleaned path p, recording the
+// encoding it was decompressed from, if any, and recording it in files for
+// later hard links when the tree keeps it.
+func addFile(tree *archive.Tree, files map[string]*graph.FileNode, p string, data []byte, encoding string, hdr *tar.Header, opts BuildOptions) error {
+	fileNode := graph.NewFileNode(name(p))
+	fileNode.SetContent(data)
+	if encoding != "" {
+		fileNode.SetMetadata(graph.METADATA_CONTENT_ENCODING, encoding)
+	}
+	fileNode.SetMode(hdr.FileInfo().Mode())
+	setXattrs(fileNode, hdr)
+	setOwner(fileNode, hdr, opts)
+	added, err := tree.Add(p, fileNode)
+	if err != nil {
+		return err
+	}
+	if added {
+		files[p] = fileNode
+	}
+	return nil
+}
+
+// setXattrs copies extended attributes recorded in PAX headers to node.
+func setXattrs(node interface{ SetXattr(string, []byte) }, hdr *tar.Header) {
+	for key, value := range hdr.PAXRecords {
+		if attr, ok := strings.CutPrefix(key, xattrPrefix); ok {
+			node.SetXattr(attr, []byte(value))
+		}
+	}
+}
+
+// setOwner records the owner and group of the entry on node when enabled.
+func setOwner(node interface{ SetOwner(int, int) }, hdr *tar.Header, opts BuildOptions) {
+	if opts.CaptureOwnership {
+		node.SetOwner(hdr.Uid, hdr.Gid)
+	}
+}
+
+// name returns the final segment of the cleaned path p.
+func name(p string) string {
+	return p[strings.LastIndex(p, "/")+1:]
+}
@@ -0,0 +1,200 @@
+package tar
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+	sinktar "github.com/sthussey/ska/sink/tar"
+)
+
+func TestOwnershipRoundTrip(t *testing.T) {
+	root, err := graph.NewBuilder("root").
+		Dir("dir", func(b *graph.Builder) { b.File("a.txt").Content([]byte("a")) }).
+		Symlink("link", "dir/a.txt").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	owners := map[string][2]int{"dir": {1001, 1002}, "dir/a.txt": {1003, 1004}, "link": {1005, 1006}}
+	for p, owner := range owners {
+		node, err := graph.FindByPath(root, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		node.(interface{ SetOwner(int, int) }).SetOwner(owner[0], owner[1])
+	}
+
+	var buf bytes.Buffer
+	if err := sinktar.WriteGraph(root, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		opts      BuildOptions
+		wantOwner bool
+	}{
+		{name: "captured", opts: BuildOptions{CaptureOwnership: true}, wantOwner: true},
+		{name: "ignored", opts: BuildOptions{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			read, err := BuildGraphWithOptions(bytes.NewReader(buf.Bytes()), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for p, want := range owners {
+				node, err := graph.FindByPath(read, p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				uid, gid, ok := node.(interface{ Owner() (int, int, bool) }).Owner()
+				if ok != tt.wantOwner {
+					t.Fatalf("%s has owner %v, want %v", p, ok, tt.wantOwner)
+				}
+				if ok && (uid != want[0] || gid != want[1]) {
+					t.Errorf("%s owned by %d:%d, want %d:%d", p, uid, gid, want[0], want[1])
+				}
+			}
+		})
+	}
+}
+
+func TestDecompressExtensions(t *testing.T) {
+	original := []byte("<svg>logo</svg>\n")
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	if _, err := zw.Write(original); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	entries := []struct {
+		name string
+		data []byte
+	}{
+		{"assets/logo.svg.gz", gz.Bytes()},
+		{"assets/font.woff.br", []byte("brotli")},
+	}
+	for _, e := range entries {
+		if err := tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(e.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: "assets/copy.svg.gz", Linkname: "assets/logo.svg.gz", Typeflag: tar.TypeLink}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := BuildGraphWithOptions(bytes.NewReader(buf.Bytes()), BuildOptions{DecompressExtensions: []string{".gz", ".br"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path     string
+		want     []byte
+		encoding string
+	}{
+		{"assets/logo.svg", original, "gzip"},
+		{"assets/copy.svg", original, "gzip"},
+		{"assets/font.woff.br", []byte("brotli"), ""},
+	}
+	for _, tt := range tests {
+		node, err := graph.FindByPath(root, tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := node.(*graph.FileNode)
+		content, err := file.Content()
+		if err != nil || !bytes.Equal(content, tt.want) {
+			t.Errorf("%s content = %q, %v; want %q", tt.path, content, err, tt.want)
+		}
+		if got := file.Metadata()[graph.METADATA_CONTENT_ENCODING]; got != tt.encoding {
+			t.Errorf("%s encoding = %q, want %q", tt.path, got, tt.encoding)
+		}
+	}
+
+	// Without the option the compressed entry is kept as it is
+	root, err = BuildGraph(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := graph.FindByPath(root, "assets/logo.svg.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := node.(*graph.FileNode).Content(); !bytes.Equal(content, gz.Bytes()) {
+		t.Error("logo.svg.gz was decompressed without DecompressExtensions")
+	}
+}
+
+func TestDuplicatePolicy(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, content := range []string{"first", "second"} {
+		hdr := &tar.Header{Name: "a.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A hard link follows whichever a.txt was kept
+	if err := tw.WriteHeader(&tar.Header{Name: "b.txt", Typeflag: tar.TypeLink, Linkname: "a.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		policy  DuplicatePolicy
+		want    string
+		wantErr bool
+	}{
+		{name: "last wins", policy: LastWins, want: "second"},
+		{name: "first wins", policy: FirstWins, want: "first"},
+		{name: "error", policy: ErrorOnDuplicate, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := BuildGraphWithOptions(bytes.NewReader(buf.Bytes()), BuildOptions{DuplicatePolicy: tt.policy})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if n := len(root.(*graph.DirectoryNode).Children()); n != 2 {
+				t.Errorf("root has %d children, want 2", n)
+			}
+			for _, p := range []string{"a.txt", "b.txt"} {
+				node, err := graph.FindByPath(root, p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				content, err := node.(*graph.FileNode).Content()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(content) != tt.want {
+					t.Errorf("%s = %q, want %q", p, content, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+// Package yaml builds a scaffold graph from a nested YAML (or JSON) document.
+//
+// The document describes the root directory. Every node has a name and a type
+// of DIRECTORY, FILE or SYMLINK; when the type is omitted, nodes with a
+// children list are directories, nodes with a target are symlinks and all
+// others are files. Directories list their children, symlinks give their
+// target, and files may set an action (COPY or TEMPLATE, otherwise derived
+// from the name) and inline content, given as a string or as a base64
+// !!binary scalar. Binary content may instead be given as a base64 string in
+// content_base64, which cannot be combined with content:
+//
+//	name: my-project
+//	type: DIRECTORY
+//	children:
+//	  - name: main.go.tmpl
+//	    action: TEMPLATE
+//	    content: |
+//	      package {{.Package}}
+//	  - name: logo.png
+//	    content_base64: iVBORw0KGgo=
+package yaml
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/sthussey/ska/graph"
+	"gopkg.in/yaml.v3"
+)
+
+// node is the YAML representation of a graph node.
+type node struct {
+	Name     string   `yaml:"name"`
+	Type     string   `yaml:"type"`
+	Action   string   `yaml:"action"`
+	Content  *content `yaml:"content"`
+	Base64   *string  `yaml:"content_base64"`
+	Target   string   `yaml:"target"`
+	Children []*node  `yaml:"children"`
+}
+
+// content accepts plain strings as well as base64 !!binary scalars.
+type content []byte
+
+func (c *content) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: content must be a scalar", value.Line)
+	}
+	if value.Tag == "!!binary" {
+		data, err := base64.StdEncoding.DecodeString(value.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid binary content: %w", value.Line, err)
+		}
+		*c = data
+		return nil
+	}
+	*c = []byte(value.Value)
+	return nil
+}
+
+// BuildGraph parses a YAML document describing a directory tree and builds
+// the corresponding graph.
+func BuildGraph(r io.Reader) (graph.SkaffoldNode, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+
+	var doc node
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML graph: %w", err)
+	}
+
+	if doc.nodeType() != graph.NODETYPE_DIRECTORY {
+		return nil, fmt.Errorf("graph root %s must be a directory", doc.Name)
+	}
+	if doc.Name == "" {
+		return nil, fmt.Errorf("graph root must have a name")
+	}
+
+	return buildNode(&doc, doc.Name)
+}
+
+// nodeType returns the declared type or infers it from the presence of
+// children or a target.
+func (n *node) nodeType() string {
+	if n.Type != "" {
+		return strings.ToUpper(n.Type)
+	}
+	if n.Children != nil {
+		return graph.NODETYPE_DIRECTORY
+	}
+	if n.Target != "" {
+		return graph.NODETYPE_SYMLINK
+	}
+	return graph.NODETYPE_FILE
+}
+
+// buildNode converts a YAML node to a graph node, where keyPath locates it in
+// the document for error messages.
+func buildNode(n *node, keyPath string) (graph.SkaffoldNode, error) {
+	switch n.nodeType() {
+	case graph.NODETYPE_DIRECTORY:
+		if n.Action != "" || n.Content != nil || n.Base64 != nil || n.Target != "" {
+			return nil, fmt.Errorf("directory %s cannot have an action, content or target", keyPath)
+		}
+
+		dirNode := graph.NewDirectoryNode(n.Name)
+		seen := make(map[string]bool, len(n.Children))
+		for _, c := range n.Children {
+			childPath := path.Join(keyPath, c.Name)
+			if err := validateName(c.Name, childPath); err != nil {
+				return nil, err
+			}
+			if seen[c.Name] {
+				return nil, fmt.Errorf("duplicate entry %s", childPath)
+			}
+			seen[c.Name] = true
+
+			child, err := buildNode(c, childPath)
+			if err != nil {
+				return nil, err
+			}
+			if err := dirNode.AddChild(child); err != nil {
+				return nil, err
+			}
+		}
+		return dirNode, nil
+	case graph.NODETYPE_FILE:
+		if n.Children != nil || n.Target != "" {
+			return nil, fmt.Errorf("file %s cannot have children or a target", keyPath)
+		}
+
+		fileNode := graph.NewFileNode(n.Name)
+		if n.Action != "" {
+			if err := fileNode.SetAction(strings.ToUpper(n.Action)); err != nil {
+				return nil, fmt.Errorf("invalid action for %s: %w", keyPath, err)
+			}
+		}
+		switch {
+		case n.Content != nil && n.Base64 != nil:
+			return nil, fmt.Errorf("file %s cannot have both content and content_base64", keyPath)
+		case n.Content != nil:
+			fileNode.SetContent(*n.Content)
+		case n.Base64 != nil:
+			data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(*n.Base64), ""))
+			if err != nil {
+				return nil, fmt.Errorf("invalid content_base64 for %s: %w", keyPath, err)
+			}
+			fileNode.SetContent(data)
+		}
+		return fileNode, nil
+	case graph.NODETYPE_SYMLINK:
+		if n.Children != nil || n.Action != "" || n.Content != nil || n.Base64 != nil {
+			return nil, fmt.Errorf("symlink %s cannot have children, an action or content", keyPath)
+		}
+		if n.Target == "" {
+			return nil, fmt.Errorf("symlink %s has no target", keyPath)
+		}
+		return graph.NewSymlinkNode(n.Name, n.Target), nil
+	default:
+		return nil, fmt.Errorf("node %s has unknown type %s", keyPath, n.Type)
+	}
+}
+
+// validateName rejects names that are empty or would not form a single path segment.
+func validateName(name, keyPath string) error {
+	if name == "" {
+		return fmt.Errorf("entry under %s has no name", path.Dir(keyPath))
+	}
+	if name == "." || name == ".." || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid name %q at %s", name, keyPath)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package yaml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+	sinkyaml "github.com/sthussey/ska/sink/yaml"
+)
+
+func TestRoundTrip(t *testing.T) {
+	doc := `
+name: my-project
+type: DIRECTORY
+children:
+  - name: cmd
+    children:
+      - name: main.go.tmpl
+        content: |
+          package {{.Package}}
+      - name: static.tmpl
+        action: COPY
+        content: "{{ not a template }}"
+  - name: README.md
+    action: TEMPLATE
+    content: "# {{.Name}}\n"
+  - name: logo.bin
+    content: !!binary AAEC/w==
+  - name: docs
+    children: []
+  - name: current
+    target: cmd
+`
+	root, err := BuildGraph(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := []struct {
+		path    string
+		action  string
+		content string
+	}{
+		{"cmd/main.go.tmpl", graph.FILEACTION_TEMPLATE, "package {{.Package}}\n"},
+		{"cmd/static.tmpl", graph.FILEACTION_COPY, "{{ not a template }}"},
+		{"README.md", graph.FILEACTION_TEMPLATE, "# {{.Name}}\n"},
+		{"logo.bin", graph.FILEACTION_COPY, "\x00\x01\x02\xff"},
+	}
+	for _, f := range files {
+		node, err := graph.FindByPath(root, f.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileNode, ok := node.(*graph.FileNode)
+		if !ok {
+			t.Fatalf("%s is a %s, want a file", f.path, node.Type())
+		}
+		if fileNode.Action() != f.action {
+			t.Errorf("%s has action %s, want %s", f.path, fileNode.Action(), f.action)
+		}
+		content, err := fileNode.Content()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != f.content {
+			t.Errorf("%s = %q, want %q", f.path, content, f.content)
+		}
+		if fileNode.DataHash() == nil {
+			t.Errorf("%s has no content hash", f.path)
+		}
+	}
+	if node, err := graph.FindByPath(root, "docs"); err != nil || node.Type() != graph.NODETYPE_DIRECTORY {
+		t.Errorf("docs = %v, %v; want an empty directory", node, err)
+	}
+	if node, err := graph.FindByPath(root, "current"); err != nil || node.(*graph.SymlinkNode).Target() != "cmd" {
+		t.Errorf("current = %v, %v; want a symlink to cmd", node, err)
+	}
+
+	var buf bytes.Buffer
+	if err := sinkyaml.WriteGraph(root, &buf); err != nil {
+		t.Fatal(err)
+	}
+	read, err := BuildGraph(&buf)
+	if err != nil {
+		t.Fatalf("failed to read back:\n%s\n%v", buf.String(), err)
+	}
+	if equal, diff := graph.EqualDetailed(root, read); !equal {
+		t.Errorf("graph differs after a round trip at %s:\n%s", diff, buf.String())
+	}
+}
+
+func TestContentBase64(t *testing.T) {
+	doc := `
+name: root
+children:
+  - name: logo.bin
+    content_base64: AAEC/w==
+  - name: wrapped.bin
+    content_base64: |
+      AAEC
+      /w==
+`
+	root, err := BuildGraph(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"logo.bin", "wrapped.bin"} {
+		node, err := graph.FindByPath(root, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := node.(*graph.FileNode).Content()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []byte{0, 1, 2, 0xff}; !bytes.Equal(content, want) {
+			t.Errorf("%s = %v, want %v", p, content, want)
+		}
+	}
+}
+
+func TestContentBase64Invalid(t *testing.T) {
+	tests := map[string]string{
+		"both fields": `
+name: root
+children:
+  - name: a.bin
+    content: text
+    content_base64: AAEC
+`,
+		"invalid base64": `
+name: root
+children:
+  - name: a.bin
+    content_base64: "not base64!"
+`,
+		"on a directory": `
+name: root
+children:
+  - name: dir
+    content_base64: AAEC
+    children: []
+`,
+	}
+	for name, doc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := BuildGraph(strings.NewReader(doc))
+			if err == nil {
+				t.Fatal("BuildGraph succeeded")
+			}
+			if !strings.Contains(err.Error(), "root/") {
+				t.Errorf("error %q does not name the key path", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Package zip builds a scaffold graph from a zip archive.
+package zip
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/sthussey/ska/graph"
+	"github.com/sthussey/ska/internal/archive"
+)
+
+// DuplicatePolicy determines which entry is kept when an archive holds a
+// file or symlink at the same path more than once.
+type DuplicatePolicy = archive.DuplicatePolicy
+
+const (
+	LastWins         = archive.LastWins
+	FirstWins        = archive.FirstWins
+	ErrorOnDuplicate = archive.ErrorOnDuplicate
+)
+
+// BuildOptions controls how BuildGraphWithOptions reads an archive.
+type BuildOptions struct {
+	// DuplicatePolicy resolves files and symlinks appearing at the same path
+	// more than once. The zero value, LastWins, matches extracting the archive.
+	DuplicatePolicy DuplicatePolicy
+	// DecompressExtensions lists extensions, such as ".gz", of individually
+	// compressed files to decompress. Such files are keyed without the
+	// extension and record the encoding under graph.METADATA_CONTENT_ENCODING.
+	// Extensions of encodings that cannot be decoded are kept as they are.
+	DecompressExtensions []string
+}
+
+// BuildGraph reads the zip archive of the given size from r and builds the
+// graph it describes. The root directory stands for the archive itself and is
+// keyed ".". Entries may appear in any order, with missing parent directories
+// created as needed, and a later entry replaces an earlier one at the same
+// path. Entry modes are kept, symlinks stored Info-ZIP style become symlink
+// nodes, and other special entries are errors.
+func BuildGraph(r io.ReaderAt, size int64) (graph.SkaffoldNode, error) {
+	return BuildGraphWithOptions(r, size, BuildOptions{})
+}
+
+// BuildGraphWithOptions reads the zip archive of the given size from r and
+// builds the graph it describes as BuildGraph does, using the provided
+// options.
+func BuildGraphWithOptions(r io.ReaderAt, size int64, opts BuildOptions) (graph.SkaffoldNode, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip archive: %w", err)
+	}
+
+	tree := archive.NewTreeWithPolicy(opts.DuplicatePolicy)
+	for _, f := range zr.File {
+		p, err := archive.Clean(f.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir() || strings.HasSuffix(f.Name, "/"):
+			dir, err := tree.Dir(p)
+			if err != nil {
+				return nil, err
+			}
+			dir.SetMode(mode)
+		case mode&fs.ModeSymlink != 0:
+			target, err := readEntry(f)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := tree.Add(p, graph.NewSymlinkNode(name(p), string(target))); err != nil {
+				return nil, err
+			}
+		case mode.IsRegular():
+			data, err := readEntry(f)
+			if err != nil {
+				return nil, err
+			}
+			filePath, encoding := archive.TrimEncoding(p, opts.DecompressExtensions)
+			if encoding != "" {
+				if data, err = archive.Decode(p, data, encoding); err != nil {
+					return nil, err
+				}
+			}
+			fileNode := graph.NewFileNode(name(filePath))
+			fileNode.SetContent(data)
+			fileNode.SetMode(mode)
+			if encoding != "" {
+				fileNode.SetMetadata(graph.METADATA_CONTENT_ENCODING, encoding)
+			}
+			if _, err := tree.Add(filePath, fileNode); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("zip entry %s has unsupported mode %s", f.Name, mode)
+		}
+	}
+	return tree.Root(), nil
+}
+
+// readEntry returns the decompressed content of f.
+func readEntry(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip entry %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip entry %s: %w", f.Name, err)
+	}
+	return data, nil
+}
+
+// name returns the final segment of the cleaned path p.
+func name(p string) string {
+	return p[strings.LastIndex(p, "/")+1:]
+}
@@ -0,0 +1,122 @@
+package zip
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/sthussey/ska/graph"
+)
+
+func TestDecompressExtensions(t *testing.T) {
+	original := []byte("body { color: red }\n")
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	if _, err := zw.Write(original); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create("static/site.CSS.GZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write(gz.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := BuildGraphWithOptions(bytes.NewReader(buf.Bytes()), int64(buf.Len()), BuildOptions{DecompressExtensions: []string{".gz"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := graph.FindByPath(root, "static/site.CSS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := node.(*graph.FileNode)
+	content, err := file.Content()
+	if err != nil || !bytes.Equal(content, original) {
+		t.Errorf("content = %q, %v; want %q", content, err, original)
+	}
+	if got := file.Metadata()[graph.METADATA_CONTENT_ENCODING]; got != "gzip" {
+		t.Errorf("encoding = %q, want gzip", got)
+	}
+}
+
+func TestDecompressExtensionsInvalidContent(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create("broken.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("not gzip")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := BuildGraphWithOptions(bytes.NewReader(buf.Bytes()), int64(buf.Len()), BuildOptions{DecompressExtensions: []string{".gz"}}); err == nil {
+		t.Error("BuildGraphWithOptions succeeded on an entry that is not gzip")
+	}
+}
+
+func TestDuplicatePolicy(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, content := range []string{"first", "second"} {
+		w, err := zw.Create("a.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		policy  DuplicatePolicy
+		want    string
+		wantErr bool
+	}{
+		{name: "last wins", policy: LastWins, want: "second"},
+		{name: "first wins", policy: FirstWins, want: "first"},
+		{name: "error", policy: ErrorOnDuplicate, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bytes.NewReader(buf.Bytes())
+			root, err := BuildGraphWithOptions(r, r.Size(), BuildOptions{DuplicatePolicy: tt.policy})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			node, err := graph.FindByPath(root, "a.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err := node.(*graph.FileNode).Content()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.want {
+				t.Errorf("a.txt = %q, want %q", content, tt.want)
+			}
+		})
+	}
+}